@@ -0,0 +1,798 @@
+package wotlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// TDContextURL is the context of thing descriptions as defined by the w3c recommendation
+const TDContextURL = "https://www.w3.org/2019/wot/td/v1"
+
+// BuildError is returned if a thing description built by a ThingBuilder
+// misses required fields or is inconsistent
+type BuildError struct {
+	// Path points to the invalid element, e.g. "/properties/brightness/forms"
+	Path   string
+	Reason string
+}
+
+func (e *BuildError) Error() string {
+	if e.Path == "" {
+		return "invalid thing description: " + e.Reason
+	}
+
+	return fmt.Sprintf("invalid thing description at %s: %s", e.Path, e.Reason)
+}
+
+// ThingBuilder builds thing descriptions. Compact iris of semantic types are
+// resolved using the DefaultContext
+type ThingBuilder struct {
+	document            map[string]interface{}
+	securityDefinitions map[string]*SecuritySchemeBuilder
+	properties          []*PropertyBuilder
+	actions             []*ActionBuilder
+	events              []*EventBuilder
+	err                 error
+}
+
+// NewThing starts a thing description with the given title
+func NewThing(title string) *ThingBuilder {
+	return &ThingBuilder{
+		document:            map[string]interface{}{"title": title},
+		securityDefinitions: map[string]*SecuritySchemeBuilder{},
+	}
+}
+
+// WithID sets the id of the thing
+func (b *ThingBuilder) WithID(id string) *ThingBuilder {
+	b.document["id"] = id
+	return b
+}
+
+// WithType adds semantic types to the thing
+func (b *ThingBuilder) WithType(types ...string) *ThingBuilder {
+	appendTypes(b.document, types)
+	return b
+}
+
+// WithDescription sets the description of the thing
+func (b *ThingBuilder) WithDescription(description string) *ThingBuilder {
+	b.document["description"] = description
+	return b
+}
+
+// WithBase sets the base url relative hrefs of forms are resolved against
+func (b *ThingBuilder) WithBase(base string) *ThingBuilder {
+	b.document["base"] = base
+	return b
+}
+
+// WithSecurityDefinition adds a named security scheme which can be
+// referenced by the security of the thing or of forms
+func (b *ThingBuilder) WithSecurityDefinition(name string, scheme *SecuritySchemeBuilder) *ThingBuilder {
+	b.securityDefinitions[name] = scheme
+	return b
+}
+
+// WithSecurity sets the names of the security definitions which apply
+// to all forms without own security
+func (b *ThingBuilder) WithSecurity(names ...string) *ThingBuilder {
+	b.document["security"] = names
+	return b
+}
+
+// AddProperty adds a property affordance
+func (b *ThingBuilder) AddProperty(property *PropertyBuilder) *ThingBuilder {
+	b.properties = append(b.properties, property)
+	return b
+}
+
+// AddAction adds an action affordance
+func (b *ThingBuilder) AddAction(action *ActionBuilder) *ThingBuilder {
+	b.actions = append(b.actions, action)
+	return b
+}
+
+// AddEvent adds an event affordance
+func (b *ThingBuilder) AddEvent(event *EventBuilder) *ThingBuilder {
+	b.events = append(b.events, event)
+	return b
+}
+
+// Document validates the thing description and returns it in its compact form
+func (b *ThingBuilder) Document() ([]byte, error) {
+	document, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document)
+}
+
+// Build validates the thing description and returns it in its expanded form
+func (b *ThingBuilder) Build() (ExpandedThingDescription, error) {
+	document, err := b.Document()
+	if err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	return FromBytes(document)
+}
+
+// build validates the builder and assembles the compact document
+func (b *ThingBuilder) build() (map[string]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	document := copyMap(b.document)
+
+	context := map[string]interface{}{}
+	for prefix, iri := range DefaultContext {
+		context[prefix] = iri
+	}
+
+	document["@context"] = []interface{}{TDContextURL, context}
+
+	if title, _ := document["title"].(string); title == "" {
+		return nil, &BuildError{Path: "/title", Reason: "title is required"}
+	}
+
+	if base, ok := document["base"].(string); ok {
+		if u, err := url.Parse(base); err != nil || !u.IsAbs() {
+			return nil, &BuildError{Path: "/base", Reason: "base must be an absolute url"}
+		}
+	}
+
+	if len(b.securityDefinitions) == 0 {
+		return nil, &BuildError{Path: "/securityDefinitions", Reason: "at least one security definition is required"}
+	}
+
+	definitions := map[string]interface{}{}
+	for name, currScheme := range b.securityDefinitions {
+		scheme, err := currScheme.build("/securityDefinitions/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		definitions[name] = scheme
+	}
+
+	document["securityDefinitions"] = definitions
+
+	security, _ := document["security"].([]string)
+	if len(security) == 0 {
+		return nil, &BuildError{Path: "/security", Reason: "security is required"}
+	}
+
+	if err := b.checkSecurity("/security", security); err != nil {
+		return nil, err
+	}
+
+	properties := map[string]interface{}{}
+	for _, currProperty := range b.properties {
+		path := "/properties/" + currProperty.name
+		if _, ok := properties[currProperty.name]; ok {
+			return nil, &BuildError{Path: path, Reason: "duplicate property"}
+		}
+
+		property, err := currProperty.build(b, path)
+		if err != nil {
+			return nil, err
+		}
+
+		properties[currProperty.name] = property
+	}
+
+	actions := map[string]interface{}{}
+	for _, currAction := range b.actions {
+		path := "/actions/" + currAction.name
+		if _, ok := actions[currAction.name]; ok {
+			return nil, &BuildError{Path: path, Reason: "duplicate action"}
+		}
+
+		action, err := currAction.build(b, path)
+		if err != nil {
+			return nil, err
+		}
+
+		actions[currAction.name] = action
+	}
+
+	events := map[string]interface{}{}
+	for _, currEvent := range b.events {
+		path := "/events/" + currEvent.name
+		if _, ok := events[currEvent.name]; ok {
+			return nil, &BuildError{Path: path, Reason: "duplicate event"}
+		}
+
+		event, err := currEvent.build(b, path)
+		if err != nil {
+			return nil, err
+		}
+
+		events[currEvent.name] = event
+	}
+
+	if len(properties) > 0 {
+		document["properties"] = properties
+	}
+
+	if len(actions) > 0 {
+		document["actions"] = actions
+	}
+
+	if len(events) > 0 {
+		document["events"] = events
+	}
+
+	return document, nil
+}
+
+// checkSecurity checks that all names refer to security definitions
+func (b *ThingBuilder) checkSecurity(path string, names []string) error {
+	for _, currName := range names {
+		if _, ok := b.securityDefinitions[currName]; !ok {
+			return &BuildError{Path: path, Reason: fmt.Sprintf("unknown security definition %s", currName)}
+		}
+	}
+
+	return nil
+}
+
+// SecuritySchemeBuilder builds a security scheme
+type SecuritySchemeBuilder struct {
+	scheme map[string]interface{}
+}
+
+// NewSecurityScheme starts a security scheme like "basic", "bearer" or "oauth2"
+func NewSecurityScheme(scheme string) *SecuritySchemeBuilder {
+	return &SecuritySchemeBuilder{scheme: map[string]interface{}{"scheme": scheme}}
+}
+
+// WithIn sets where credentials are placed, e.g. "header", "query" or "cookie"
+func (b *SecuritySchemeBuilder) WithIn(in string) *SecuritySchemeBuilder {
+	b.scheme["in"] = in
+	return b
+}
+
+// WithName sets the name of the header, query parameter or cookie carrying the credentials
+func (b *SecuritySchemeBuilder) WithName(name string) *SecuritySchemeBuilder {
+	b.scheme["name"] = name
+	return b
+}
+
+// WithQOP sets the quality of protection of digest authentication
+func (b *SecuritySchemeBuilder) WithQOP(qop string) *SecuritySchemeBuilder {
+	b.scheme["qop"] = qop
+	return b
+}
+
+// WithFlow sets the oauth2 flow, e.g. "client"
+func (b *SecuritySchemeBuilder) WithFlow(flow string) *SecuritySchemeBuilder {
+	b.scheme["flow"] = flow
+	return b
+}
+
+// WithAuthorization sets the authorization endpoint of the scheme
+func (b *SecuritySchemeBuilder) WithAuthorization(authorization string) *SecuritySchemeBuilder {
+	b.scheme["authorization"] = authorization
+	return b
+}
+
+// WithToken sets the token endpoint of the scheme
+func (b *SecuritySchemeBuilder) WithToken(token string) *SecuritySchemeBuilder {
+	b.scheme["token"] = token
+	return b
+}
+
+// WithRefresh sets the refresh endpoint of the scheme
+func (b *SecuritySchemeBuilder) WithRefresh(refresh string) *SecuritySchemeBuilder {
+	b.scheme["refresh"] = refresh
+	return b
+}
+
+// WithScopes sets the scopes of the scheme
+func (b *SecuritySchemeBuilder) WithScopes(scopes ...string) *SecuritySchemeBuilder {
+	b.scheme["scopes"] = scopes
+	return b
+}
+
+func (b *SecuritySchemeBuilder) build(path string) (map[string]interface{}, error) {
+	scheme := copyMap(b.scheme)
+
+	switch scheme["scheme"] {
+	case "nosec", "basic", "digest", "bearer", "psk", "apikey":
+	case "oauth2":
+		if _, ok := scheme["flow"]; !ok {
+			return nil, &BuildError{Path: path, Reason: "oauth2 scheme requires a flow"}
+		}
+	default:
+		return nil, &BuildError{Path: path, Reason: fmt.Sprintf("unknown scheme %v", scheme["scheme"])}
+	}
+
+	return scheme, nil
+}
+
+// FormBuilder builds a form of an affordance
+type FormBuilder struct {
+	form map[string]interface{}
+}
+
+// NewForm starts a form with the given href. Relative hrefs are
+// resolved against the base of the thing
+func NewForm(href string) *FormBuilder {
+	return &FormBuilder{form: map[string]interface{}{"href": href}}
+}
+
+// WithOp sets the operation types of the form, e.g. "readproperty"
+func (b *FormBuilder) WithOp(ops ...string) *FormBuilder {
+	b.form["op"] = ops
+	return b
+}
+
+// WithContentType sets the content type of the form
+func (b *FormBuilder) WithContentType(contentType string) *FormBuilder {
+	b.form["contentType"] = contentType
+	return b
+}
+
+// WithSubprotocol sets the subprotocol of the form
+func (b *FormBuilder) WithSubprotocol(subprotocol string) *FormBuilder {
+	b.form["subprotocol"] = subprotocol
+	return b
+}
+
+// WithMethod sets the http method of the form
+func (b *FormBuilder) WithMethod(method string) *FormBuilder {
+	b.form["htv:methodName"] = method
+	return b
+}
+
+// WithSecurity sets the names of the security definitions of the form,
+// overriding the security of the thing
+func (b *FormBuilder) WithSecurity(names ...string) *FormBuilder {
+	b.form["security"] = names
+	return b
+}
+
+// WithScopes sets the oauth2 scopes required by the form
+func (b *FormBuilder) WithScopes(scopes ...string) *FormBuilder {
+	b.form["scopes"] = scopes
+	return b
+}
+
+// With sets a term of a protocol binding vocabulary, e.g. "mqv:topic"
+func (b *FormBuilder) With(term string, value interface{}) *FormBuilder {
+	b.form[term] = value
+	return b
+}
+
+func (b *FormBuilder) build(thing *ThingBuilder, path string) (map[string]interface{}, error) {
+	form := copyMap(b.form)
+
+	if href, _ := form["href"].(string); href == "" {
+		return nil, &BuildError{Path: path, Reason: "href is required"}
+	} else if _, err := url.Parse(href); err != nil {
+		return nil, &BuildError{Path: path, Reason: err.Error()}
+	}
+
+	if security, ok := form["security"].([]string); ok {
+		if err := thing.checkSecurity(path, security); err != nil {
+			return nil, err
+		}
+	}
+
+	return form, nil
+}
+
+// buildForms validates and assembles the forms of an affordance
+func buildForms(thing *ThingBuilder, path string, forms []*FormBuilder) ([]interface{}, error) {
+	if len(forms) == 0 {
+		return nil, &BuildError{Path: path + "/forms", Reason: "at least one form is required"}
+	}
+
+	result := make([]interface{}, len(forms))
+	for i, currForm := range forms {
+		form, err := currForm.build(thing, fmt.Sprintf("%s/forms/%d", path, i))
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = form
+	}
+
+	return result, nil
+}
+
+// PropertyBuilder builds a property affordance
+type PropertyBuilder struct {
+	name   string
+	schema *DataSchemaBuilder
+	forms  []*FormBuilder
+}
+
+// NewProperty starts a property with the given name and data schema
+func NewProperty(name string, schema *DataSchemaBuilder) *PropertyBuilder {
+	return &PropertyBuilder{name: name, schema: schema}
+}
+
+// WithType adds semantic types to the property
+func (b *PropertyBuilder) WithType(types ...string) *PropertyBuilder {
+	b.schema.WithType(types...)
+	return b
+}
+
+// Observable marks the property as observable
+func (b *PropertyBuilder) Observable() *PropertyBuilder {
+	b.schema.schema["observable"] = true
+	return b
+}
+
+// AddForm adds a form to the property
+func (b *PropertyBuilder) AddForm(form *FormBuilder) *PropertyBuilder {
+	b.forms = append(b.forms, form)
+	return b
+}
+
+func (b *PropertyBuilder) build(thing *ThingBuilder, path string) (map[string]interface{}, error) {
+	if b.schema == nil {
+		return nil, &BuildError{Path: path, Reason: "data schema is required"}
+	}
+
+	property, err := b.schema.build(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if property["forms"], err = buildForms(thing, path, b.forms); err != nil {
+		return nil, err
+	}
+
+	return property, nil
+}
+
+// ActionBuilder builds an action affordance
+type ActionBuilder struct {
+	action map[string]interface{}
+	name   string
+	input  *DataSchemaBuilder
+	output *DataSchemaBuilder
+	forms  []*FormBuilder
+}
+
+// NewAction starts an action with the given name
+func NewAction(name string) *ActionBuilder {
+	return &ActionBuilder{name: name, action: map[string]interface{}{}}
+}
+
+// WithType adds semantic types to the action
+func (b *ActionBuilder) WithType(types ...string) *ActionBuilder {
+	appendTypes(b.action, types)
+	return b
+}
+
+// WithInput sets the input schema of the action
+func (b *ActionBuilder) WithInput(schema *DataSchemaBuilder) *ActionBuilder {
+	b.input = schema
+	return b
+}
+
+// WithOutput sets the output schema of the action
+func (b *ActionBuilder) WithOutput(schema *DataSchemaBuilder) *ActionBuilder {
+	b.output = schema
+	return b
+}
+
+// Safe marks the action as safe
+func (b *ActionBuilder) Safe() *ActionBuilder {
+	b.action["safe"] = true
+	return b
+}
+
+// Idempotent marks the action as idempotent
+func (b *ActionBuilder) Idempotent() *ActionBuilder {
+	b.action["idempotent"] = true
+	return b
+}
+
+// AddForm adds a form to the action
+func (b *ActionBuilder) AddForm(form *FormBuilder) *ActionBuilder {
+	b.forms = append(b.forms, form)
+	return b
+}
+
+func (b *ActionBuilder) build(thing *ThingBuilder, path string) (map[string]interface{}, error) {
+	action := copyMap(b.action)
+
+	var err error
+	if b.input != nil {
+		if action["input"], err = b.input.build(path + "/input"); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.output != nil {
+		if action["output"], err = b.output.build(path + "/output"); err != nil {
+			return nil, err
+		}
+	}
+
+	if action["forms"], err = buildForms(thing, path, b.forms); err != nil {
+		return nil, err
+	}
+
+	return action, nil
+}
+
+// EventBuilder builds an event affordance
+type EventBuilder struct {
+	event map[string]interface{}
+	name  string
+	data  *DataSchemaBuilder
+	forms []*FormBuilder
+}
+
+// NewEvent starts an event with the given name
+func NewEvent(name string) *EventBuilder {
+	return &EventBuilder{name: name, event: map[string]interface{}{}}
+}
+
+// WithType adds semantic types to the event
+func (b *EventBuilder) WithType(types ...string) *EventBuilder {
+	appendTypes(b.event, types)
+	return b
+}
+
+// WithData sets the schema of the data emitted by the event
+func (b *EventBuilder) WithData(schema *DataSchemaBuilder) *EventBuilder {
+	b.data = schema
+	return b
+}
+
+// AddForm adds a form to the event
+func (b *EventBuilder) AddForm(form *FormBuilder) *EventBuilder {
+	b.forms = append(b.forms, form)
+	return b
+}
+
+func (b *EventBuilder) build(thing *ThingBuilder, path string) (map[string]interface{}, error) {
+	event := copyMap(b.event)
+
+	var err error
+	if b.data != nil {
+		if event["data"], err = b.data.build(path + "/data"); err != nil {
+			return nil, err
+		}
+	}
+
+	if event["forms"], err = buildForms(thing, path, b.forms); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// DataSchemaBuilder builds a data schema of a given data type
+type DataSchemaBuilder struct {
+	schema     map[string]interface{}
+	items      *DataSchemaBuilder
+	properties map[string]*DataSchemaBuilder
+}
+
+func newDataSchema(dataType string) *DataSchemaBuilder {
+	return &DataSchemaBuilder{schema: map[string]interface{}{"type": dataType}}
+}
+
+// ObjectSchema starts a schema of json objects
+func ObjectSchema() *DataSchemaBuilder {
+	s := newDataSchema("object")
+	s.properties = map[string]*DataSchemaBuilder{}
+	return s
+}
+
+// ArraySchema starts a schema of json arrays with the given item schema
+func ArraySchema(items *DataSchemaBuilder) *DataSchemaBuilder {
+	s := newDataSchema("array")
+	s.items = items
+	return s
+}
+
+// StringSchema starts a schema of strings
+func StringSchema() *DataSchemaBuilder {
+	return newDataSchema("string")
+}
+
+// NumberSchema starts a schema of numbers
+func NumberSchema() *DataSchemaBuilder {
+	return newDataSchema("number")
+}
+
+// IntegerSchema starts a schema of integers
+func IntegerSchema() *DataSchemaBuilder {
+	return newDataSchema("integer")
+}
+
+// BooleanSchema starts a schema of booleans
+func BooleanSchema() *DataSchemaBuilder {
+	return newDataSchema("boolean")
+}
+
+// NullSchema starts a schema of null
+func NullSchema() *DataSchemaBuilder {
+	return newDataSchema("null")
+}
+
+// WithType adds semantic types to the schema
+func (b *DataSchemaBuilder) WithType(types ...string) *DataSchemaBuilder {
+	appendTypes(b.schema, types)
+	return b
+}
+
+// WithTitle sets the title of the schema
+func (b *DataSchemaBuilder) WithTitle(title string) *DataSchemaBuilder {
+	b.schema["title"] = title
+	return b
+}
+
+// WithDescription sets the description of the schema
+func (b *DataSchemaBuilder) WithDescription(description string) *DataSchemaBuilder {
+	b.schema["description"] = description
+	return b
+}
+
+// WithMinimum sets the minimum of number and integer schemas
+func (b *DataSchemaBuilder) WithMinimum(minimum float64) *DataSchemaBuilder {
+	b.schema["minimum"] = minimum
+	return b
+}
+
+// WithMaximum sets the maximum of number and integer schemas
+func (b *DataSchemaBuilder) WithMaximum(maximum float64) *DataSchemaBuilder {
+	b.schema["maximum"] = maximum
+	return b
+}
+
+// WithMinLength sets the minimum length of string schemas
+func (b *DataSchemaBuilder) WithMinLength(minLength int) *DataSchemaBuilder {
+	b.schema["minLength"] = minLength
+	return b
+}
+
+// WithMaxLength sets the maximum length of string schemas
+func (b *DataSchemaBuilder) WithMaxLength(maxLength int) *DataSchemaBuilder {
+	b.schema["maxLength"] = maxLength
+	return b
+}
+
+// WithMinItems sets the minimum number of items of array schemas
+func (b *DataSchemaBuilder) WithMinItems(minItems int) *DataSchemaBuilder {
+	b.schema["minItems"] = minItems
+	return b
+}
+
+// WithMaxItems sets the maximum number of items of array schemas
+func (b *DataSchemaBuilder) WithMaxItems(maxItems int) *DataSchemaBuilder {
+	b.schema["maxItems"] = maxItems
+	return b
+}
+
+// WithEnum restricts the values of the schema
+func (b *DataSchemaBuilder) WithEnum(values ...interface{}) *DataSchemaBuilder {
+	b.schema["enum"] = values
+	return b
+}
+
+// WithConst restricts the schema to a single value
+func (b *DataSchemaBuilder) WithConst(value interface{}) *DataSchemaBuilder {
+	b.schema["const"] = value
+	return b
+}
+
+// WithUnit sets the unit of the values of the schema
+func (b *DataSchemaBuilder) WithUnit(unit string) *DataSchemaBuilder {
+	b.schema["unit"] = unit
+	return b
+}
+
+// ReadOnly marks the schema as read only
+func (b *DataSchemaBuilder) ReadOnly() *DataSchemaBuilder {
+	b.schema["readOnly"] = true
+	return b
+}
+
+// WriteOnly marks the schema as write only
+func (b *DataSchemaBuilder) WriteOnly() *DataSchemaBuilder {
+	b.schema["writeOnly"] = true
+	return b
+}
+
+// AddProperty adds a property to an object schema
+func (b *DataSchemaBuilder) AddProperty(name string, schema *DataSchemaBuilder) *DataSchemaBuilder {
+	if b.properties == nil {
+		b.properties = map[string]*DataSchemaBuilder{}
+	}
+
+	b.properties[name] = schema
+	return b
+}
+
+// WithRequired sets the required properties of an object schema
+func (b *DataSchemaBuilder) WithRequired(names ...string) *DataSchemaBuilder {
+	b.schema["required"] = names
+	return b
+}
+
+func (b *DataSchemaBuilder) build(path string) (map[string]interface{}, error) {
+	schema := copyMap(b.schema)
+	dataType := schema["type"]
+
+	switch dataType {
+	case "number", "integer":
+		minimum, hasMinimum := schema["minimum"].(float64)
+		maximum, hasMaximum := schema["maximum"].(float64)
+		if hasMinimum && hasMaximum && minimum > maximum {
+			return nil, &BuildError{Path: path, Reason: "minimum is greater than maximum"}
+		}
+	case "array":
+		if b.items != nil {
+			items, err := b.items.build(path + "/items")
+			if err != nil {
+				return nil, err
+			}
+
+			schema["items"] = items
+		}
+	}
+
+	if len(b.properties) > 0 {
+		if dataType != "object" {
+			return nil, &BuildError{Path: path, Reason: "properties require an object schema"}
+		}
+
+		properties := map[string]interface{}{}
+		for name, currProperty := range b.properties {
+			property, err := currProperty.build(path + "/properties/" + name)
+			if err != nil {
+				return nil, err
+			}
+
+			properties[name] = property
+		}
+
+		schema["properties"] = properties
+	}
+
+	if required, ok := schema["required"].([]string); ok {
+		for _, currName := range required {
+			if _, ok := b.properties[currName]; !ok {
+				return nil, &BuildError{Path: path, Reason: fmt.Sprintf("required property %s is not defined", currName)}
+			}
+		}
+	}
+
+	readOnly, _ := schema["readOnly"].(bool)
+	writeOnly, _ := schema["writeOnly"].(bool)
+	if readOnly && writeOnly {
+		return nil, &BuildError{Path: path, Reason: "schema can not be read only and write only"}
+	}
+
+	return schema, nil
+}
+
+// appendTypes adds semantic types to the @type of a node
+func appendTypes(node map[string]interface{}, types []string) {
+	existing, _ := node["@type"].([]string)
+	node["@type"] = append(existing, types...)
+}
+
+// copyMap creates a shallow copy of a map
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+
+	return result
+}
@@ -0,0 +1,137 @@
+package wotlib
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func testThingBuilder() *ThingBuilder {
+	return NewThing("LightOne").
+		WithID("urn:dev:ops:32473-LightOne").
+		WithType("http://iotschema.org/Light").
+		WithBase("http://light.local/").
+		WithSecurityDefinition("basic_sc", NewSecurityScheme("basic").WithIn("header")).
+		WithSecurityDefinition("oauth_sc", NewSecurityScheme("oauth2").WithFlow("client").WithToken("http://auth.local/token")).
+		WithSecurity("basic_sc").
+		AddProperty(NewProperty("brightness", IntegerSchema().WithMinimum(0).WithMaximum(100)).
+			WithType("http://iotschema.org/Brightness").
+			Observable().
+			AddForm(NewForm("properties/brightness").WithOp("readproperty", "writeproperty")).
+			AddForm(NewForm("properties/brightness/observe").WithOp("observeproperty").WithSubprotocol("longpoll"))).
+		AddAction(NewAction("fade").
+			Idempotent().
+			WithInput(ObjectSchema().
+				AddProperty("to", IntegerSchema().WithMinimum(0).WithMaximum(100)).
+				AddProperty("duration", NumberSchema()).
+				WithRequired("to")).
+			AddForm(NewForm("actions/fade").WithSecurity("oauth_sc").WithMethod("PUT"))).
+		AddEvent(NewEvent("overheated").
+			WithData(NumberSchema()).
+			AddForm(NewForm("events/overheated").WithSubprotocol("sse")))
+}
+
+func TestThingBuilder(t *testing.T) {
+	td, err := testThingBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build td: %v", err)
+	}
+
+	if td.ID != "urn:dev:ops:32473-LightOne" || len(td.Type) != 1 || td.Type[0] != "http://iotschema.org/Light" {
+		t.Fatalf("Unexpected thing %s %v", td.ID, td.Type)
+	}
+
+	if len(td.Properties) != 1 || len(td.Actions) != 1 || len(td.Events) != 1 {
+		t.Fatalf("Unexpected affordances")
+	}
+
+	brightness := td.Properties[0]
+	if maximum, _ := brightness.Maximum.Value(); maximum != 100 || brightness.DataType.Value() != DataTypeInteger || !brightness.IsObservable.Value() {
+		t.Fatalf("Unexpected property %+v", brightness)
+	}
+
+	form, _ := brightness.Form.Find(OpObserveProperty)
+	if form.Href.Value() != "http://light.local/properties/brightness/observe" || form.Subprotocol.Value() != "longpoll" {
+		t.Fatalf("Unexpected form %+v", form)
+	}
+
+	if len(form.EffectiveSecurity) != 1 || form.EffectiveSecurity[0].Scheme.Value() != SecurityBasic {
+		t.Fatalf("Unexpected security %+v", form.EffectiveSecurity)
+	}
+
+	fade := td.Actions[0]
+	if err := fade.Input.Validate(map[string]interface{}{"duration": 2}); err == nil {
+		t.Fatalf("Expected missing required property to be rejected")
+	}
+
+	if fade.Form.Value().Method.Value() != "PUT" || fade.Form.Value().EffectiveSecurity[0].Scheme.Value() != SecurityOAuth2 {
+		t.Fatalf("Unexpected action form %+v", fade.Form.Value())
+	}
+
+	document, err := testThingBuilder().Document()
+	if err != nil {
+		t.Fatalf("Failed to build document: %v", err)
+	}
+
+	var compact map[string]interface{}
+	if err := json.Unmarshal(document, &compact); err != nil || compact["title"] != "LightOne" {
+		t.Fatalf("Unexpected document %s", document)
+	}
+}
+
+func TestThingBuilderValidation(t *testing.T) {
+	nosec := NewSecurityScheme("nosec")
+	form := NewForm("properties/p")
+
+	tests := []struct {
+		builder *ThingBuilder
+		path    string
+	}{
+		{builder: NewThing(""), path: "/title"},
+		{builder: NewThing("t"), path: "/securityDefinitions"},
+		{builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec), path: "/security"},
+		{builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("basic_sc"), path: "/security"},
+		{builder: NewThing("t").WithSecurityDefinition("a", NewSecurityScheme("oauth2")).WithSecurity("a"), path: "/securityDefinitions/a"},
+		{builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("nosec_sc").WithBase("relative/"), path: "/base"},
+		{
+			builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("nosec_sc").
+				AddProperty(NewProperty("p", IntegerSchema())),
+			path: "/properties/p/forms",
+		},
+		{
+			builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("nosec_sc").
+				AddProperty(NewProperty("p", IntegerSchema().WithMinimum(10).WithMaximum(1)).AddForm(form)),
+			path: "/properties/p",
+		},
+		{
+			builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("nosec_sc").
+				AddProperty(NewProperty("p", IntegerSchema()).AddForm(form)).
+				AddProperty(NewProperty("p", StringSchema()).AddForm(form)),
+			path: "/properties/p",
+		},
+		{
+			builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("nosec_sc").
+				AddAction(NewAction("a").WithInput(ObjectSchema().WithRequired("x")).AddForm(form)),
+			path: "/actions/a/input",
+		},
+		{
+			builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("nosec_sc").
+				AddAction(NewAction("a").AddForm(NewForm(""))),
+			path: "/actions/a/forms/0",
+		},
+		{
+			builder: NewThing("t").WithSecurityDefinition("nosec_sc", nosec).WithSecurity("nosec_sc").
+				AddEvent(NewEvent("e").AddForm(NewForm("events/e").WithSecurity("unknown"))),
+			path: "/events/e/forms/0",
+		},
+	}
+
+	for _, currTest := range tests {
+		_, err := currTest.builder.Document()
+
+		var buildErr *BuildError
+		if !errors.As(err, &buildErr) || buildErr.Path != currTest.path {
+			t.Fatalf("Expected build error at %s, got %v", currTest.path, err)
+		}
+	}
+}
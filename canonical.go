@@ -0,0 +1,124 @@
+package wotlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// CanonicalizationAlgorithm is the algorithm used to canonicalize tds
+const CanonicalizationAlgorithm = "URDNA2015"
+
+// Canonicalize returns the canonical n-quads of the td. The canonical form
+// doesn't depend on the order of members or the labels of blank nodes
+func (t ExpandedThingDescription) Canonicalize() ([]byte, error) {
+	node, err := t.node()
+	if err != nil {
+		return nil, err
+	}
+
+	return canonicalize(node)
+}
+
+// Hash returns the hex encoded sha-256 digest of the canonical form of the td.
+// Equal tds have the same hash
+func (t ExpandedThingDescription) Hash() (string, error) {
+	canonical, err := t.Canonicalize()
+	if err != nil {
+		return "", err
+	}
+
+	return hashCanonical(canonical), nil
+}
+
+// HashBytes returns the hex encoded sha-256 digest of the canonical form of
+// a td in any json-ld form. Unlike Hash it covers all members of the td,
+// not only the ones supported by ExpandedThingDescription
+func HashBytes(b []byte) (string, error) {
+	var input interface{}
+	if err := json.Unmarshal(b, &input); err != nil {
+		return "", err
+	}
+
+	canonical, err := canonicalize(input)
+	if err != nil {
+		return "", err
+	}
+
+	return hashCanonical(canonical), nil
+}
+
+func canonicalize(input interface{}) ([]byte, error) {
+	opts := DefaultJSONDLDOptions.Copy()
+	opts.Algorithm = CanonicalizationAlgorithm
+	opts.Format = "application/n-quads"
+
+	canonical, err := ld.NewJsonLdProcessor().Normalize(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := canonical.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected canonical form %T", canonical)
+	}
+
+	return []byte(s), nil
+}
+
+func hashCanonical(canonical []byte) string {
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// source is the document a td was expanded from. Unlike the hash of the td,
+// the hash of the document covers members the td doesn't support. It's
+// computed once and shared by all copies of the td
+type source struct {
+	document []byte
+	// fingerprint detects modifications of the td after it was expanded
+	fingerprint [sha256.Size]byte
+
+	once sync.Once
+	hash string
+	err  error
+}
+
+func newSource(document []byte, td ExpandedThingDescription) *source {
+	return &source{document: document, fingerprint: fingerprint(td)}
+}
+
+// WithDocument returns the td remembering the document it was expanded
+// from, like tds restored from a storage which keeps the document. Such tds
+// compare equal to tds expanded from an equal document
+func (t ExpandedThingDescription) WithDocument(document []byte) ExpandedThingDescription {
+	t.source = newSource(document, t)
+	return t
+}
+
+// contentHash returns the hash of the document the td was expanded from and
+// true, as long as the td wasn't modified since. Otherwise the td is hashed
+func (t ExpandedThingDescription) contentHash() (string, bool, error) {
+	if t.source == nil || fingerprint(t) != t.source.fingerprint {
+		hash, err := t.Hash()
+		return hash, false, err
+	}
+
+	t.source.once.Do(func() {
+		t.source.hash, t.source.err = HashBytes(t.source.document)
+	})
+
+	return t.source.hash, true, t.source.err
+}
+
+// fingerprint is a cheap digest of the supported members of a td. The id
+// isn't covered, so ids can be assigned to anonymous tds
+func fingerprint(td ExpandedThingDescription) [sha256.Size]byte {
+	td.ID = ""
+	b, _ := json.Marshal(td)
+	return sha256.Sum256(b)
+}
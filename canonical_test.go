@@ -0,0 +1,96 @@
+package wotlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	lamp := []byte(`{
+		"@context": "https://www.w3.org/2019/wot/td/v1",
+		"id": "urn:dev:ops:lamp",
+		"title": "Lamp",
+		"properties": {
+			"on": {"type": "boolean", "forms": [{"href": "http://lamp.local/on"}]},
+			"brightness": {"type": "integer", "forms": [{"href": "http://lamp.local/brightness"}]}
+		}
+	}`)
+
+	reordered := []byte(`{
+		"properties": {
+			"brightness": {"forms": [{"href": "http://lamp.local/brightness"}], "type": "integer"},
+			"on": {"forms": [{"href": "http://lamp.local/on"}], "type": "boolean"}
+		},
+		"title": "Lamp",
+		"id": "urn:dev:ops:lamp",
+		"@context": "https://www.w3.org/2019/wot/td/v1"
+	}`)
+
+	changed := []byte(strings.Replace(string(lamp), `"Lamp"`, `"Lamp 2"`, 1))
+
+	tests := []struct {
+		a     []byte
+		b     []byte
+		equal bool
+	}{
+		{lamp, lamp, true},
+		{lamp, reordered, true},
+		{lamp, changed, false},
+	}
+
+	for i, currTest := range tests {
+		hashA, err := HashBytes(currTest.a)
+		if err != nil {
+			t.Fatalf("Test %d: failed to hash td: %v", i, err)
+		}
+
+		hashB, err := HashBytes(currTest.b)
+		if err != nil {
+			t.Fatalf("Test %d: failed to hash td: %v", i, err)
+		}
+
+		if (hashA == hashB) != currTest.equal || len(hashA) != 64 {
+			t.Fatalf("Test %d: expected equal hashes to be %v, got %s and %s", i, currTest.equal, hashA, hashB)
+		}
+
+		tdA, err := FromBytes(currTest.a)
+		if err != nil {
+			t.Fatalf("Test %d: failed to build expanded td: %v", i, err)
+		}
+
+		tdB, err := FromBytes(currTest.b)
+		if err != nil {
+			t.Fatalf("Test %d: failed to build expanded td: %v", i, err)
+		}
+
+		// affordances are in a different order after expansion
+		hashA, errA := tdA.Hash()
+		hashB, errB := tdB.Hash()
+		if errA != nil || errB != nil || (hashA == hashB) != currTest.equal {
+			t.Fatalf("Test %d: expected equal hashes of expanded tds to be %v, got %s and %s (%v, %v)", i, currTest.equal, hashA, hashB, errA, errB)
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	td, err := FromBytes(testTDOne)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	canonical, err := td.Canonicalize()
+	if err != nil {
+		t.Fatalf("Failed to canonicalize td: %v", err)
+	}
+
+	if !strings.Contains(string(canonical), "_:c14n0") || strings.Contains(string(canonical), "_:b0") {
+		t.Fatalf("Expected canonical blank node labels, got\n%s", canonical)
+	}
+
+	// anonymous tds are canonicalized as blank nodes
+	td.ID = ""
+	canonical, err = td.Canonicalize()
+	if err != nil || !strings.Contains(string(canonical), `<https://www.w3.org/2019/wot/td#title> "LightOne"`) {
+		t.Fatalf("Expected anonymous td to be canonicalized, got %s (%v)", canonical, err)
+	}
+}
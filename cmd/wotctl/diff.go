@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kinds of changes
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// change is a difference between two tds
+type change struct {
+	Change string      `json:"change"`
+	Path   string      `json:"path"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+func runDiff(env *environment, args []string) int {
+	fs, opts := newFlagSet(env, "diff", true)
+	if !parseFlags(env, fs, opts, args) {
+		return exitUsage
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var documents []map[string]interface{}
+	for _, currPath := range fs.Args() {
+		inputs, err := readInputs(env, []string{currPath})
+		if err != nil {
+			fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+			return exitFindings
+		}
+
+		if len(inputs) != 1 {
+			fmt.Fprintf(env.stderr, "wotctl: %s contains %d tds, expected one\n", currPath, len(inputs))
+			return exitUsage
+		}
+
+		if _, err := inputs[0].expand(); err != nil {
+			fmt.Fprintf(env.stderr, "wotctl: %s: %v\n", currPath, err)
+			return exitFindings
+		}
+
+		document, _ := inputs[0].document()
+		documents = append(documents, document)
+	}
+
+	changes := diff("", documents[0], documents[1])
+
+	var err error
+	if opts.format == formatJSON {
+		err = writeJSON(env.stdout, changes)
+	} else if len(changes) > 0 {
+		rows := make([][]string, len(changes))
+		for i, currChange := range changes {
+			rows[i] = []string{currChange.Change, currChange.Path, displayValue(currChange.Old), displayValue(currChange.New)}
+		}
+
+		err = writeTable(env.stdout, []string{"CHANGE", "PATH", "OLD", "NEW"}, rows)
+	}
+
+	if err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+		return exitFindings
+	}
+
+	if len(changes) > 0 {
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+// diff compares two json values. Objects are compared member by member, so
+// affordances are matched by name. Arrays are compared element by element.
+// Single values equal to an array containing only them, like ops often are
+func diff(path string, old, new interface{}) []change {
+	changes := []change{}
+
+	oldObject, oldIsObject := old.(map[string]interface{})
+	newObject, newIsObject := new.(map[string]interface{})
+	if oldIsObject && newIsObject {
+		keys := map[string]bool{}
+		for key := range oldObject {
+			keys[key] = true
+		}
+
+		for key := range newObject {
+			keys[key] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+
+		sort.Strings(sorted)
+
+		for _, currKey := range sorted {
+			memberPath := path + "/" + escapePointer(currKey)
+			oldValue, inOld := oldObject[currKey]
+			newValue, inNew := newObject[currKey]
+
+			switch {
+			case !inOld:
+				changes = append(changes, change{Change: changeAdded, Path: memberPath, New: newValue})
+			case !inNew:
+				changes = append(changes, change{Change: changeRemoved, Path: memberPath, Old: oldValue})
+			default:
+				changes = append(changes, diff(memberPath, oldValue, newValue)...)
+			}
+		}
+
+		return changes
+	}
+
+	oldArray, oldIsArray := asArray(old)
+	newArray, newIsArray := asArray(new)
+	if oldIsArray && newIsArray && (isArray(old) || isArray(new)) {
+		for i := 0; i < len(oldArray) || i < len(newArray); i++ {
+			elementPath := path + "/" + strconv.Itoa(i)
+
+			switch {
+			case i >= len(oldArray):
+				changes = append(changes, change{Change: changeAdded, Path: elementPath, New: newArray[i]})
+			case i >= len(newArray):
+				changes = append(changes, change{Change: changeRemoved, Path: elementPath, Old: oldArray[i]})
+			default:
+				changes = append(changes, diff(elementPath, oldArray[i], newArray[i])...)
+			}
+		}
+
+		return changes
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		changes = append(changes, change{Change: changeChanged, Path: path, Old: old, New: new})
+	}
+
+	return changes
+}
+
+// asArray returns arrays as they are and wraps scalar values
+func asArray(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case map[string]interface{}:
+		return nil, false
+	}
+
+	return []interface{}{value}, true
+}
+
+func isArray(value interface{}) bool {
+	_, ok := value.([]interface{})
+	return ok
+}
+
+// escapePointer escapes a member name for json pointers
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// displayValue formats a value for tables
+func displayValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+)
+
+func runExpand(env *environment, args []string) int {
+	return transform(env, "expand", args, func(i input) (interface{}, error) {
+		return i.expand()
+	})
+}
+
+func runCompact(env *environment, args []string) int {
+	return transform(env, "compact", args, func(i input) (interface{}, error) {
+		td, err := i.expand()
+		if err != nil {
+			return nil, err
+		}
+
+		return td.Compact()
+	})
+}
+
+// transform prints the transformed tds, a single one as object and
+// multiple ones as array
+func transform(env *environment, name string, args []string, f func(i input) (interface{}, error)) int {
+	fs, opts := newFlagSet(env, name, false)
+	if !parseFlags(env, fs, opts, args) {
+		return exitUsage
+	}
+
+	inputs, err := readInputs(env, fs.Args())
+	if err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+		return exitFindings
+	}
+
+	var results []interface{}
+	for _, currInput := range inputs {
+		result, err := f(currInput)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "wotctl: %s: %v\n", currInput.source, err)
+			return exitFindings
+		}
+
+		results = append(results, result)
+	}
+
+	var output interface{} = results
+	if len(results) == 1 {
+		output = results[0]
+	}
+
+	if err := writeJSON(env.stdout, output); err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+		return exitFindings
+	}
+
+	return exitOK
+}
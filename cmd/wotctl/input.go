@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/connctd/wotlib"
+)
+
+// stdinPath names stdin as input
+const stdinPath = "-"
+
+// input is a td read from a file or stdin
+type input struct {
+	source string
+	raw    []byte
+}
+
+// document returns the td as generic json object
+func (i input) document() (map[string]interface{}, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(i.raw, &document); err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// expand returns the expanded td
+func (i input) expand() (wotlib.ExpandedThingDescription, error) {
+	return wotlib.FromBytes(i.raw)
+}
+
+// readInputs reads all tds of the given paths. Directories are searched
+// recursively for .json and .jsonld files. Without paths stdin is read
+func readInputs(env *environment, paths []string) ([]input, error) {
+	if len(paths) == 0 {
+		paths = []string{stdinPath}
+	}
+
+	var inputs []input
+	for _, currPath := range paths {
+		if currPath == stdinPath {
+			raw, err := ioutil.ReadAll(env.stdin)
+			if err != nil {
+				return nil, err
+			}
+
+			inputs = append(inputs, input{source: "<stdin>", raw: raw})
+			continue
+		}
+
+		info, err := os.Stat(currPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			raw, err := ioutil.ReadFile(currPath)
+			if err != nil {
+				return nil, err
+			}
+
+			inputs = append(inputs, input{source: currPath, raw: raw})
+			continue
+		}
+
+		// walk visits files in lexical order
+		err = filepath.Walk(currPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" && ext != ".jsonld" {
+				return nil
+			}
+
+			raw, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			inputs = append(inputs, input{source: path, raw: raw})
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no thing descriptions found in %s", strings.Join(paths, ", "))
+	}
+
+	return inputs, nil
+}
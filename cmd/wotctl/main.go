@@ -0,0 +1,158 @@
+// Command wotctl works with thing descriptions on the command line.
+//
+//	wotctl expand lamp.json
+//	wotctl compact lamp.json
+//	wotctl validate things/
+//	wotctl query -c 'property.type=iot:SwitchStatus' things/
+//	wotctl diff old.json new.json
+//	wotctl lint -o json lamp.json
+//
+// Thing descriptions are read from files, directories (all .json and .jsonld
+// files) or stdin if no path or "-" is given
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/connctd/wotlib"
+)
+
+// exit codes
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitUsage    = 2
+)
+
+// output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// command is a subcommand of wotctl
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(env *environment, args []string) int
+}
+
+// commands are initialized by init as their flag sets refer to them
+var commands []command
+
+func init() {
+	commands = []command{
+		{"expand", "expand [flags] [path...]", "prints the expanded json-ld form of tds", runExpand},
+		{"compact", "compact [flags] [path...]", "prints tds compacted with the default context", runCompact},
+		{"validate", "validate [flags] [path...]", "reports errors making tds unusable", runValidate},
+		{"query", "query [flags] -c constraint [path...]", "lists things, properties or actions matching a constraint", runQuery},
+		{"diff", "diff [flags] old new", "reports the differences between two tds", runDiff},
+		{"lint", "lint [flags] [path...]", "reports errors and questionable constructs of tds", runLint},
+	}
+}
+
+// environment contains the streams of a run
+type environment struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &environment{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+func run(args []string, env *environment) int {
+	if len(args) == 0 {
+		usage(env.stderr)
+		return exitUsage
+	}
+
+	for _, currCommand := range commands {
+		if currCommand.name == args[0] {
+			return currCommand.run(env, args[1:])
+		}
+	}
+
+	if args[0] != "help" && args[0] != "-h" && args[0] != "-help" {
+		fmt.Fprintf(env.stderr, "wotctl: unknown command %s\n", args[0])
+	}
+
+	usage(env.stderr)
+
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: wotctl <command> [flags] [path...]\n\nCommands:\n")
+	for _, currCommand := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", currCommand.name, currCommand.description)
+	}
+}
+
+// options shared by all commands
+type options struct {
+	format string
+}
+
+// newFlagSet creates the flags of a command including the shared ones. Commands
+// producing tds always print json and don't offer an output format
+func newFlagSet(env *environment, name string, formats bool) (*flag.FlagSet, *options) {
+	opts := &options{format: formatJSON}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	if formats {
+		fs.StringVar(&opts.format, "o", formatTable, "output format, table or json")
+	}
+
+	fs.Var(prefixFlag{}, "prefix", "additional prefix like iot=http://iotschema.org/ used for compact iris, may be repeated")
+	fs.Usage = func() {
+		for _, currCommand := range commands {
+			if currCommand.name == name {
+				fmt.Fprintf(env.stderr, "Usage: wotctl %s\n\n%s\n\nFlags:\n", currCommand.usage, currCommand.description)
+			}
+		}
+
+		fs.PrintDefaults()
+	}
+
+	return fs, opts
+}
+
+// parseFlags parses the arguments of a command and checks the output format
+func parseFlags(env *environment, fs *flag.FlagSet, opts *options, args []string) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+
+	if opts.format != formatTable && opts.format != formatJSON {
+		fmt.Fprintf(env.stderr, "wotctl: unknown output format %s\n", opts.format)
+		return false
+	}
+
+	return true
+}
+
+// prefixFlag appends schemas to the default context of the wotlib
+type prefixFlag struct{}
+
+func (prefixFlag) String() string {
+	return ""
+}
+
+func (prefixFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected prefix=iri, got %s", value)
+	}
+
+	wotlib.AppendSchema(wotlib.SchemaMapping{Prefix: wotlib.SchemaPrefix(parts[0]), IRI: parts[1]})
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/wottest"
+)
+
+func init() {
+	wotlib.DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
+
+// runCommand runs wotctl and returns the exit code and the output
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+
+	code := run(args, &environment{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	lamp, err := ioutil.ReadFile("testdata/things/lamp.json")
+	if err != nil {
+		t.Fatalf("Failed to read td: %v", err)
+	}
+
+	testCases := []struct {
+		args     []string
+		stdin    string
+		code     int
+		expected []string
+	}{
+		{
+			args:     []string{"expand"},
+			stdin:    string(lamp),
+			code:     exitOK,
+			expected: []string{`"@id": "urn:dev:ops:32473-LampOne"`, `"https://www.w3.org/2019/wot/td#hasPropertyAffordance"`},
+		},
+		{
+			args:     []string{"compact", "-"},
+			stdin:    string(lamp),
+			code:     exitOK,
+			expected: []string{`"@context"`, `"wot:hasPropertyAffordance"`},
+		},
+		{
+			args:     []string{"validate", "testdata/things"},
+			code:     exitOK,
+			expected: []string{},
+		},
+		{
+			args: []string{"validate", "testdata"},
+			code: exitFindings,
+			expected: []string{
+				"testdata/invalid.json  error     /actions/reset/forms             missing forms",
+				"relative href status without base",
+				"operation type invokeaction is not allowed here",
+				"unknown security definition basic_sc",
+				"/title                           missing title",
+			},
+		},
+		{
+			args: []string{"lint", "testdata/invalid.json"},
+			code: exitFindings,
+			expected: []string{
+				"warning   /id                              thing has no id",
+				"security definition nosec_sc disables security",
+				"read only property is writable",
+				"observable property has no form to observe it",
+				"property has no data type",
+			},
+		},
+		{
+			args: []string{"query", "-c", "property.type=iot:SwitchStatus", "testdata/things"},
+			code: exitOK,
+			expected: []string{
+				"urn:dev:ops:32473-LampOne   property  on    http://iotschema.org/SwitchStatus",
+				"urn:dev:ops:4711-SwitchOne  property  on    http://iotschema.org/SwitchStatus",
+			},
+		},
+		{
+			args:     []string{"query", "-c", `{"type": ["iot:Light"], "actionConstraint": {"isIdempotent": false}}`, "testdata/things"},
+			code:     exitOK,
+			expected: []string{"urn:dev:ops:32473-LampOne  action  toggle  http://iotschema.org/Toggle"},
+		},
+		{
+			args:     []string{"query", "-c", "property.dataType=integer", "-o", "json", "testdata/things"},
+			code:     exitOK,
+			expected: []string{`"kind": "property"`, `"name": "brightness"`},
+		},
+		{
+			args:     []string{"query", "-c", "color=red", "testdata/things"},
+			code:     exitUsage,
+			expected: []string{},
+		},
+		{
+			args: []string{"diff", "testdata/things/lamp.json", "testdata/lamp-v2.json"},
+			code: exitFindings,
+			expected: []string{
+				`added    /actions/setColor`,
+				`removed  /actions/toggle`,
+				`changed  /description                    "A dimmable lamp"`,
+				`changed  /properties/brightness/maximum  100`,
+			},
+		},
+		{
+			args:     []string{"diff", "testdata/things/lamp.json", "testdata/things/lamp.json"},
+			code:     exitOK,
+			expected: []string{},
+		},
+		{
+			args:     []string{"unknown"},
+			code:     exitUsage,
+			expected: []string{},
+		},
+	}
+
+	for _, currTestCase := range testCases {
+		code, stdout, stderr := runCommand(currTestCase.stdin, currTestCase.args...)
+		if code != currTestCase.code {
+			t.Fatalf("%v: expected exit code %d, got %d\n%s%s", currTestCase.args, currTestCase.code, code, stdout, stderr)
+		}
+
+		for _, currExpected := range currTestCase.expected {
+			if !strings.Contains(stdout, currExpected) {
+				t.Fatalf("%v: expected output to contain %q:\n%s", currTestCase.args, currExpected, stdout)
+			}
+		}
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	_, stdout, _ := runCommand("", "diff", "-o", "json", "testdata/things/lamp.json", "testdata/lamp-v2.json")
+
+	var changes []change
+	if err := json.Unmarshal([]byte(stdout), &changes); err != nil {
+		t.Fatalf("Failed to decode changes: %v", err)
+	}
+
+	// the op of the sse form changed from a string to an array, which is equivalent
+	if len(changes) != 4 {
+		t.Fatalf("Expected 4 changes, got %+v", changes)
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	c, err := parseConstraint("type=iot:Light, type=iot:Dimmer property.observable=true action.input.data.dataType=number")
+	if err != nil {
+		t.Fatalf("Failed to parse constraint: %v", err)
+	}
+
+	if len(*c.Type) != 2 || (*c.Type)[1] != wotlib.ExpandIRI("iot:Dimmer") {
+		t.Fatalf("Unexpected types %v", *c.Type)
+	}
+
+	if !*c.PropertyConstraint.IsObservable {
+		t.Fatalf("Expected observable property constraint")
+	}
+
+	if dataType := *c.ActionConstraint.InputConstraint.DataPropertyConstraint.DataType; dataType != wotlib.DataTypeNumber {
+		t.Fatalf("Unexpected data type %s", dataType)
+	}
+
+	if _, err := parseConstraint("property.observable=maybe"); err == nil {
+		t.Fatalf("Expected invalid boolean to fail")
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/connctd/wotlib"
+)
+
+// match is a thing or affordance matching a query
+type match struct {
+	Thing  string   `json:"thing"`
+	Source string   `json:"source"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Types  []string `json:"types"`
+}
+
+func runQuery(env *environment, args []string) int {
+	fs, opts := newFlagSet(env, "query", true)
+	constraint := fs.String("c", "", "constraint as json object or terms like 'type=iot:Light property.observable=true'")
+	if !parseFlags(env, fs, opts, args) {
+		return exitUsage
+	}
+
+	inputs, err := readInputs(env, fs.Args())
+	if err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+		return exitFindings
+	}
+
+	learnPrefixes(inputs)
+
+	c, err := parseConstraint(*constraint)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: invalid constraint: %v\n", err)
+		return exitUsage
+	}
+
+	matches := []match{}
+	for _, currInput := range inputs {
+		td, err := currInput.expand()
+		if err != nil {
+			fmt.Fprintf(env.stderr, "wotctl: %s: %v\n", currInput.source, err)
+			return exitFindings
+		}
+
+		matches = append(matches, query(td, currInput.source, c)...)
+	}
+
+	if opts.format == formatJSON {
+		err = writeJSON(env.stdout, matches)
+	} else {
+		rows := make([][]string, len(matches))
+		for i, currMatch := range matches {
+			rows[i] = []string{currMatch.Thing, currMatch.Kind, currMatch.Name, strings.Join(currMatch.Types, ", ")}
+		}
+
+		err = writeTable(env.stdout, []string{"THING", "KIND", "NAME", "TYPES"}, rows)
+	}
+
+	if err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+// query searches the td and returns the matching thing or, if the constraint
+// contains property or action constraints, the matching affordances
+func query(td wotlib.ExpandedThingDescription, source string, c wotlib.ThingConstraint) []match {
+	set := wotlib.ExpandedThingDescriptionSet{td.ID: td}
+
+	var matches []match
+	for _, currResult := range set.Search(c) {
+		result := match{Thing: currResult.ThingID, Source: source, Kind: currResult.Kind, Name: currResult.Name}
+
+		switch currResult.Kind {
+		case wotlib.ResultKindThing:
+			result.Name, result.Types = td.Name.Value(), td.Type
+		case wotlib.ResultKindProperty:
+			for _, currProperty := range td.GetPropertyAffordances(wotlib.PropertyConstraint{Name: &currResult.Name}) {
+				result.Types = currProperty.Type
+			}
+		case wotlib.ResultKindAction:
+			for _, currAction := range td.GetActionAffordances(wotlib.ActionConstraint{Name: &currResult.Name}) {
+				result.Types = currAction.Type
+			}
+		}
+
+		matches = append(matches, result)
+	}
+
+	return matches
+}
+
+// learnPrefixes appends the prefixes defined by the contexts of the tds to the
+// default context, so constraints can use them. Known prefixes are kept
+func learnPrefixes(inputs []input) {
+	for _, currInput := range inputs {
+		document, err := currInput.document()
+		if err != nil {
+			continue
+		}
+
+		contexts, ok := document["@context"].([]interface{})
+		if !ok {
+			contexts = []interface{}{document["@context"]}
+		}
+
+		for _, currContext := range contexts {
+			definitions, _ := currContext.(map[string]interface{})
+			for prefix, currIRI := range definitions {
+				iri, ok := currIRI.(string)
+				if _, known := wotlib.DefaultContext[prefix]; known || !ok || !strings.HasSuffix(iri, "/") && !strings.HasSuffix(iri, "#") {
+					continue
+				}
+
+				wotlib.AppendSchema(wotlib.SchemaMapping{Prefix: wotlib.SchemaPrefix(prefix), IRI: iri})
+			}
+		}
+	}
+}
+
+// parseConstraint parses a constraint given as json object or as terms. Compact
+// iris and short data types like "integer" are expanded
+func parseConstraint(s string) (wotlib.ThingConstraint, error) {
+	var c wotlib.ThingConstraint
+
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "{") {
+		if err := json.Unmarshal([]byte(s), &c); err != nil {
+			return c, err
+		}
+	} else {
+		for _, currTerm := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if err := applyTerm(&c, currTerm); err != nil {
+				return c, err
+			}
+		}
+	}
+
+	expandConstraint(&c)
+
+	return c, nil
+}
+
+// applyTerm applies a term like "property.type=iot:SwitchStatus" to the constraint.
+// Repeated types have to be contained all
+func applyTerm(c *wotlib.ThingConstraint, term string) error {
+	parts := strings.SplitN(term, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected key=value, got %s", term)
+	}
+
+	key, value := parts[0], parts[1]
+
+	property := func() *wotlib.PropertyConstraint {
+		if c.PropertyConstraint == nil {
+			c.PropertyConstraint = &wotlib.PropertyConstraint{}
+		}
+
+		return c.PropertyConstraint
+	}
+
+	action := func() *wotlib.ActionConstraint {
+		if c.ActionConstraint == nil {
+			c.ActionConstraint = &wotlib.ActionConstraint{}
+		}
+
+		return c.ActionConstraint
+	}
+
+	input := func() *wotlib.InputConstraint {
+		if action().InputConstraint == nil {
+			action().InputConstraint = &wotlib.InputConstraint{}
+		}
+
+		return action().InputConstraint
+	}
+
+	propertyData := func() *wotlib.DataPropertyConstraint {
+		if property().DataPropertyConstraint == nil {
+			property().DataPropertyConstraint = &wotlib.DataPropertyConstraint{}
+		}
+
+		return property().DataPropertyConstraint
+	}
+
+	inputData := func() *wotlib.DataPropertyConstraint {
+		if input().DataPropertyConstraint == nil {
+			input().DataPropertyConstraint = &wotlib.DataPropertyConstraint{}
+		}
+
+		return input().DataPropertyConstraint
+	}
+
+	var err error
+	switch key {
+	case "id":
+		c.ID = &value
+	case "name":
+		c.Name = &value
+	case "type":
+		c.Type = appendType(c.Type, value)
+	case "property.name":
+		property().Name = &value
+	case "property.type":
+		property().Type = appendType(property().Type, value)
+	case "property.dataType":
+		property().DataType = &value
+	case "property.observable":
+		property().IsObservable, err = parseBool(value)
+	case "property.data.name":
+		propertyData().Name = &value
+	case "property.data.type":
+		propertyData().Type = appendType(propertyData().Type, value)
+	case "property.data.dataType":
+		propertyData().DataType = &value
+	case "action.name":
+		action().Name = &value
+	case "action.type":
+		action().Type = appendType(action().Type, value)
+	case "action.idempotent":
+		action().IsIdempotent, err = parseBool(value)
+	case "action.safe":
+		action().IsSafe, err = parseBool(value)
+	case "action.input.dataType":
+		input().DataType = &value
+	case "action.input.data.name":
+		inputData().Name = &value
+	case "action.input.data.type":
+		inputData().Type = appendType(inputData().Type, value)
+	case "action.input.data.dataType":
+		inputData().DataType = &value
+	default:
+		return fmt.Errorf("unknown key %s", key)
+	}
+
+	return err
+}
+
+func appendType(types *[]string, value string) *[]string {
+	if types == nil {
+		types = &[]string{}
+	}
+
+	result := append(*types, value)
+
+	return &result
+}
+
+func parseBool(value string) (*bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// expandConstraint expands all types and data types of the constraint
+func expandConstraint(c *wotlib.ThingConstraint) {
+	expandTypes(c.Type)
+
+	if p := c.PropertyConstraint; p != nil {
+		expandTypes(p.Type)
+		expandDataType(p.DataType)
+		expandDataProperty(p.DataPropertyConstraint)
+	}
+
+	if a := c.ActionConstraint; a != nil {
+		expandTypes(a.Type)
+		if a.InputConstraint != nil {
+			expandDataType(a.InputConstraint.DataType)
+			expandDataProperty(a.InputConstraint.DataPropertyConstraint)
+		}
+	}
+}
+
+func expandDataProperty(c *wotlib.DataPropertyConstraint) {
+	if c != nil {
+		expandTypes(c.Type)
+		expandDataType(c.DataType)
+	}
+}
+
+func expandTypes(types *[]string) {
+	if types == nil {
+		return
+	}
+
+	for i := range *types {
+		(*types)[i] = wotlib.ExpandIRI((*types)[i])
+	}
+}
+
+// data types by their names in compact tds
+var dataTypes = map[string]string{
+	"object":  wotlib.DataTypeObject,
+	"array":   wotlib.DataTypeArray,
+	"string":  wotlib.DataTypeString,
+	"number":  wotlib.DataTypeNumber,
+	"integer": wotlib.DataTypeInteger,
+	"boolean": wotlib.DataTypeBoolean,
+	"null":    wotlib.DataTypeNull,
+}
+
+func expandDataType(dataType *string) {
+	if dataType == nil {
+		return
+	}
+
+	if expanded, ok := dataTypes[*dataType]; ok {
+		*dataType = expanded
+		return
+	}
+
+	*dataType = wotlib.ExpandIRI(*dataType)
+}
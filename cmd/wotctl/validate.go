@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// severities of issues
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// issue is a problem found in a td
+type issue struct {
+	Source   string `json:"source"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// checker collects the issues of a single td
+type checker struct {
+	source string
+	issues []issue
+}
+
+func (c *checker) report(severity string, path string, format string, args ...interface{}) {
+	c.issues = append(c.issues, issue{
+		Source:   c.source,
+		Severity: severity,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func runValidate(env *environment, args []string) int {
+	return check(env, "validate", args, false)
+}
+
+func runLint(env *environment, args []string) int {
+	return check(env, "lint", args, true)
+}
+
+// check reports the errors and optionally the warnings of all tds. Any issue
+// leads to a non zero exit code
+func check(env *environment, name string, args []string, warnings bool) int {
+	fs, opts := newFlagSet(env, name, true)
+	if !parseFlags(env, fs, opts, args) {
+		return exitUsage
+	}
+
+	inputs, err := readInputs(env, fs.Args())
+	if err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+		return exitFindings
+	}
+
+	issues := []issue{}
+	for _, currInput := range inputs {
+		c := &checker{source: currInput.source}
+		c.validate(currInput)
+		if warnings {
+			c.lint(currInput)
+		}
+
+		issues = append(issues, c.issues...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Source != issues[j].Source {
+			return issues[i].Source < issues[j].Source
+		}
+
+		return issues[i].Path < issues[j].Path
+	})
+
+	if opts.format == formatJSON {
+		err = writeJSON(env.stdout, issues)
+	} else if len(issues) > 0 {
+		rows := make([][]string, len(issues))
+		for i, currIssue := range issues {
+			rows[i] = []string{currIssue.Source, currIssue.Severity, displayPath(currIssue.Path), currIssue.Message}
+		}
+
+		err = writeTable(env.stdout, []string{"SOURCE", "SEVERITY", "PATH", "MESSAGE"}, rows)
+	}
+
+	if err != nil {
+		fmt.Fprintf(env.stderr, "wotctl: %v\n", err)
+		return exitFindings
+	}
+
+	if len(issues) > 0 {
+		return exitFindings
+	}
+
+	return exitOK
+}
+
+// affordance types and the operation types allowed for them
+var affordanceOps = map[string][]string{
+	"properties": {"readproperty", "writeproperty", "observeproperty", "unobserveproperty"},
+	"actions":    {"invokeaction"},
+	"events":     {"subscribeevent", "unsubscribeevent"},
+}
+
+// validate reports the errors of a td: missing mandatory members, unknown
+// security definitions, affordances without forms and invalid hrefs
+func (c *checker) validate(i input) {
+	document, err := i.document()
+	if err != nil {
+		c.report(severityError, "", "invalid json: %v", err)
+		return
+	}
+
+	if _, err := i.expand(); err != nil {
+		c.report(severityError, "", "expansion failed: %v", err)
+	}
+
+	if _, ok := document["@context"]; !ok {
+		c.report(severityError, "/@context", "missing @context")
+	}
+
+	if title, ok := document["title"].(string); !ok || title == "" {
+		c.report(severityError, "/title", "missing title")
+	}
+
+	definitions, _ := document["securityDefinitions"].(map[string]interface{})
+	if len(definitions) == 0 {
+		c.report(severityError, "/securityDefinitions", "missing security definitions")
+	}
+
+	for _, currName := range sortedKeys(definitions) {
+		definition, _ := definitions[currName].(map[string]interface{})
+		if scheme, ok := definition["scheme"].(string); !ok || scheme == "" {
+			c.report(severityError, "/securityDefinitions/"+currName, "missing scheme")
+		}
+	}
+
+	if security, ok := document["security"]; !ok {
+		c.report(severityError, "/security", "missing security")
+	} else {
+		c.checkSecurity("/security", security, definitions)
+	}
+
+	base, _ := url.Parse(stringOf(document["base"]))
+
+	for _, currKind := range sortedKeys(affordanceOps) {
+		affordances, _ := document[currKind].(map[string]interface{})
+		for _, currName := range sortedKeys(affordances) {
+			path := "/" + currKind + "/" + currName
+			affordance, _ := affordances[currName].(map[string]interface{})
+
+			forms, _ := affordance["forms"].([]interface{})
+			if len(forms) == 0 {
+				c.report(severityError, path+"/forms", "missing forms")
+			}
+
+			for j, currForm := range forms {
+				c.checkForm(path+"/forms/"+strconv.Itoa(j), currForm, affordanceOps[currKind], base, definitions)
+			}
+		}
+	}
+}
+
+func (c *checker) checkForm(path string, value interface{}, allowedOps []string, base *url.URL, definitions map[string]interface{}) {
+	form, _ := value.(map[string]interface{})
+
+	href, ok := form["href"].(string)
+	if !ok || href == "" {
+		c.report(severityError, path+"/href", "missing href")
+	} else if u, err := url.Parse(href); err != nil {
+		c.report(severityError, path+"/href", "invalid href: %v", err)
+	} else if !u.IsAbs() && (base == nil || !base.IsAbs()) {
+		c.report(severityError, path+"/href", "relative href %s without base", href)
+	}
+
+	for _, currOp := range stringsOf(form["op"]) {
+		if !contains(allowedOps, currOp) {
+			c.report(severityError, path+"/op", "operation type %s is not allowed here", currOp)
+		}
+	}
+
+	if security, ok := form["security"]; ok {
+		c.checkSecurity(path+"/security", security, definitions)
+	}
+}
+
+func (c *checker) checkSecurity(path string, security interface{}, definitions map[string]interface{}) {
+	names := stringsOf(security)
+	if len(names) == 0 {
+		c.report(severityError, path, "no security definition is referenced")
+	}
+
+	for _, currName := range names {
+		if _, ok := definitions[currName]; !ok {
+			c.report(severityError, path, "unknown security definition %s", currName)
+		}
+	}
+}
+
+// lint reports questionable constructs which are valid but likely unintended
+func (c *checker) lint(i input) {
+	document, err := i.document()
+	if err != nil {
+		return
+	}
+
+	if id := stringOf(document["id"]); id == "" {
+		c.report(severityWarning, "/id", "thing has no id")
+	} else if u, err := url.Parse(id); err != nil || !u.IsAbs() {
+		c.report(severityWarning, "/id", "id %s is not a uri", id)
+	}
+
+	if stringOf(document["description"]) == "" {
+		c.report(severityWarning, "/description", "thing has no description")
+	}
+
+	// schemes sending credentials which must be protected by tls
+	definitions, _ := document["securityDefinitions"].(map[string]interface{})
+	credentials := false
+	for _, currName := range stringsOf(document["security"]) {
+		definition, _ := definitions[currName].(map[string]interface{})
+		switch stringOf(definition["scheme"]) {
+		case "nosec":
+			c.report(severityWarning, "/security", "security definition %s disables security", currName)
+		case "basic", "bearer", "apikey", "oauth2":
+			credentials = true
+		}
+	}
+
+	targets := map[string]string{}
+	for _, currKind := range sortedKeys(affordanceOps) {
+		affordances, _ := document[currKind].(map[string]interface{})
+		for _, currName := range sortedKeys(affordances) {
+			path := "/" + currKind + "/" + currName
+			affordance, _ := affordances[currName].(map[string]interface{})
+
+			if currKind == "properties" {
+				c.lintProperty(path, affordance)
+			}
+
+			forms, _ := affordance["forms"].([]interface{})
+			for j, currForm := range forms {
+				formPath := path + "/forms/" + strconv.Itoa(j)
+				form, _ := currForm.(map[string]interface{})
+				href := stringOf(form["href"])
+
+				if credentials && strings.HasPrefix(href, "http://") {
+					c.report(severityWarning, formPath+"/href", "credentials are sent without tls")
+				}
+
+				ops := stringsOf(form["op"])
+				if len(ops) == 0 {
+					ops = []string{""}
+				}
+
+				for _, currOp := range ops {
+					target := href + " " + strings.Join(stringsOf(form["htv:methodName"]), "") + " " + currOp
+					if previous, ok := targets[target]; ok && previous != path {
+						c.report(severityWarning, formPath, "target is used by %s as well", previous)
+					}
+
+					targets[target] = path
+				}
+			}
+		}
+	}
+}
+
+func (c *checker) lintProperty(path string, property map[string]interface{}) {
+	if _, ok := property["type"]; !ok {
+		c.report(severityWarning, path, "property has no data type")
+	}
+
+	forms, _ := property["forms"].([]interface{})
+	observable := false
+	for j, currForm := range forms {
+		form, _ := currForm.(map[string]interface{})
+		ops := stringsOf(form["op"])
+
+		if property["readOnly"] == true && contains(ops, "writeproperty") {
+			c.report(severityWarning, path+"/forms/"+strconv.Itoa(j)+"/op", "read only property is writable")
+		}
+
+		if property["writeOnly"] == true && contains(ops, "readproperty") {
+			c.report(severityWarning, path+"/forms/"+strconv.Itoa(j)+"/op", "write only property is readable")
+		}
+
+		observable = observable || contains(ops, "observeproperty")
+	}
+
+	if property["observable"] == true && !observable {
+		c.report(severityWarning, path, "observable property has no form to observe it")
+	}
+}
+
+// displayPath returns a path suitable for tables
+func displayPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
+
+func stringOf(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// stringsOf returns the strings of a value which is either a string or an array
+func stringsOf(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, currValue := range v {
+			if s, ok := currValue.(string); ok {
+				result = append(result, s)
+			}
+		}
+
+		return result
+	}
+
+	return nil
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch v := m.(type) {
+	case map[string]interface{}:
+		for key := range v {
+			keys = append(keys, key)
+		}
+	case map[string][]string:
+		for key := range v {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, currValue := range values {
+		if currValue == value {
+			return true
+		}
+	}
+
+	return false
+}
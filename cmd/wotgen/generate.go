@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/connctd/wotlib"
+)
+
+// generator collects the declarations of a generated client
+type generator struct {
+	methods bytes.Buffer
+	types   bytes.Buffer
+
+	// names of generated identifiers to detect collisions
+	names map[string]string
+}
+
+// generate generates the source of a typed client package for a td
+func generate(pkg string, td wotlib.ExpandedThingDescription) ([]byte, error) {
+	g := &generator{names: map[string]string{}}
+
+	properties := append([]wotlib.ExpandedPropertyAffordance{}, td.Properties...)
+	sort.Slice(properties, func(i, j int) bool { return properties[i].Name.Value() < properties[j].Name.Value() })
+
+	for _, currProperty := range properties {
+		if err := g.property(currProperty); err != nil {
+			return nil, err
+		}
+	}
+
+	actions := append([]wotlib.ExpandedActionAffordance{}, td.Actions...)
+	sort.Slice(actions, func(i, j int) bool { return actions[i].Name.Value() < actions[j].Name.Value() })
+
+	for _, currAction := range actions {
+		if err := g.action(currAction); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, header, pkg, td.ID)
+	out.Write(g.methods.Bytes())
+	out.Write(g.types.Bytes())
+	out.WriteString(helpers)
+
+	return format.Source(out.Bytes())
+}
+
+func (g *generator) property(property wotlib.ExpandedPropertyAffordance) error {
+	name := property.Name.Value()
+	ident := exportedName(name)
+
+	typeName, err := g.goType(ident+"Value", property.ExpandedDataSchema)
+	if err != nil {
+		return fmt.Errorf("property %s: %w", name, err)
+	}
+
+	if _, ok := property.Form.Find(wotlib.OpReadProperty); ok && !property.WriteOnly.Value() {
+		if err := g.declare("Read"+ident, "property "+name); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&g.methods, `
+// Read%[1]s reads the property %[2]s
+func (c *Client) Read%[1]s(ctx context.Context) (%[3]s, error) {
+	var result %[3]s
+
+	property, err := c.property(%[2]q)
+	if err != nil {
+		return result, err
+	}
+
+	value, err := c.consumer.ReadProperty(ctx, property)
+	if err != nil {
+		return result, err
+	}
+
+	err = convert(value, &result)
+
+	return result, err
+}
+`, ident, name, typeName)
+	}
+
+	if _, ok := property.Form.Find(wotlib.OpWriteProperty); ok && !property.ReadOnly.Value() {
+		if err := g.declare("Write"+ident, "property "+name); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&g.methods, `
+// Write%[1]s writes the property %[2]s
+func (c *Client) Write%[1]s(ctx context.Context, value %[3]s) error {
+	property, err := c.property(%[2]q)
+	if err != nil {
+		return err
+	}
+
+	return c.consumer.WriteProperty(ctx, property, value)
+}
+`, ident, name, typeName)
+	}
+
+	return nil
+}
+
+func (g *generator) action(action wotlib.ExpandedActionAffordance) error {
+	name := action.Name.Value()
+	ident := exportedName(name)
+
+	if err := g.declare(ident, "action "+name); err != nil {
+		return err
+	}
+
+	params := "ctx context.Context"
+	input := "nil"
+	if len(action.Input) > 0 {
+		inputType, err := g.goType(ident+"Input", action.Input.Value())
+		if err != nil {
+			return fmt.Errorf("action %s: %w", name, err)
+		}
+
+		params += ", input " + inputType
+		input = "input"
+	}
+
+	if len(action.Output) == 0 {
+		fmt.Fprintf(&g.methods, `
+// %[1]s invokes the action %[2]s
+func (c *Client) %[1]s(%[3]s) error {
+	action, err := c.action(%[2]q)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.consumer.InvokeAction(ctx, action, %[4]s)
+
+	return err
+}
+`, ident, name, params, input)
+
+		return nil
+	}
+
+	outputType, err := g.goType(ident+"Output", action.Output.Value())
+	if err != nil {
+		return fmt.Errorf("action %s: %w", name, err)
+	}
+
+	fmt.Fprintf(&g.methods, `
+// %[1]s invokes the action %[2]s
+func (c *Client) %[1]s(%[3]s) (%[5]s, error) {
+	var result %[5]s
+
+	action, err := c.action(%[2]q)
+	if err != nil {
+		return result, err
+	}
+
+	output, err := c.consumer.InvokeAction(ctx, action, %[4]s)
+	if err != nil {
+		return result, err
+	}
+
+	err = convert(output, &result)
+
+	return result, err
+}
+`, ident, name, params, input, outputType)
+
+	return nil
+}
+
+// goType returns the go type of a data schema. Objects with properties are
+// declared as structs with the given name
+func (g *generator) goType(name string, schema wotlib.ExpandedDataSchema) (string, error) {
+	switch schema.DataType.Value() {
+	case wotlib.DataTypeBoolean:
+		return "bool", nil
+	case wotlib.DataTypeInteger:
+		return "int64", nil
+	case wotlib.DataTypeNumber:
+		return "float64", nil
+	case wotlib.DataTypeString:
+		return "string", nil
+	case wotlib.DataTypeArray:
+		itemType, err := g.goType(name+"Item", schema.Items.Value())
+		if err != nil {
+			return "", err
+		}
+
+		return "[]" + itemType, nil
+	case wotlib.DataTypeObject:
+		if len(schema.Properties) == 0 {
+			return "map[string]interface{}", nil
+		}
+
+		return name, g.declareStruct(name, schema)
+	}
+
+	return "interface{}", nil
+}
+
+// declareStruct declares a struct with a field per property of an object schema.
+// Optional fields are omitted if empty, scalar ones are pointers
+func (g *generator) declareStruct(name string, schema wotlib.ExpandedDataSchema) error {
+	if err := g.declare(name, "type"); err != nil {
+		return err
+	}
+
+	required := map[string]bool{}
+	for _, currName := range schema.Required.Values() {
+		required[currName] = true
+	}
+
+	properties := append([]wotlib.ExpandedDataProperty{}, schema.Properties...)
+	sort.Slice(properties, func(i, j int) bool { return properties[i].Name.Value() < properties[j].Name.Value() })
+
+	var fields bytes.Buffer
+	for _, currProperty := range properties {
+		propertyName := currProperty.Name.Value()
+		fieldName := exportedName(propertyName)
+
+		fieldType, err := g.goType(name+fieldName, currProperty.ExpandedDataSchema)
+		if err != nil {
+			return fmt.Errorf("%s: %w", propertyName, err)
+		}
+
+		tag := propertyName
+		if !required[propertyName] {
+			tag += ",omitempty"
+			if !strings.HasPrefix(fieldType, "[]") && !strings.HasPrefix(fieldType, "map[") && fieldType != "interface{}" {
+				fieldType = "*" + fieldType
+			}
+		}
+
+		fmt.Fprintf(&fields, "\t%s %s `json:%q`\n", fieldName, fieldType, tag)
+	}
+
+	fmt.Fprintf(&g.types, "\n// %s is a generated data schema\ntype %s struct {\n%s}\n", name, name, fields.String())
+
+	return nil
+}
+
+// declare reserves an identifier and fails if it is used already
+func (g *generator) declare(ident string, origin string) error {
+	if existing, ok := g.names[ident]; ok {
+		return fmt.Errorf("%s and %s both map to %s", existing, origin, ident)
+	}
+
+	g.names[ident] = origin
+
+	return nil
+}
+
+// exportedName converts names like "set-color" or "setColor" to "SetColor"
+func exportedName(name string) string {
+	var b strings.Builder
+
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteRune('X')
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		b.WriteRune(r)
+	}
+
+	if b.Len() == 0 {
+		return "X"
+	}
+
+	return b.String()
+}
+
+const header = `// Code generated by wotgen. DO NOT EDIT.
+
+package %s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/consumer"
+)
+
+// ErrUnknownAffordance is returned if the td of the client lacks an affordance
+var ErrUnknownAffordance = errors.New("unknown affordance")
+
+// Client is a typed client for the thing %q
+type Client struct {
+	consumer *consumer.Consumer
+	td       wotlib.ExpandedThingDescription
+}
+
+// NewClient creates a client for the thing described by the td
+func NewClient(c *consumer.Consumer, td wotlib.ExpandedThingDescription) *Client {
+	return &Client{consumer: c, td: td}
+}
+`
+
+const helpers = `
+func (c *Client) property(name string) (wotlib.ExpandedPropertyAffordance, error) {
+	for _, currProperty := range c.td.Properties {
+		if currProperty.Name.Value() == name {
+			return currProperty, nil
+		}
+	}
+
+	return wotlib.ExpandedPropertyAffordance{}, fmt.Errorf("%w: property %s", ErrUnknownAffordance, name)
+}
+
+func (c *Client) action(name string) (wotlib.ExpandedActionAffordance, error) {
+	for _, currAction := range c.td.Actions {
+		if currAction.Name.Value() == name {
+			return currAction, nil
+		}
+	}
+
+	return wotlib.ExpandedActionAffordance{}, fmt.Errorf("%w: action %s", ErrUnknownAffordance, name)
+}
+
+// convert converts decoded json values into typed values
+func convert(value interface{}, target interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
+`
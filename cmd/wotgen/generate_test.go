@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/wottest"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func init() {
+	wotlib.DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
+
+func readTD(t *testing.T, path string) wotlib.ExpandedThingDescription {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read td: %v", err)
+	}
+
+	td, err := wotlib.FromBytes(b)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	return td
+}
+
+func TestGenerate(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		t.Fatalf("Failed to list test data: %v", err)
+	}
+
+	for _, currInput := range inputs {
+		name := strings.TrimSuffix(filepath.Base(currInput), ".json")
+		golden := filepath.Join("testdata", name+".golden")
+
+		code, err := generate(name, readTD(t, currInput))
+		if err != nil {
+			t.Fatalf("Failed to generate code for %s: %v", name, err)
+		}
+
+		if *update {
+			if err := ioutil.WriteFile(golden, code, 0644); err != nil {
+				t.Fatalf("Failed to update golden file: %v", err)
+			}
+		}
+
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("Failed to read golden file: %v", err)
+		}
+
+		if !bytes.Equal(expected, code) {
+			t.Fatalf("Generated code for %s differs from %s:\n%s", name, golden, code)
+		}
+
+		typeCheck(t, name, code)
+	}
+}
+
+// typeCheck checks that generated code compiles against the packages
+// of this module
+func typeCheck(t *testing.T, name string, code []byte) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, name+".go", code, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse generated code for %s: %v", name, err)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check(name, fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("Generated code for %s doesn't compile: %v", name, err)
+	}
+}
+
+func TestGenerateNameCollision(t *testing.T) {
+	td := readTD(t, "testdata/lamp.json")
+	td.Actions = append(td.Actions, td.Actions[0])
+	td.Actions[len(td.Actions)-1].Name = wotlib.StringNode{{Value: "set_color"}}
+
+	if _, err := generate("lamp", td); err == nil {
+		t.Fatalf("Expected an error for colliding names")
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"brightness", "Brightness"},
+		{"setColor", "SetColor"},
+		{"set-color", "SetColor"},
+		{"on_off", "OnOff"},
+		{"2nd", "X2nd"},
+		{"-", "X"},
+	}
+
+	for _, currTestCase := range testCases {
+		if actual := exportedName(currTestCase.name); actual != currTestCase.expected {
+			t.Fatalf("Expected %s for %s, got %s", currTestCase.expected, currTestCase.name, actual)
+		}
+	}
+}
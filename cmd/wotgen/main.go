@@ -0,0 +1,61 @@
+// Command wotgen generates a typed go client from a thing description.
+//
+//	wotgen -package lamp -o lamp/client.go lamp.td.json
+//
+// The generated client offers a method per readable or writable property and per
+// action. Input and output schemas of objects become go structs. Requests are
+// performed by the consumer of the wotlib
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/connctd/wotlib"
+)
+
+func main() {
+	pkg := flag.String("package", "client", "name of the generated package")
+	output := flag.String("o", "", "file to write the generated code to, defaults to stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: wotgen [flags] <td file>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *pkg, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "wotgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(input string, pkg string, output string) error {
+	b, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	td, err := wotlib.FromBytes(b)
+	if err != nil {
+		return err
+	}
+
+	code, err := generate(pkg, td)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+
+	return ioutil.WriteFile(output, code, 0644)
+}
@@ -0,0 +1,54 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/connctd/wotlib"
+)
+
+// InvokeAction invokes an action. The input is validated against the input
+// schema of the action and serialized according to the content type of the form.
+// The output is decoded and validated against the output schema. Failed requests
+// are only retried if the action is safe or idempotent
+func (c *Consumer) InvokeAction(ctx context.Context, action wotlib.ExpandedActionAffordance, input interface{}) (interface{}, error) {
+	form, b, err := c.selectForm(action.Form, wotlib.OpInvokeAction, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var in Payload
+	if len(action.Input) > 0 || input != nil {
+		if err := action.Input.Validate(input); err != nil {
+			return nil, &SchemaError{Affordance: action.Name.Value(), Direction: DirectionInput, Err: err}
+		}
+
+		body, err := marshalPayload(form.ContentType.Value(), input)
+		if err != nil {
+			return nil, err
+		}
+
+		in = Payload{ContentType: contentTypeOf(form), Body: body}
+	}
+
+	retryable := action.IsIdempotent.Value() || action.IsSafe.Value()
+
+	out, err := c.request(ctx, b, form, wotlib.OpInvokeAction, in, retryable)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out.Body) == 0 {
+		return nil, nil
+	}
+
+	output, err := unmarshalPayload(out.ContentType, out.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := action.Output.Validate(output); err != nil {
+		return nil, &SchemaError{Affordance: action.Name.Value(), Direction: DirectionOutput, Err: err}
+	}
+
+	return output, nil
+}
@@ -0,0 +1,178 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/connctd/wotlib"
+)
+
+func TestInvokeAction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/actions/setColor" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected content type %s", r.Header.Get("Content-Type"))
+		}
+
+		var input map[string]int
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input["red"] != 255 {
+			t.Errorf("Unexpected input %v: %v", input, err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"done"}`))
+	}))
+	defer server.Close()
+
+	action := findAction(t, testThing(t, server.URL), "setColor")
+
+	output, err := NewConsumer().InvokeAction(context.Background(), action, map[string]int{"red": 255, "green": 0, "blue": 0})
+	if err != nil {
+		t.Fatalf("Failed to invoke action: %v", err)
+	}
+
+	if output.(map[string]interface{})["status"] != "done" {
+		t.Fatalf("Unexpected output %v", output)
+	}
+}
+
+func TestInvokeActionUsesFormMethod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Unexpected method %s", r.Method)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	action := findAction(t, testThing(t, server.URL), "toggle")
+
+	output, err := NewConsumer().InvokeAction(context.Background(), action, nil)
+	if err != nil {
+		t.Fatalf("Failed to invoke action: %v", err)
+	}
+
+	if output != nil {
+		t.Fatalf("Expected no output, got %v", output)
+	}
+}
+
+func TestInvokeActionSchemaViolations(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"unknown"}`))
+	}))
+	defer server.Close()
+
+	action := findAction(t, testThing(t, server.URL), "setColor")
+	consumer := NewConsumer()
+
+	var tests = []struct {
+		Name      string
+		Input     interface{}
+		Direction Direction
+		Path      string
+	}{
+		{
+			Name:      "Missing required property",
+			Input:     map[string]int{"red": 1, "green": 2},
+			Direction: DirectionInput,
+		},
+		{
+			Name:      "Value out of range",
+			Input:     map[string]int{"red": 256, "green": 0, "blue": 0},
+			Direction: DirectionInput,
+			Path:      "/red",
+		},
+		{
+			Name:      "Wrong type",
+			Input:     map[string]interface{}{"red": 1.5, "green": 0, "blue": 0},
+			Direction: DirectionInput,
+			Path:      "/red",
+		},
+		{
+			Name:      "Invalid output",
+			Input:     map[string]int{"red": 1, "green": 2, "blue": 3},
+			Direction: DirectionOutput,
+			Path:      "/status",
+		},
+	}
+
+	for _, currTest := range tests {
+		t.Run(currTest.Name, func(t *testing.T) {
+			_, err := consumer.InvokeAction(context.Background(), action, currTest.Input)
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("Expected schema error, got %v", err)
+			}
+
+			if schemaErr.Direction != currTest.Direction {
+				t.Fatalf("Expected %s violation, got %s", currTest.Direction, schemaErr.Direction)
+			}
+
+			var validationErr *wotlib.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Path != currTest.Path {
+				t.Fatalf("Unexpected validation error %v", err)
+			}
+		})
+	}
+
+	if requests != 1 {
+		t.Fatalf("Only valid inputs should be sent, got %d requests", requests)
+	}
+}
+
+func TestInvokeActionRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"accepted"}`))
+	}))
+	defer server.Close()
+
+	td := testThing(t, server.URL)
+	consumer := NewConsumer(WithRetries(2, time.Millisecond))
+
+	// idempotent actions are retried
+	_, err := consumer.InvokeAction(context.Background(), findAction(t, td, "setColor"), map[string]int{"red": 1, "green": 2, "blue": 3})
+	if err != nil {
+		t.Fatalf("Expected retries to succeed: %v", err)
+	}
+
+	if requests != 3 {
+		t.Fatalf("Expected 3 requests, got %d", requests)
+	}
+
+	// other actions are not
+	atomic.StoreInt32(&requests, 0)
+	_, err = consumer.InvokeAction(context.Background(), findAction(t, td, "toggle"), nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected http error, got %v", err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("Expected a single request, got %d", requests)
+	}
+}
@@ -0,0 +1,202 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/retry"
+)
+
+// ProtocolBinding performs the operations described by forms of a specific
+// protocol. Bindings are registered at a consumer for the uri schemes and
+// subprotocols they serve
+type ProtocolBinding interface {
+	// Request performs a request-response like operation. The input
+	// may be empty if the operation does not transfer a value
+	Request(ctx context.Context, form wotlib.ExpandedForm, op string, input Payload) (Payload, error)
+
+	// Subscribe delivers payloads until the context is done. The
+	// returned channel is closed afterwards
+	Subscribe(ctx context.Context, form wotlib.ExpandedForm, op string) (<-chan Message, error)
+}
+
+// Payload is a serialized value
+type Payload struct {
+	ContentType string
+	Body        []byte
+}
+
+// Message is a payload received by a subscription or the reason
+// why receiving failed
+type Message struct {
+	Payload
+	Err error
+}
+
+// bindingKey identifies the forms served by a binding
+type bindingKey struct {
+	scheme      string
+	subprotocol string
+}
+
+func newBindingKey(scheme, subprotocol string) bindingKey {
+	return bindingKey{scheme: strings.ToLower(scheme), subprotocol: wotlib.ExpandIRI(subprotocol)}
+}
+
+// WithBinding registers a binding for forms with the given uri scheme and
+// subprotocol. An empty subprotocol matches forms without subprotocol.
+// Bindings registered this way replace the built-in ones
+func WithBinding(scheme, subprotocol string, b ProtocolBinding) Option {
+	return func(c *Consumer) {
+		c.RegisterBinding(scheme, subprotocol, b)
+	}
+}
+
+// RegisterBinding registers a binding for forms with the given uri scheme and
+// subprotocol, replacing a previously registered one. An empty subprotocol
+// matches forms without subprotocol. Compact subprotocols like "cov:observe"
+// are expanded if their prefix is part of the default context
+func (c *Consumer) RegisterBinding(scheme, subprotocol string, b ProtocolBinding) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.bindings[newBindingKey(scheme, subprotocol)] = b
+}
+
+// registerDefault registers a built-in binding unless another binding
+// was registered for the same scheme and subprotocol
+func (c *Consumer) registerDefault(b ProtocolBinding, subprotocols []string, schemes ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, currScheme := range schemes {
+		for _, currSubprotocol := range subprotocols {
+			key := newBindingKey(currScheme, currSubprotocol)
+			if _, ok := c.bindings[key]; !ok {
+				c.bindings[key] = b
+			}
+		}
+	}
+}
+
+// bindingFor returns the binding registered for the uri scheme of the
+// href and the subprotocol of a form
+func (c *Consumer) bindingFor(form wotlib.ExpandedForm) (ProtocolBinding, error) {
+	u, err := url.Parse(form.Href.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	b, ok := c.bindings[newBindingKey(u.Scheme, form.Subprotocol.Value())]
+	c.mutex.RUnlock()
+
+	if !ok {
+		if subprotocol := form.Subprotocol.Value(); subprotocol != "" {
+			return nil, fmt.Errorf("%w: scheme %s with subprotocol %s", ErrUnsupportedForm, u.Scheme, subprotocol)
+		}
+
+		return nil, fmt.Errorf("%w: scheme %s", ErrUnsupportedForm, u.Scheme)
+	}
+
+	return b, nil
+}
+
+// selectForm returns the first form supporting the operation which has a
+// registered binding. If explicit is set, forms without operation types
+// are skipped. ErrNoForm is returned if no form supports the operation at all
+func (c *Consumer) selectForm(forms wotlib.ExpandedFormNode, op string, explicit bool) (wotlib.ExpandedForm, ProtocolBinding, error) {
+	var firstErr error
+
+	for _, currForm := range forms {
+		if explicit && len(currForm.Op) == 0 || !currForm.HasOp(op) {
+			continue
+		}
+
+		b, err := c.bindingFor(currForm)
+		if err == nil {
+			return currForm, b, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = ErrNoForm
+	}
+
+	return wotlib.ExpandedForm{}, nil, firstErr
+}
+
+// request performs a request-response like operation. If retryable
+// is set temporary failures are retried
+func (c *Consumer) request(ctx context.Context, b ProtocolBinding, form wotlib.ExpandedForm, op string, input Payload, retryable bool) (Payload, error) {
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		output, err := b.Request(ctx, form, op, input)
+		if err == nil || !retryable || attempt >= c.maxRetries || !retry.Temporary(err) {
+			return output, err
+		}
+
+		// a missing retained value won't appear by asking again
+		if errors.Is(err, ErrNoRetainedValue) {
+			return output, err
+		}
+
+		if !retry.Sleep(ctx, delay) {
+			return Payload{}, ctx.Err()
+		}
+
+		delay = retry.Backoff(delay)
+	}
+}
+
+// subscribe decodes and validates the payloads received by a subscription
+func (c *Consumer) subscribe(ctx context.Context, b ProtocolBinding, form wotlib.ExpandedForm, op string, name string, schema wotlib.ExpandedDataSchema) (<-chan Notification, error) {
+	messages, err := b.Subscribe(ctx, form, op)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &notifier{
+		ctx:    ctx,
+		ch:     make(chan Notification),
+		name:   name,
+		schema: schema,
+	}
+
+	go func() {
+		defer close(n.ch)
+
+		for msg := range messages {
+			if msg.Err != nil {
+				if !n.send(Notification{Err: msg.Err}) {
+					return
+				}
+
+				continue
+			}
+
+			if !n.deliver(msg.ContentType, msg.Body) {
+				return
+			}
+		}
+	}()
+
+	return n.ch, nil
+}
+
+// contentTypeOf returns the content type of the form or the default content type
+func contentTypeOf(form wotlib.ExpandedForm) string {
+	if contentType := form.ContentType.Value(); contentType != "" {
+		return contentType
+	}
+
+	return DefaultContentType
+}
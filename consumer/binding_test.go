@@ -0,0 +1,113 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/connctd/wotlib"
+)
+
+// memoryBinding stores property values in memory
+type memoryBinding struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+}
+
+func (b *memoryBinding) Request(ctx context.Context, form wotlib.ExpandedForm, op string, input Payload) (Payload, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if op == wotlib.OpWriteProperty {
+		b.values[form.Href.Value()] = input.Body
+		return Payload{}, nil
+	}
+
+	return Payload{ContentType: contentTypeOf(form), Body: b.values[form.Href.Value()]}, nil
+}
+
+func (b *memoryBinding) Subscribe(ctx context.Context, form wotlib.ExpandedForm, op string) (<-chan Message, error) {
+	messages := make(chan Message, 1)
+	messages <- Message{Payload: Payload{ContentType: contentTypeOf(form), Body: []byte("99")}}
+	close(messages)
+
+	return messages, nil
+}
+
+func TestCustomBinding(t *testing.T) {
+	td, err := wotlib.FromBytes([]byte(testBindingTD))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	property := findProperty(t, td, "brightness")
+	memory := &memoryBinding{values: map[string][]byte{}}
+
+	// without registered binding no form can be used
+	if _, err := NewConsumer().ReadProperty(context.Background(), property); !errors.Is(err, ErrUnsupportedForm) {
+		t.Fatalf("Expected unsupported form error, got %v", err)
+	}
+
+	consumer := NewConsumer(WithBinding("mem", "", memory))
+
+	if err := consumer.WriteProperty(context.Background(), property, 42); err != nil {
+		t.Fatalf("Failed to write property: %v", err)
+	}
+
+	if string(memory.values["mem://lamp/brightness"]) != "42" {
+		t.Fatalf("Expected value to be written by the memory binding, got %v", memory.values)
+	}
+
+	value, err := consumer.ReadProperty(context.Background(), property)
+	if err != nil || value != float64(42) {
+		t.Fatalf("Unexpected value %v (%v)", value, err)
+	}
+
+	// the observe form uses a subprotocol which is not registered yet
+	if _, err := consumer.ObserveProperty(context.Background(), property); !errors.Is(err, ErrUnsupportedForm) {
+		t.Fatalf("Expected unsupported form error, got %v", err)
+	}
+
+	consumer.RegisterBinding("mem", "push", memory)
+
+	notifications, err := consumer.ObserveProperty(context.Background(), property)
+	if err != nil {
+		t.Fatalf("Failed to observe property: %v", err)
+	}
+
+	if notification := <-notifications; notification.Value != float64(99) {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+
+	if _, err := consumer.InvokeAction(context.Background(), wotlib.ExpandedActionAffordance{}, nil); !errors.Is(err, ErrNoForm) {
+		t.Fatalf("Expected no form error, got %v", err)
+	}
+}
+
+var testBindingTD = `{
+    "@context": "https://www.w3.org/2019/wot/td/v1",
+    "id": "urn:dev:ops:32473-LampMemory",
+    "title": "LampMemory",
+    "properties": {
+        "brightness": {
+            "type": "integer",
+            "observable": true,
+            "forms": [
+                {
+                    "op": ["readproperty", "writeproperty"],
+                    "href": "unknown://lamp/brightness"
+                },
+                {
+                    "op": ["readproperty", "writeproperty"],
+                    "href": "mem://lamp/brightness"
+                },
+                {
+                    "op": "observeproperty",
+                    "href": "mem://lamp/brightness",
+                    "subprotocol": "push"
+                }
+            ]
+        }
+    }
+}`
@@ -0,0 +1,160 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/coap"
+)
+
+// coapDefaultPort is used for coap hrefs without port
+const coapDefaultPort = "5683"
+
+// defaultCoAPMethods defines the methods used if a form does not specify one
+var defaultCoAPMethods = map[string]coap.Code{
+	wotlib.OpReadProperty:     coap.GET,
+	wotlib.OpWriteProperty:    coap.PUT,
+	wotlib.OpObserveProperty:  coap.GET,
+	wotlib.OpInvokeAction:     coap.POST,
+	wotlib.OpSubscribeEvent:   coap.GET,
+	wotlib.OpUnsubscribeEvent: coap.GET,
+}
+
+// coapBinding implements the coap protocol binding. Each operation uses its
+// own endpoint. Observations and event subscriptions register an observation
+// on the resource of the form
+type coapBinding struct{}
+
+func (b *coapBinding) Request(ctx context.Context, form wotlib.ExpandedForm, op string, input Payload) (Payload, error) {
+	client, req, err := b.connect(ctx, form, op, input)
+	if err != nil {
+		return Payload{}, err
+	}
+	defer client.Close()
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		return Payload{}, err
+	}
+
+	if resp.Code.Class() != 2 {
+		return Payload{}, &CoAPError{Method: req.Code.String(), URL: form.Href.Value(), Code: resp.Code, Body: resp.Payload}
+	}
+
+	return payloadOf(form, resp), nil
+}
+
+func (b *coapBinding) Subscribe(ctx context.Context, form wotlib.ExpandedForm, op string) (<-chan Message, error) {
+	if subprotocol := form.Subprotocol.Value(); subprotocol != "" && wotlib.ExpandIRI(subprotocol) != wotlib.CoAPObserve {
+		return nil, fmt.Errorf("%w: subprotocol %s", ErrUnsupportedForm, subprotocol)
+	}
+
+	client, req, err := b.connect(ctx, form, op, Payload{})
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := client.Observe(ctx, req)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	messages := make(chan Message)
+
+	go func() {
+		defer close(messages)
+		defer client.Close()
+
+		for resp := range received {
+			if resp.Code.Class() != 2 {
+				sendMessage(ctx, messages, Message{Err: &CoAPError{Method: req.Code.String(), URL: form.Href.Value(), Code: resp.Code, Body: resp.Payload}})
+				return
+			}
+
+			if len(resp.Payload) == 0 {
+				continue
+			}
+
+			if !sendMessage(ctx, messages, Message{Payload: payloadOf(form, resp)}) {
+				return
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+// connect creates a client for the endpoint of the form and the request
+// for the given operation
+func (b *coapBinding) connect(ctx context.Context, form wotlib.ExpandedForm, op string, input Payload) (*coap.Client, *coap.Message, error) {
+	u, err := url.Parse(form.Href.Value())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method, err := coapMethodOf(form, op)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := &coap.Message{Code: method, Payload: input.Body}
+	req.SetPath(u.Path)
+
+	if u.RawQuery != "" {
+		for _, currQuery := range strings.Split(u.RawQuery, "&") {
+			req.Options = append(req.Options, coap.Option{Number: coap.OptionURIQuery, Value: []byte(currQuery)})
+		}
+	}
+
+	contentType := contentTypeOf(form)
+	if format, ok := coap.ContentFormat(contentType); ok {
+		req.SetUintOption(coap.OptionAccept, format)
+		if len(input.Body) > 0 {
+			req.SetUintOption(coap.OptionContentFormat, format)
+		}
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), coapDefaultPort)
+	}
+
+	client, err := coap.Dial(ctx, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, req, nil
+}
+
+// coapMethodOf returns the method of the form or the default method of the operation
+func coapMethodOf(form wotlib.ExpandedForm, op string) (coap.Code, error) {
+	if method := form.CoAPMethod.Value(); method != "" {
+		code, ok := coap.ParseMethod(method)
+		if !ok {
+			return coap.Empty, fmt.Errorf("%w: coap method %s", ErrUnsupportedForm, method)
+		}
+
+		return code, nil
+	}
+
+	return defaultCoAPMethods[op], nil
+}
+
+// payloadOf returns the payload of a response. The content type defaults
+// to the one of the form if the response has no content format
+func payloadOf(form wotlib.ExpandedForm, resp *coap.Message) Payload {
+	contentType := contentTypeOf(form)
+	if format, ok := resp.UintOption(coap.OptionContentFormat); ok {
+		if mediaType, ok := coap.MediaType(format); ok {
+			contentType = mediaType
+		}
+	}
+
+	return Payload{ContentType: contentType, Body: resp.Payload}
+}
@@ -0,0 +1,208 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/coap"
+)
+
+func startCoAPServer(t *testing.T, handler coap.Handler) (*coap.Server, string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := coap.NewServer(handler)
+	go server.Serve(conn)
+
+	return server, conn.LocalAddr().String()
+}
+
+func testCoAPThing(t *testing.T, addr string) wotlib.ExpandedThingDescription {
+	td, err := wotlib.FromBytes([]byte(strings.Replace(testCoAPTD, "{{SERVER}}", addr, -1)))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	return td
+}
+
+func jsonResponse(code coap.Code, body string) *coap.Message {
+	resp := &coap.Message{Code: code, Payload: []byte(body)}
+	resp.SetUintOption(coap.OptionContentFormat, 50)
+
+	return resp
+}
+
+func TestCoAPProperties(t *testing.T) {
+	var mutex sync.Mutex
+	brightness := "50"
+
+	server, addr := startCoAPServer(t, func(req *coap.Message) *coap.Message {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if req.Path() != "/lamp/brightness" {
+			return &coap.Message{Code: coap.NotFound}
+		}
+
+		switch req.Code {
+		case coap.GET:
+			return jsonResponse(coap.Content, brightness)
+		case coap.PUT:
+			if format, _ := req.UintOption(coap.OptionContentFormat); format != 50 {
+				return &coap.Message{Code: coap.UnsupportedMediaType}
+			}
+
+			brightness = string(req.Payload)
+			return &coap.Message{Code: coap.Changed}
+		}
+
+		return &coap.Message{Code: coap.MethodNotAllowed}
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	property := findProperty(t, testCoAPThing(t, addr), "brightness")
+	consumer := NewConsumer()
+
+	if err := consumer.WriteProperty(ctx, property, 42); err != nil {
+		t.Fatalf("Failed to write property: %v", err)
+	}
+
+	value, err := consumer.ReadProperty(ctx, property)
+	if err != nil {
+		t.Fatalf("Failed to read property: %v", err)
+	}
+
+	if value != float64(42) {
+		t.Fatalf("Unexpected value %v", value)
+	}
+
+	notifications, err := consumer.ObserveProperty(ctx, property)
+	if err != nil {
+		t.Fatalf("Failed to observe property: %v", err)
+	}
+
+	if notification := <-notifications; notification.Value != float64(42) {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+
+	server.Notify("/lamp/brightness", 50, []byte("7"))
+
+	if notification := <-notifications; notification.Value != float64(7) {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+}
+
+func TestCoAPActions(t *testing.T) {
+	server, addr := startCoAPServer(t, func(req *coap.Message) *coap.Message {
+		switch req.Path() {
+		case "/lamp/toggle":
+			if req.Code != coap.POST || string(req.Payload) != `{"on":true}` {
+				return &coap.Message{Code: coap.BadRequest}
+			}
+
+			return jsonResponse(coap.Changed, `{"on":true}`)
+		case "/lamp/reset":
+			if req.Code != coap.PUT {
+				return &coap.Message{Code: coap.MethodNotAllowed}
+			}
+
+			return &coap.Message{Code: coap.ServiceUnavailable}
+		}
+
+		return &coap.Message{Code: coap.NotFound}
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	td := testCoAPThing(t, addr)
+	consumer := NewConsumer(WithRetries(1, time.Millisecond))
+
+	output, err := consumer.InvokeAction(ctx, findAction(t, td, "toggle"), map[string]bool{"on": true})
+	if err != nil {
+		t.Fatalf("Failed to invoke action: %v", err)
+	}
+
+	if state, ok := output.(map[string]interface{}); !ok || state["on"] != true {
+		t.Fatalf("Unexpected output %v", output)
+	}
+
+	_, err = consumer.InvokeAction(ctx, findAction(t, td, "reset"), nil)
+
+	var coapErr *CoAPError
+	if !errors.As(err, &coapErr) || coapErr.Code != coap.ServiceUnavailable || !coapErr.Temporary() {
+		t.Fatalf("Expected service unavailable error, got %v", err)
+	}
+}
+
+var testCoAPTD = `{
+    "@context": [
+        "https://www.w3.org/2019/wot/td/v1",
+        {
+            "cov": "http://www.example.org/coap-binding#"
+        }
+    ],
+    "id": "urn:dev:ops:32473-LampCoAP",
+    "title": "LampCoAP",
+    "properties": {
+        "brightness": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 100,
+            "observable": true,
+            "forms": [
+                {
+                    "op": ["readproperty", "writeproperty"],
+                    "href": "coap://{{SERVER}}/lamp/brightness"
+                },
+                {
+                    "op": "observeproperty",
+                    "href": "coap://{{SERVER}}/lamp/brightness",
+                    "subprotocol": "cov:observe"
+                }
+            ]
+        }
+    },
+    "actions": {
+        "toggle": {
+            "input": {
+                "type": "object",
+                "properties": {"on": {"type": "boolean"}}
+            },
+            "output": {
+                "type": "object",
+                "properties": {"on": {"type": "boolean"}}
+            },
+            "forms": [
+                {
+                    "op": "invokeaction",
+                    "href": "coap://{{SERVER}}/lamp/toggle",
+                    "cov:method": "POST"
+                }
+            ]
+        },
+        "reset": {
+            "idempotent": true,
+            "forms": [
+                {
+                    "op": "invokeaction",
+                    "href": "coap://{{SERVER}}/lamp/reset",
+                    "cov:method": "PUT"
+                }
+            ]
+        }
+    }
+}`
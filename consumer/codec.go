@@ -0,0 +1,16 @@
+package consumer
+
+import "github.com/connctd/wotlib/internal/codec"
+
+// DefaultContentType is used if a form does not define a content type
+const DefaultContentType = codec.DefaultContentType
+
+// marshalPayload serializes a value according to the given content type
+func marshalPayload(contentType string, value interface{}) ([]byte, error) {
+	return codec.Marshal(contentType, value)
+}
+
+// unmarshalPayload deserializes a payload according to the given content type
+func unmarshalPayload(contentType string, b []byte) (interface{}, error) {
+	return codec.Unmarshal(contentType, b)
+}
@@ -0,0 +1,85 @@
+// Package consumer implements the client side of thing descriptions. It
+// performs the operations described by the forms of expanded affordances
+// using the http, mqtt or coap protocol binding
+package consumer
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/connctd/wotlib"
+)
+
+// Default settings of a consumer
+const (
+	DefaultMaxRetries = 2
+	DefaultRetryDelay = 200 * time.Millisecond
+)
+
+// Consumer performs operations on affordances of thing descriptions
+type Consumer struct {
+	client      *http.Client
+	maxRetries  int
+	retryDelay  time.Duration
+	mqtt        MQTTOptions
+	credentials CredentialStore
+
+	mutex    sync.RWMutex
+	bindings map[bindingKey]ProtocolBinding
+}
+
+// Option configures a consumer
+type Option func(c *Consumer)
+
+// WithHTTPClient sets the client used for http requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Consumer) {
+		c.client = client
+	}
+}
+
+// WithRetries defines how often a failed request is retried and how long
+// to wait before the first retry. The delay doubles with each attempt.
+// Only requests of safe or idempotent operations are retried
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(c *Consumer) {
+		c.maxRetries = maxRetries
+		c.retryDelay = delay
+	}
+}
+
+// WithMQTTOptions sets the options used to connect to mqtt brokers
+func WithMQTTOptions(opts MQTTOptions) Option {
+	return func(c *Consumer) {
+		c.mqtt = opts
+	}
+}
+
+// NewConsumer creates a new consumer. The http, mqtt and coap bindings are
+// registered for their uri schemes unless replaced by WithBinding
+func NewConsumer(opts ...Option) *Consumer {
+	c := &Consumer{
+		client:     http.DefaultClient,
+		maxRetries: DefaultMaxRetries,
+		retryDelay: DefaultRetryDelay,
+		bindings:   map[bindingKey]ProtocolBinding{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	httpBinding := &httpBinding{
+		client:     c.client,
+		retryDelay: c.retryDelay,
+		auth:       newAuthenticator(c.credentials, c.client),
+	}
+
+	c.registerDefault(httpBinding, []string{"", SubprotocolLongPoll, SubprotocolSSE}, "http", "https")
+	c.registerDefault(httpBinding, []string{""}, "ws", "wss")
+	c.registerDefault(&mqttBinding{opts: c.mqtt}, []string{""}, "mqtt", "mqtts")
+	c.registerDefault(&coapBinding{}, []string{"", wotlib.CoAPObserve}, "coap")
+
+	return c
+}
@@ -0,0 +1,162 @@
+package consumer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/wottest"
+)
+
+func init() {
+	wotlib.DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
+
+// testThing parses the test td with all hrefs pointing to the given base url
+func testThing(t *testing.T, baseURL string) wotlib.ExpandedThingDescription {
+	replacer := strings.NewReplacer(
+		"{{BASE}}", baseURL,
+		"{{WSBASE}}", "ws"+strings.TrimPrefix(baseURL, "http"),
+	)
+
+	td, err := wotlib.FromBytes([]byte(replacer.Replace(testTD)))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	return td
+}
+
+func findAction(t *testing.T, td wotlib.ExpandedThingDescription, name string) wotlib.ExpandedActionAffordance {
+	for _, currAction := range td.Actions {
+		if currAction.Name.Value() == name {
+			return currAction
+		}
+	}
+
+	t.Fatalf("Action %s not found", name)
+	return wotlib.ExpandedActionAffordance{}
+}
+
+func findProperty(t *testing.T, td wotlib.ExpandedThingDescription, name string) wotlib.ExpandedPropertyAffordance {
+	for _, currProperty := range td.Properties {
+		if currProperty.Name.Value() == name {
+			return currProperty
+		}
+	}
+
+	t.Fatalf("Property %s not found", name)
+	return wotlib.ExpandedPropertyAffordance{}
+}
+
+func findEvent(t *testing.T, td wotlib.ExpandedThingDescription, name string) wotlib.ExpandedEventAffordance {
+	for _, currEvent := range td.Events {
+		if currEvent.Name.Value() == name {
+			return currEvent
+		}
+	}
+
+	t.Fatalf("Event %s not found", name)
+	return wotlib.ExpandedEventAffordance{}
+}
+
+var testTD = `{
+    "@context": [
+        "https://www.w3.org/2019/wot/td/v1",
+        {
+            "iot": "http://iotschema.org/"
+        }
+    ],
+    "id": "urn:dev:ops:32473-LampOne",
+    "title": "LampOne",
+    "securityDefinitions": {
+        "nosec_sc": {"scheme": "nosec"}
+    },
+    "security": ["nosec_sc"],
+    "properties": {
+        "brightness": {
+            "@type": "iot:CurrentDimmer",
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 100,
+            "forms": [
+                {
+                    "op": ["readproperty", "writeproperty"],
+                    "href": "{{BASE}}/properties/brightness",
+                    "contentType": "application/json"
+                },
+                {
+                    "op": "observeproperty",
+                    "href": "{{BASE}}/properties/brightness/longpoll",
+                    "subprotocol": "longpoll"
+                }
+            ]
+        },
+        "temperature": {
+            "@type": "iot:Temperature",
+            "type": "number",
+            "observable": true,
+            "forms": [
+                {
+                    "op": "readproperty",
+                    "href": "{{BASE}}/properties/temperature"
+                },
+                {
+                    "op": "observeproperty",
+                    "href": "{{BASE}}/properties/temperature/sse",
+                    "subprotocol": "sse",
+                    "contentType": "text/event-stream"
+                }
+            ]
+        }
+    },
+    "events": {
+        "overheated": {
+            "data": {"type": "number"},
+            "forms": [
+                {
+                    "op": "subscribeevent",
+                    "href": "{{WSBASE}}/events/overheated"
+                }
+            ]
+        }
+    },
+    "actions": {
+        "setColor": {
+            "@type": "iot:SetColour",
+            "idempotent": true,
+            "input": {
+                "type": "object",
+                "required": ["red", "green", "blue"],
+                "properties": {
+                    "red": {"type": "integer", "@type": "iot:RColourData", "minimum": 0, "maximum": 255},
+                    "green": {"type": "integer", "@type": "iot:GColourData", "minimum": 0, "maximum": 255},
+                    "blue": {"type": "integer", "@type": "iot:BColourData", "minimum": 0, "maximum": 255}
+                }
+            },
+            "output": {
+                "type": "object",
+                "required": ["status"],
+                "properties": {
+                    "status": {"type": "string", "enum": ["accepted", "done"]}
+                }
+            },
+            "forms": [
+                {
+                    "op": "invokeaction",
+                    "href": "{{BASE}}/actions/setColor",
+                    "contentType": "application/json"
+                }
+            ]
+        },
+        "toggle": {
+            "forms": [
+                {
+                    "op": "invokeaction",
+                    "href": "{{BASE}}/actions/toggle",
+                    "htv:methodName": "PUT"
+                }
+            ]
+        }
+    }
+}`
@@ -0,0 +1,239 @@
+package consumer
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/connctd/wotlib"
+)
+
+// digestProvider implements digest authentication (RFC 7616). The first request
+// to a host is sent without credentials, the challenge of the server is kept
+// and used for all following requests
+type digestProvider struct {
+	mutex      sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+// digestChallenge is a challenge received from a server
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	count     int
+}
+
+func newDigestProvider() *digestProvider {
+	return &digestProvider{challenges: map[string]*digestChallenge{}}
+}
+
+func (p *digestProvider) apply(ctx context.Context, req *http.Request, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme, creds Credentials) error {
+	p.mutex.Lock()
+	c, ok := p.challenges[digestKey(req, scheme)]
+	if !ok {
+		p.mutex.Unlock()
+		return nil
+	}
+
+	c.count++
+	count := c.count
+	p.mutex.Unlock()
+
+	authorization, err := c.authorize(req, creds, count)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", authorization)
+
+	return nil
+}
+
+func (p *digestProvider) challenge(ctx context.Context, req *http.Request, resp *http.Response, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error) {
+	var params map[string]string
+	for _, currHeader := range resp.Header.Values("WWW-Authenticate") {
+		if len(currHeader) > 7 && strings.EqualFold(currHeader[:7], "Digest ") {
+			params = parseAuthParams(currHeader[7:])
+			break
+		}
+	}
+
+	if params == nil {
+		return false, nil
+	}
+
+	qop := ""
+	if offered := params["qop"]; offered != "" {
+		for _, currQOP := range strings.Split(offered, ",") {
+			if strings.TrimSpace(currQOP) == "auth" {
+				qop = "auth"
+			}
+		}
+
+		if qop == "" {
+			return false, fmt.Errorf("%w: digest qop %s", ErrUnsupportedSecurity, offered)
+		}
+	}
+
+	c := &digestChallenge{
+		realm:     params["realm"],
+		nonce:     params["nonce"],
+		opaque:    params["opaque"],
+		algorithm: params["algorithm"],
+		qop:       qop,
+	}
+
+	if _, err := c.hash(); err != nil {
+		return false, err
+	}
+
+	key := digestKey(req, scheme)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// a rejected nonce which is not stale means the credentials are wrong
+	if previous, ok := p.challenges[key]; ok && previous.nonce == c.nonce && !strings.EqualFold(params["stale"], "true") {
+		return false, nil
+	}
+
+	p.challenges[key] = c
+
+	return true, nil
+}
+
+// authorize builds the authorization header for a request
+func (c *digestChallenge) authorize(req *http.Request, creds Credentials, count int) (string, error) {
+	h, err := c.hash()
+	if err != nil {
+		return "", err
+	}
+
+	digest := func(values ...string) string {
+		h.Reset()
+		h.Write([]byte(strings.Join(values, ":")))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	cnonce := make([]byte, 8)
+	if _, err := rand.Read(cnonce); err != nil {
+		return "", err
+	}
+
+	cnonceHex := hex.EncodeToString(cnonce)
+	nc := fmt.Sprintf("%08x", count)
+	uri := req.URL.RequestURI()
+
+	ha1 := digest(creds.Username, c.realm, creds.Password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = digest(ha1, c.nonce, cnonceHex)
+	}
+
+	ha2 := digest(req.Method, uri)
+
+	var response string
+	if c.qop != "" {
+		response = digest(ha1, c.nonce, nc, cnonceHex, c.qop, ha2)
+	} else {
+		response = digest(ha1, c.nonce, ha2)
+	}
+
+	fields := []string{
+		fmt.Sprintf("username=%q", creds.Username),
+		fmt.Sprintf("realm=%q", c.realm),
+		fmt.Sprintf("nonce=%q", c.nonce),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("response=%q", response),
+	}
+
+	if c.algorithm != "" {
+		fields = append(fields, "algorithm="+c.algorithm)
+	}
+
+	if c.opaque != "" {
+		fields = append(fields, fmt.Sprintf("opaque=%q", c.opaque))
+	}
+
+	if c.qop != "" {
+		fields = append(fields, "qop="+c.qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cnonceHex))
+	}
+
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// hash returns the hash function of the algorithm of the challenge
+func (c *digestChallenge) hash() (hash.Hash, error) {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(c.algorithm), "-sess")) {
+	case "", "MD5":
+		return md5.New(), nil
+	case "SHA-256":
+		return sha256.New(), nil
+	}
+
+	return nil, fmt.Errorf("%w: digest algorithm %s", ErrUnsupportedSecurity, c.algorithm)
+}
+
+// digestKey identifies the protection space of a challenge
+func digestKey(req *http.Request, scheme wotlib.ExpandedSecurityScheme) string {
+	return scheme.Index + "@" + req.URL.Host
+}
+
+// parseAuthParams parses the comma separated parameters of an authentication
+// header. Values may be quoted strings
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " ")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+
+				b.WriteByte(s[i])
+			}
+
+			value = b.String()
+			if i < len(s) {
+				i++
+			}
+
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+
+		params[key] = value
+	}
+
+	return params
+}
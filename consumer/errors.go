@@ -0,0 +1,79 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/connctd/wotlib/internal/coap"
+	"github.com/connctd/wotlib/internal/codec"
+)
+
+// well known errors
+var (
+	ErrNoForm                 = errors.New("affordance has no form for the requested operation")
+	ErrUnsupportedContentType = codec.ErrUnsupportedContentType
+	ErrUnsupportedForm        = errors.New("unsupported form")
+	ErrUnsupportedSecurity    = errors.New("unsupported security scheme")
+	ErrNoCredentials          = errors.New("no credentials for security scheme")
+	ErrNoRetainedValue        = errors.New("no retained value")
+)
+
+// Direction of a payload
+type Direction string
+
+// payload directions
+const (
+	DirectionInput  Direction = "input"
+	DirectionOutput Direction = "output"
+)
+
+// SchemaError is returned if a payload does not match the data schema
+// of an affordance
+type SchemaError struct {
+	Affordance string
+	Direction  Direction
+	Err        error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s of %s violates schema: %v", e.Direction, e.Affordance, e.Err)
+}
+
+// Unwrap returns the underlying validation error
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPError is returned if a http request was answered with an unexpected status
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s failed with status %d", e.Method, e.URL, e.StatusCode)
+}
+
+// Temporary reports whether the request may succeed if it is repeated
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == 429
+}
+
+// CoAPError is returned if a coap request was answered with an error code
+type CoAPError struct {
+	Method string
+	URL    string
+	Code   coap.Code
+	Body   []byte
+}
+
+func (e *CoAPError) Error() string {
+	return fmt.Sprintf("%s %s failed with code %s", e.Method, e.URL, e.Code)
+}
+
+// Temporary reports whether the request may succeed if it is repeated
+func (e *CoAPError) Temporary() bool {
+	return e.Code.Class() == 5
+}
@@ -0,0 +1,191 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/payload"
+	"github.com/connctd/wotlib/internal/retry"
+)
+
+// well known subprotocols of http forms
+const (
+	SubprotocolLongPoll = "longpoll"
+	SubprotocolSSE      = "sse"
+)
+
+// minPollDelay is the shortest delay between long polls answered at once
+const minPollDelay = 50 * time.Millisecond
+
+// httpBinding implements the http protocol binding including websockets
+type httpBinding struct {
+	client     *http.Client
+	retryDelay time.Duration
+	auth       *authenticator
+}
+
+func (b *httpBinding) Request(ctx context.Context, form wotlib.ExpandedForm, op string, input Payload) (Payload, error) {
+	method := methodOf(form, op)
+
+	resp, err := b.do(ctx, form, func() (*http.Request, error) {
+		req, err := http.NewRequest(method, form.Href.Value(), bytes.NewReader(input.Body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", contentTypeOf(form))
+		if input.Body != nil {
+			req.Header.Set("Content-Type", input.ContentType)
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return Payload{}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Payload{}, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Payload{}, &HTTPError{
+			Method:     method,
+			URL:        resp.Request.URL.String(),
+			StatusCode: resp.StatusCode,
+			Body:       respBody,
+		}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = contentTypeOf(form)
+	}
+
+	return Payload{ContentType: contentType, Body: respBody}, nil
+}
+
+// do sends the request built by newRequest with the security of the form
+// applied. Unauthorized requests are repeated once if a security scheme
+// answered the challenge of the server
+func (b *httpBinding) do(ctx context.Context, form wotlib.ExpandedForm, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		req = req.WithContext(ctx)
+		if err := b.auth.apply(ctx, req, form); err != nil {
+			return nil, err
+		}
+
+		resp, err := b.client.Do(req)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, err
+		}
+
+		retry, err := b.auth.challenge(ctx, req, resp, form)
+		if err != nil || !retry {
+			if err != nil {
+				resp.Body.Close()
+			}
+
+			return resp, err
+		}
+
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+func (b *httpBinding) Subscribe(ctx context.Context, form wotlib.ExpandedForm, op string) (<-chan Message, error) {
+	u, err := url.Parse(form.Href.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case u.Scheme == "ws" || u.Scheme == "wss":
+		return b.streamWebSocket(ctx, form)
+	case form.Subprotocol.Value() == SubprotocolSSE:
+		return b.streamSSE(ctx, form, op)
+	case form.Subprotocol.Value() == SubprotocolLongPoll, form.Subprotocol.Value() == "":
+		messages := make(chan Message)
+		go b.longPoll(ctx, form, op, messages)
+		return messages, nil
+	}
+
+	return nil, fmt.Errorf("%w: subprotocol %s", ErrUnsupportedForm, form.Subprotocol.Value())
+}
+
+// longPoll requests the href of the form over and over again. Empty
+// responses are treated as poll timeouts and the next request is delayed,
+// so servers answering at once aren't flooded with requests
+func (b *httpBinding) longPoll(ctx context.Context, form wotlib.ExpandedForm, op string, messages chan<- Message) {
+	defer close(messages)
+
+	delay := b.retryDelay
+	for {
+		resp, err := b.Request(ctx, form, op, Payload{})
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			if !sendMessage(ctx, messages, Message{Err: err}) || !retry.Temporary(err) {
+				return
+			}
+
+			if !retry.Sleep(ctx, delay) {
+				return
+			}
+
+			delay = retry.Backoff(delay)
+			continue
+		}
+
+		delay = b.retryDelay
+		if len(resp.Body) == 0 {
+			if !retry.Sleep(ctx, pollDelay(delay)) {
+				return
+			}
+
+			continue
+		}
+
+		if !sendMessage(ctx, messages, Message{Payload: resp}) {
+			return
+		}
+	}
+}
+
+// pollDelay returns the delay before polling again after an empty response,
+// which is at least minPollDelay
+func pollDelay(delay time.Duration) time.Duration {
+	if delay < minPollDelay {
+		return minPollDelay
+	}
+
+	return delay
+}
+
+// methodOf returns the http method of the form, which defaults
+// to the method of the operation type
+func methodOf(form wotlib.ExpandedForm, op string) string {
+	if method := form.Method.Value(); method != "" {
+		return method
+	}
+
+	method, _ := payload.DefaultMethod(op)
+	return method
+}
@@ -0,0 +1,202 @@
+package consumer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/mqtt"
+)
+
+// DefaultMQTTReadTimeout is the time to wait for a retained message if no
+// read timeout is configured
+const DefaultMQTTReadTimeout = 5 * time.Second
+
+// MQTTOptions configure connections to mqtt brokers
+type MQTTOptions struct {
+	// ClientIDPrefix is prepended to the random ids of the clients
+	ClientIDPrefix string
+	Username       string
+	Password       string
+	// TLS is used for mqtts brokers
+	TLS *tls.Config
+	// ReadTimeout limits the time to wait for the retained message of a
+	// property, defaults to DefaultMQTTReadTimeout
+	ReadTimeout time.Duration
+}
+
+// mqttBinding implements the mqtt protocol binding. Each operation uses
+// its own connection to the broker. Properties are read by waiting for the
+// retained message of their topic, writing a property or invoking an action
+// publishes the input. Observations and event subscriptions subscribe to the
+// filter or topic of the form. If no retained message arrives within the
+// read timeout ErrNoRetainedValue is returned
+type mqttBinding struct {
+	opts MQTTOptions
+}
+
+func (b *mqttBinding) Request(ctx context.Context, form wotlib.ExpandedForm, op string, input Payload) (Payload, error) {
+	client, topic, err := b.connect(ctx, form)
+	if err != nil {
+		return Payload{}, err
+	}
+	defer client.Close()
+
+	if op == wotlib.OpReadProperty || form.ControlPacket.Value() == wotlib.MQTTSubscribe {
+		filter := filterOf(form, topic)
+		messages, err := client.Subscribe(ctx, filter, qosOf(form))
+		if err != nil {
+			return Payload{}, err
+		}
+
+		timeout := b.opts.ReadTimeout
+		if timeout <= 0 {
+			timeout = DefaultMQTTReadTimeout
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return Payload{}, client.Err()
+			}
+
+			return Payload{ContentType: contentTypeOf(form), Body: msg.Payload}, nil
+		case <-timer.C:
+			return Payload{}, fmt.Errorf("%w for %s within %s", ErrNoRetainedValue, filter, timeout)
+		case <-ctx.Done():
+			return Payload{}, ctx.Err()
+		}
+	}
+
+	err = client.Publish(ctx, topic, input.Body, qosOf(form), form.Retain.Value())
+
+	return Payload{}, err
+}
+
+func (b *mqttBinding) Subscribe(ctx context.Context, form wotlib.ExpandedForm, op string) (<-chan Message, error) {
+	client, topic, err := b.connect(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := client.Subscribe(ctx, filterOf(form, topic), qosOf(form))
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	messages := make(chan Message)
+
+	go func() {
+		defer close(messages)
+		defer client.Close()
+
+		for {
+			select {
+			case msg, ok := <-received:
+				if !ok {
+					sendMessage(ctx, messages, Message{Err: client.Err()})
+					return
+				}
+
+				if !sendMessage(ctx, messages, Message{Payload: Payload{ContentType: contentTypeOf(form), Body: msg.Payload}}) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+// connect connects to the broker of the form and returns the topic of the form
+func (b *mqttBinding) connect(ctx context.Context, form wotlib.ExpandedForm) (*mqtt.Client, string, error) {
+	u, err := url.Parse(form.Href.Value())
+	if err != nil {
+		return nil, "", err
+	}
+
+	opts := mqtt.ClientOptions{
+		ClientID: b.opts.ClientIDPrefix + randomID(),
+		Username: b.opts.Username,
+		Password: b.opts.Password,
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if u.Scheme == "mqtts" {
+			port = "8883"
+		}
+
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if u.Scheme == "mqtts" {
+		opts.TLS = &tls.Config{ServerName: u.Hostname()}
+		if b.opts.TLS != nil {
+			opts.TLS = b.opts.TLS
+		}
+	}
+
+	if u.User != nil && opts.Username == "" {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+
+	client, err := mqtt.Dial(ctx, addr, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	topic := form.Topic.Value()
+	if topic == "" {
+		topic = strings.TrimPrefix(u.Path, "/")
+	}
+
+	return client, topic, nil
+}
+
+// filterOf returns the topic filter of the form, which defaults to the topic
+func filterOf(form wotlib.ExpandedForm, topic string) string {
+	if filter := form.Filter.Value(); filter != "" {
+		return filter
+	}
+
+	return topic
+}
+
+// qosOf returns the quality of service level of the form
+func qosOf(form wotlib.ExpandedForm) byte {
+	if len(form.QoS) == 0 {
+		return 0
+	}
+
+	switch fmt.Sprint(form.QoS[0].Value) {
+	case "1":
+		return 1
+	case "2":
+		return 2
+	}
+
+	return 0
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,210 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/mqtt"
+)
+
+func startBroker(t *testing.T) (*mqtt.Broker, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	broker := mqtt.NewBroker()
+	go broker.Serve(l)
+
+	return broker, l.Addr().String()
+}
+
+func testMQTTThing(t *testing.T, brokerAddr string) wotlib.ExpandedThingDescription {
+	td, err := wotlib.FromBytes([]byte(strings.Replace(testMQTTTD, "{{BROKER}}", brokerAddr, -1)))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	return td
+}
+
+func TestMQTTProperties(t *testing.T) {
+	broker, addr := startBroker(t)
+	defer broker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	brightness := findProperty(t, testMQTTThing(t, addr), "brightness")
+	consumer := NewConsumer()
+
+	if err := consumer.WriteProperty(ctx, brightness, 42); err != nil {
+		t.Fatalf("Failed to write property: %v", err)
+	}
+
+	if msg, ok := broker.Retained("lamp/brightness"); !ok || string(msg.Payload) != "42" {
+		t.Fatalf("Expected retained message, got %+v", msg)
+	}
+
+	value, err := consumer.ReadProperty(ctx, brightness)
+	if err != nil {
+		t.Fatalf("Failed to read property: %v", err)
+	}
+
+	if value != float64(42) {
+		t.Fatalf("Unexpected value %v", value)
+	}
+
+	notifications, err := consumer.ObserveProperty(ctx, brightness)
+	if err != nil {
+		t.Fatalf("Failed to observe property: %v", err)
+	}
+
+	// the retained value is delivered first
+	if notification := <-notifications; notification.Value != float64(42) {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+
+	if err := consumer.WriteProperty(ctx, brightness, 7); err != nil {
+		t.Fatalf("Failed to write property: %v", err)
+	}
+
+	if notification := <-notifications; notification.Value != float64(7) {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+}
+
+func TestMQTTReadTimeout(t *testing.T) {
+	broker, addr := startBroker(t)
+	defer broker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	brightness := findProperty(t, testMQTTThing(t, addr), "brightness")
+	consumer := NewConsumer(WithMQTTOptions(MQTTOptions{ReadTimeout: 50 * time.Millisecond}))
+
+	start := time.Now()
+	if _, err := consumer.ReadProperty(ctx, brightness); !errors.Is(err, ErrNoRetainedValue) {
+		t.Fatalf("Expected missing retained value, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected read to time out, took %s", elapsed)
+	}
+}
+
+func TestMQTTActionsAndEvents(t *testing.T) {
+	broker, addr := startBroker(t)
+	defer broker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	td := testMQTTThing(t, addr)
+	consumer := NewConsumer()
+
+	device, err := mqtt.Dial(ctx, addr, mqtt.ClientOptions{ClientID: "device"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer device.Close()
+
+	invocations, err := device.Subscribe(ctx, "lamp/toggle", 1)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	if _, err := consumer.InvokeAction(ctx, findAction(t, td, "toggle"), map[string]bool{"on": true}); err != nil {
+		t.Fatalf("Failed to invoke action: %v", err)
+	}
+
+	if msg := <-invocations; string(msg.Payload) != `{"on":true}` {
+		t.Fatalf("Unexpected invocation %s", msg.Payload)
+	}
+
+	notifications, err := consumer.SubscribeEvent(ctx, findEvent(t, td, "overheated"))
+	if err != nil {
+		t.Fatalf("Failed to subscribe event: %v", err)
+	}
+
+	if err := device.Publish(ctx, "lamp/events/overheated", []byte("81.5"), 1, false); err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+
+	if notification := <-notifications; notification.Err != nil || notification.Value != 81.5 {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+}
+
+var testMQTTTD = `{
+    "@context": [
+        "https://www.w3.org/2019/wot/td/v1",
+        {
+            "mqv": "http://www.example.org/mqtt-binding#"
+        }
+    ],
+    "id": "urn:dev:ops:32473-LampMQTT",
+    "title": "LampMQTT",
+    "properties": {
+        "brightness": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 100,
+            "observable": true,
+            "forms": [
+                {
+                    "op": "readproperty",
+                    "href": "mqtt://{{BROKER}}/lamp/brightness",
+                    "mqv:controlPacket": "mqv:subscribe"
+                },
+                {
+                    "op": "writeproperty",
+                    "href": "mqtt://{{BROKER}}",
+                    "mqv:topic": "lamp/brightness",
+                    "mqv:controlPacket": "mqv:publish",
+                    "mqv:retain": true,
+                    "mqv:qos": "1"
+                },
+                {
+                    "op": "observeproperty",
+                    "href": "mqtt://{{BROKER}}/lamp/brightness",
+                    "mqv:controlPacket": "mqv:subscribe"
+                }
+            ]
+        }
+    },
+    "actions": {
+        "toggle": {
+            "input": {
+                "type": "object",
+                "properties": {"on": {"type": "boolean"}}
+            },
+            "forms": [
+                {
+                    "op": "invokeaction",
+                    "href": "mqtt://{{BROKER}}/lamp/toggle",
+                    "mqv:controlPacket": "mqv:publish"
+                }
+            ]
+        }
+    },
+    "events": {
+        "overheated": {
+            "data": {"type": "number"},
+            "forms": [
+                {
+                    "op": "subscribeevent",
+                    "href": "mqtt://{{BROKER}}",
+                    "mqv:filter": "lamp/events/+",
+                    "mqv:controlPacket": "mqv:subscribe"
+                }
+            ]
+        }
+    }
+}`
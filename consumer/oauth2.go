@@ -0,0 +1,209 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/connctd/wotlib"
+)
+
+// oauth2ExpiryDelta is subtracted from the lifetime of tokens so that
+// they are renewed before they actually expire
+const oauth2ExpiryDelta = 10 * time.Second
+
+// oauth2Provider implements the client credentials flow of oauth2. Tokens are
+// cached per token endpoint, client and scopes and renewed using the refresh
+// token, if one was issued, once they expire
+type oauth2Provider struct {
+	client *http.Client
+	now    func() time.Time
+
+	mutex  sync.Mutex
+	tokens map[string]*oauth2Token
+}
+
+// oauth2Token is a token issued by a token endpoint
+type oauth2Token struct {
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+}
+
+// oauth2TokenResponse is the response of a token endpoint
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func newOAuth2Provider(client *http.Client) *oauth2Provider {
+	return &oauth2Provider{
+		client: client,
+		now:    time.Now,
+		tokens: map[string]*oauth2Token{},
+	}
+}
+
+func (p *oauth2Provider) apply(ctx context.Context, req *http.Request, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme, creds Credentials) error {
+	if flow := scheme.Flow.Value(); flow != "client" && flow != "client_credentials" {
+		return fmt.Errorf("%w: oauth2 flow %s", ErrUnsupportedSecurity, flow)
+	}
+
+	scopes := form.Scopes.Values()
+	if len(scopes) == 0 {
+		scopes = scheme.Scopes.Values()
+	}
+
+	token, err := p.token(ctx, scheme, creds, scopes)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return nil
+}
+
+// challenge drops the cached tokens of the client so that the repeated
+// request uses a new token
+func (p *oauth2Provider) challenge(ctx context.Context, req *http.Request, resp *http.Response, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error) {
+	prefix := oauth2Key(scheme, creds, nil)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for currKey := range p.tokens {
+		if strings.HasPrefix(currKey, prefix) {
+			delete(p.tokens, currKey)
+		}
+	}
+
+	return true, nil
+}
+
+// token returns a valid access token, either from the cache, by refreshing
+// an expired token or by requesting a new one
+func (p *oauth2Provider) token(ctx context.Context, scheme wotlib.ExpandedSecurityScheme, creds Credentials, scopes []string) (string, error) {
+	key := oauth2Key(scheme, creds, scopes)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	cached, ok := p.tokens[key]
+	if ok && (cached.expiry.IsZero() || p.now().Before(cached.expiry)) {
+		return cached.accessToken, nil
+	}
+
+	if ok && cached.refreshToken != "" {
+		endpoint := scheme.Refresh.Value()
+		if endpoint == "" {
+			endpoint = scheme.Token.Value()
+		}
+
+		token, err := p.request(ctx, endpoint, creds, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {cached.refreshToken},
+		})
+		if err == nil {
+			if token.refreshToken == "" {
+				token.refreshToken = cached.refreshToken
+			}
+
+			p.tokens[key] = token
+			return token.accessToken, nil
+		}
+
+		// fall back to a new token if refreshing failed
+		delete(p.tokens, key)
+	}
+
+	params := url.Values{"grant_type": {"client_credentials"}}
+	if len(scopes) > 0 {
+		params.Set("scope", strings.Join(scopes, " "))
+	}
+
+	token, err := p.request(ctx, scheme.Token.Value(), creds, params)
+	if err != nil {
+		return "", err
+	}
+
+	p.tokens[key] = token
+
+	return token.accessToken, nil
+}
+
+// request requests a token from a token endpoint. The client authenticates
+// using basic authentication
+func (p *oauth2Provider) request(ctx context.Context, endpoint string, creds Credentials, params url.Values) (*oauth2Token, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("%w: oauth2 scheme without token endpoint", ErrUnsupportedSecurity)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(creds.ClientID), url.QueryEscape(creds.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{Method: req.Method, URL: endpoint, StatusCode: resp.StatusCode, Body: body}
+	}
+
+	var tokenResp oauth2TokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, err
+	}
+
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint %s returned no access token", endpoint)
+	}
+
+	if tokenResp.TokenType != "" && !strings.EqualFold(tokenResp.TokenType, "bearer") {
+		return nil, fmt.Errorf("%w: oauth2 token type %s", ErrUnsupportedSecurity, tokenResp.TokenType)
+	}
+
+	token := &oauth2Token{
+		accessToken:  tokenResp.AccessToken,
+		refreshToken: tokenResp.RefreshToken,
+	}
+
+	if tokenResp.ExpiresIn > 0 {
+		token.expiry = p.now().Add(time.Duration(tokenResp.ExpiresIn)*time.Second - oauth2ExpiryDelta)
+	}
+
+	return token, nil
+}
+
+// oauth2Key identifies cached tokens. Without scopes it is the common
+// prefix of all keys of a client
+func oauth2Key(scheme wotlib.ExpandedSecurityScheme, creds Credentials, scopes []string) string {
+	key := scheme.Token.Value() + "\x00" + creds.ClientID + "\x00"
+	if scopes != nil {
+		key += strings.Join(scopes, " ")
+	}
+
+	return key
+}
@@ -0,0 +1,82 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/connctd/wotlib"
+)
+
+// Notification carries a value of an observed property or an emitted event.
+// Err is set if a value could not be received or does not match the data schema
+type Notification struct {
+	Value interface{}
+	Err   error
+}
+
+// ObserveProperty observes a property using the first form with an observeproperty
+// operation and a registered binding. Using http, values are received via websocket
+// (ws and wss hrefs), server-sent events (sse subprotocol) or long polling. Using mqtt,
+// the topic of the form is subscribed, using coap the resource is observed. The
+// returned channel is closed once the context is done or the observation failed
+// permanently. Note that the timeout of the http client applies to event streams as well
+func (c *Consumer) ObserveProperty(ctx context.Context, property wotlib.ExpandedPropertyAffordance) (<-chan Notification, error) {
+	form, b, err := c.selectForm(property.Form, wotlib.OpObserveProperty, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.subscribe(ctx, b, form, wotlib.OpObserveProperty, property.Name.Value(), property.ExpandedDataSchema)
+}
+
+// SubscribeEvent subscribes to an event using the first form with a subscribeevent
+// operation and a registered binding. Transports are chosen the same way as in ObserveProperty
+func (c *Consumer) SubscribeEvent(ctx context.Context, event wotlib.ExpandedEventAffordance) (<-chan Notification, error) {
+	form, b, err := c.selectForm(event.Form, wotlib.OpSubscribeEvent, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.subscribe(ctx, b, form, wotlib.OpSubscribeEvent, event.Name.Value(), event.Data.Value())
+}
+
+// notifier delivers decoded and validated values to a channel
+type notifier struct {
+	ctx    context.Context
+	ch     chan Notification
+	name   string
+	schema wotlib.ExpandedDataSchema
+}
+
+// deliver decodes and validates a payload and sends it. It returns
+// false if the context is done
+func (n *notifier) deliver(contentType string, body []byte) bool {
+	value, err := unmarshalPayload(contentType, body)
+	if err == nil {
+		if validationErr := n.schema.Validate(value); validationErr != nil {
+			value = nil
+			err = &SchemaError{Affordance: n.name, Direction: DirectionOutput, Err: validationErr}
+		}
+	}
+
+	return n.send(Notification{Value: value, Err: err})
+}
+
+func (n *notifier) send(notification Notification) bool {
+	select {
+	case n.ch <- notification:
+		return true
+	case <-n.ctx.Done():
+		return false
+	}
+}
+
+// sendMessage sends a message of a subscription and returns false if the
+// context is done
+func sendMessage(ctx context.Context, messages chan<- Message, msg Message) bool {
+	select {
+	case messages <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,192 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/connctd/wotlib/internal/sse"
+	"github.com/connctd/wotlib/internal/websocket"
+)
+
+func TestObservePropertyLongPoll(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/properties/brightness/longpoll" {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			// poll timeout
+			w.WriteHeader(http.StatusNoContent)
+		case 2:
+			w.Write([]byte(`50`))
+		default:
+			w.Write([]byte(`60`))
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	property := findProperty(t, testThing(t, server.URL), "brightness")
+
+	notifications, err := NewConsumer().ObserveProperty(ctx, property)
+	if err != nil {
+		t.Fatalf("Failed to observe property: %v", err)
+	}
+
+	for _, expected := range []float64{50, 60} {
+		notification := <-notifications
+		if notification.Err != nil || notification.Value != expected {
+			t.Fatalf("Unexpected notification %+v, expected %v", notification, expected)
+		}
+	}
+
+	cancel()
+	for range notifications {
+	}
+}
+
+func TestObservePropertyLongPollEmpty(t *testing.T) {
+	// without retry delay polls are delayed by the minimum delay
+	for _, currDelay := range []time.Duration{50 * time.Millisecond, 0} {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+
+		property := findProperty(t, testThing(t, server.URL), "brightness")
+
+		notifications, err := NewConsumer(WithRetries(0, currDelay)).ObserveProperty(ctx, property)
+		if err != nil {
+			t.Fatalf("Failed to observe property: %v", err)
+		}
+
+		for notification := range notifications {
+			t.Fatalf("Unexpected notification %+v", notification)
+		}
+
+		cancel()
+		server.Close()
+
+		// empty responses delay the next poll
+		if n := atomic.LoadInt32(&requests); n < 2 || n > 5 {
+			t.Fatalf("Expected a poll every 50ms with delay %s, got %d requests", currDelay, n)
+		}
+	}
+}
+
+func TestObservePropertySSE(t *testing.T) {
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != sse.ContentType {
+			t.Errorf("Unexpected accept header %s", r.Header.Get("Accept"))
+		}
+
+		w.Header().Set("Content-Type", sse.ContentType)
+
+		switch atomic.AddInt32(&connections, 1) {
+		case 1:
+			sse.Write(w, sse.Event{ID: "1", Data: "21.5", Retry: time.Millisecond})
+			sse.Write(w, sse.Event{ID: "2", Data: `"hot"`})
+			// connection is closed afterwards
+		case 2:
+			if r.Header.Get("Last-Event-ID") != "2" {
+				t.Errorf("Unexpected last event id %q", r.Header.Get("Last-Event-ID"))
+			}
+
+			sse.Write(w, sse.Event{ID: "3", Data: "22"})
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	property := findProperty(t, testThing(t, server.URL), "temperature")
+
+	notifications, err := NewConsumer().ObserveProperty(ctx, property)
+	if err != nil {
+		t.Fatalf("Failed to observe property: %v", err)
+	}
+
+	if notification := <-notifications; notification.Err != nil || notification.Value != 21.5 {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+
+	var schemaErr *SchemaError
+	if notification := <-notifications; !errors.As(notification.Err, &schemaErr) {
+		t.Fatalf("Expected schema error, got %+v", notification)
+	}
+
+	if notification := <-notifications; notification.Err != nil || notification.Value != float64(22) {
+		t.Fatalf("Unexpected notification %+v", notification)
+	}
+
+	cancel()
+	for range notifications {
+	}
+}
+
+func TestSubscribeEventWebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events/overheated" {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte("81"))
+		conn.WriteMessage(websocket.TextMessage, []byte("85.5"))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	event := findEvent(t, testThing(t, server.URL), "overheated")
+
+	notifications, err := NewConsumer().SubscribeEvent(ctx, event)
+	if err != nil {
+		t.Fatalf("Failed to subscribe event: %v", err)
+	}
+
+	for _, expected := range []float64{81, 85.5} {
+		notification := <-notifications
+		if notification.Err != nil || notification.Value != expected {
+			t.Fatalf("Unexpected notification %+v, expected %v", notification, expected)
+		}
+	}
+
+	cancel()
+	for range notifications {
+	}
+}
+
+func TestObservePropertyWithoutForm(t *testing.T) {
+	property := findProperty(t, testThing(t, "http://localhost"), "brightness")
+	property.Form = property.Form[:1]
+
+	if _, err := NewConsumer().ObserveProperty(context.Background(), property); err != ErrNoForm {
+		t.Fatalf("Expected ErrNoForm, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/connctd/wotlib"
+)
+
+// ReadProperty reads the value of a property and validates it against the
+// data schema of the property
+func (c *Consumer) ReadProperty(ctx context.Context, property wotlib.ExpandedPropertyAffordance) (interface{}, error) {
+	form, b, err := c.selectForm(property.Form, wotlib.OpReadProperty, false)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.request(ctx, b, form, wotlib.OpReadProperty, Payload{}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := unmarshalPayload(out.ContentType, out.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := property.Validate(value); err != nil {
+		return nil, &SchemaError{Affordance: property.Name.Value(), Direction: DirectionOutput, Err: err}
+	}
+
+	return value, nil
+}
+
+// WriteProperty validates a value against the data schema of a property and writes it
+func (c *Consumer) WriteProperty(ctx context.Context, property wotlib.ExpandedPropertyAffordance, value interface{}) error {
+	form, b, err := c.selectForm(property.Form, wotlib.OpWriteProperty, false)
+	if err != nil {
+		return err
+	}
+
+	if err := property.Validate(value); err != nil {
+		return &SchemaError{Affordance: property.Name.Value(), Direction: DirectionInput, Err: err}
+	}
+
+	body, err := marshalPayload(form.ContentType.Value(), value)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.request(ctx, b, form, wotlib.OpWriteProperty, Payload{ContentType: contentTypeOf(form), Body: body}, true)
+
+	return err
+}
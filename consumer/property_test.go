@@ -0,0 +1,46 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadWriteProperty(t *testing.T) {
+	value := []byte("10")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(value)
+		case http.MethodPut:
+			value, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	brightness := findProperty(t, testThing(t, server.URL), "brightness")
+	consumer := NewConsumer()
+
+	if err := consumer.WriteProperty(context.Background(), brightness, 80); err != nil {
+		t.Fatalf("Failed to write property: %v", err)
+	}
+
+	read, err := consumer.ReadProperty(context.Background(), brightness)
+	if err != nil {
+		t.Fatalf("Failed to read property: %v", err)
+	}
+
+	if read != float64(80) {
+		t.Fatalf("Unexpected value %v", read)
+	}
+
+	var schemaErr *SchemaError
+	if err := consumer.WriteProperty(context.Background(), brightness, 101); !errors.As(err, &schemaErr) {
+		t.Fatalf("Expected schema error, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package consumer
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/connctd/wotlib"
+)
+
+// Credentials hold the secrets required by security schemes. Basic and digest
+// authentication use username and password, bearer and apikey schemes use the
+// token and oauth2 uses the client id and secret
+type Credentials struct {
+	Username     string
+	Password     string
+	Token        string
+	ClientID     string
+	ClientSecret string
+}
+
+// CredentialStore provides the credentials for the security schemes of forms
+type CredentialStore interface {
+	// Credentials returns the credentials to use for a scheme of the
+	// effective security of a form
+	Credentials(ctx context.Context, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme) (Credentials, error)
+}
+
+// CredentialStoreFunc allows to use a function as credential store
+type CredentialStoreFunc func(ctx context.Context, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme) (Credentials, error)
+
+// Credentials calls the function
+func (f CredentialStoreFunc) Credentials(ctx context.Context, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme) (Credentials, error) {
+	return f(ctx, form, scheme)
+}
+
+// StaticCredentials is a credential store providing credentials by the name
+// of the security definition, e.g. "basic_sc"
+type StaticCredentials map[string]Credentials
+
+// Credentials returns the credentials stored for the name of the scheme
+func (s StaticCredentials) Credentials(ctx context.Context, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme) (Credentials, error) {
+	creds, ok := s[scheme.Index]
+	if !ok {
+		return Credentials{}, fmt.Errorf("%w: %s", ErrNoCredentials, scheme.Index)
+	}
+
+	return creds, nil
+}
+
+// WithCredentials sets the store providing credentials for secured forms. Without
+// a store, http requests are sent without applying any security scheme
+func WithCredentials(store CredentialStore) Option {
+	return func(c *Consumer) {
+		c.credentials = store
+	}
+}
+
+// securityProvider applies a security scheme to http requests
+type securityProvider interface {
+	// apply adds the credentials to a request
+	apply(ctx context.Context, req *http.Request, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme, creds Credentials) error
+
+	// challenge inspects the response to an unauthorized request and
+	// reports whether the request should be repeated
+	challenge(ctx context.Context, req *http.Request, resp *http.Response, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error)
+}
+
+// authenticator applies the effective security of forms to http requests
+type authenticator struct {
+	store     CredentialStore
+	providers map[string]securityProvider
+}
+
+func newAuthenticator(store CredentialStore, client *http.Client) *authenticator {
+	return &authenticator{
+		store: store,
+		providers: map[string]securityProvider{
+			wotlib.SecurityBasic:  basicProvider{},
+			wotlib.SecurityBearer: bearerProvider{},
+			wotlib.SecurityAPIKey: apiKeyProvider{},
+			wotlib.SecurityDigest: newDigestProvider(),
+			wotlib.SecurityOAuth2: newOAuth2Provider(client),
+		},
+	}
+}
+
+// apply applies all schemes of the effective security of a form
+func (a *authenticator) apply(ctx context.Context, req *http.Request, form wotlib.ExpandedForm) error {
+	_, err := a.each(ctx, form, func(p securityProvider, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error) {
+		return false, p.apply(ctx, req, form, scheme, creds)
+	})
+
+	return err
+}
+
+// challenge lets the schemes of a form answer an unauthorized response and
+// reports whether the request should be repeated
+func (a *authenticator) challenge(ctx context.Context, req *http.Request, resp *http.Response, form wotlib.ExpandedForm) (bool, error) {
+	return a.each(ctx, form, func(p securityProvider, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error) {
+		return p.challenge(ctx, req, resp, scheme, creds)
+	})
+}
+
+func (a *authenticator) each(ctx context.Context, form wotlib.ExpandedForm, f func(p securityProvider, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error)) (bool, error) {
+	if a == nil || a.store == nil {
+		return false, nil
+	}
+
+	result := false
+	for _, currScheme := range form.EffectiveSecurity {
+		if currScheme.Scheme.Value() == wotlib.SecurityNoSec {
+			continue
+		}
+
+		p, ok := a.providers[currScheme.Scheme.Value()]
+		if !ok {
+			return false, fmt.Errorf("%w: %s", ErrUnsupportedSecurity, currScheme.Scheme.Value())
+		}
+
+		creds, err := a.store.Credentials(ctx, form, currScheme)
+		if err != nil {
+			return false, err
+		}
+
+		ok, err = f(p, currScheme, creds)
+		if err != nil {
+			return false, err
+		}
+
+		result = result || ok
+	}
+
+	return result, nil
+}
+
+// basicProvider implements basic authentication
+type basicProvider struct{}
+
+func (basicProvider) apply(ctx context.Context, req *http.Request, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme, creds Credentials) error {
+	value := "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+creds.Password))
+
+	return setCredential(req, scheme, "Authorization", value)
+}
+
+func (basicProvider) challenge(ctx context.Context, req *http.Request, resp *http.Response, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error) {
+	return false, nil
+}
+
+// bearerProvider implements bearer tokens which are issued out of band
+type bearerProvider struct{}
+
+func (bearerProvider) apply(ctx context.Context, req *http.Request, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme, creds Credentials) error {
+	value := creds.Token
+	if isAuthorizationHeader(scheme) {
+		value = "Bearer " + value
+	}
+
+	return setCredential(req, scheme, "Authorization", value)
+}
+
+func (bearerProvider) challenge(ctx context.Context, req *http.Request, resp *http.Response, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error) {
+	return false, nil
+}
+
+// apiKeyProvider implements api keys passed in a header, query parameter or cookie
+type apiKeyProvider struct{}
+
+func (apiKeyProvider) apply(ctx context.Context, req *http.Request, form wotlib.ExpandedForm, scheme wotlib.ExpandedSecurityScheme, creds Credentials) error {
+	if scheme.Name.Value() == "" {
+		return fmt.Errorf("%w: apikey scheme %s without name", ErrUnsupportedSecurity, scheme.Index)
+	}
+
+	return setCredential(req, scheme, "", creds.Token)
+}
+
+func (apiKeyProvider) challenge(ctx context.Context, req *http.Request, resp *http.Response, scheme wotlib.ExpandedSecurityScheme, creds Credentials) (bool, error) {
+	return false, nil
+}
+
+// setCredential places a value in the header, query parameter or cookie
+// defined by the scheme. The name defaults to the given name
+func setCredential(req *http.Request, scheme wotlib.ExpandedSecurityScheme, defaultName string, value string) error {
+	name := scheme.Name.Value()
+	if name == "" {
+		name = defaultName
+	}
+
+	switch in := scheme.In.Value(); in {
+	case "", "header":
+		req.Header.Set(name, value)
+	case "query":
+		query := req.URL.Query()
+		query.Set(name, value)
+		req.URL.RawQuery = query.Encode()
+	case "cookie":
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	default:
+		return fmt.Errorf("%w: credentials in %s", ErrUnsupportedSecurity, in)
+	}
+
+	return nil
+}
+
+// isAuthorizationHeader checks if the scheme places its credentials into
+// the authorization header
+func isAuthorizationHeader(scheme wotlib.ExpandedSecurityScheme) bool {
+	in := scheme.In.Value()
+	name := scheme.Name.Value()
+
+	return (in == "" || in == "header") && (name == "" || strings.EqualFold(name, "Authorization"))
+}
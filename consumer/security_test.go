@@ -0,0 +1,264 @@
+package consumer
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/connctd/wotlib"
+)
+
+// tokenEndpoint is a stub of an oauth2 token endpoint
+type tokenEndpoint struct {
+	mutex   sync.Mutex
+	grants  []string
+	issued  int
+	revoked map[string]bool
+}
+
+func (e *tokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != "lamp-client" || secret != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	grant := r.PostForm.Get("grant_type")
+	if grant == "refresh_token" && r.PostForm.Get("refresh_token") != "refresh-1" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e.grants = append(e.grants, grant+" "+r.PostForm.Get("scope"))
+	e.issued++
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":60,"refresh_token":"refresh-1"}`, e.issued)
+}
+
+func (e *tokenEndpoint) valid(token string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return strings.HasPrefix(token, "token-") && !e.revoked[token]
+}
+
+func testSecureThing(t *testing.T, baseURL string) wotlib.ExpandedThingDescription {
+	td, err := wotlib.FromBytes([]byte(strings.Replace(testSecureTD, "{{BASE}}", baseURL, -1)))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	return td
+}
+
+func TestSecuritySchemes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ok bool
+		switch r.URL.Path {
+		case "/nosec":
+			ok = r.Header.Get("Authorization") == ""
+		case "/basic":
+			user, password, _ := r.BasicAuth()
+			ok = user == "admin" && password == "secret"
+		case "/bearer":
+			ok = r.Header.Get("Authorization") == "Bearer abc"
+		case "/apikey/header":
+			ok = r.Header.Get("X-API-Key") == "key-1"
+		case "/apikey/query":
+			ok = r.URL.Query().Get("api_key") == "key-2"
+		case "/apikey/cookie":
+			cookie, err := r.Cookie("session")
+			ok = err == nil && cookie.Value == "key-3"
+		}
+
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	td := testSecureThing(t, server.URL)
+	consumer := NewConsumer(WithCredentials(StaticCredentials{
+		"basic_sc":         {Username: "admin", Password: "secret"},
+		"bearer_sc":        {Token: "abc"},
+		"apikey_header_sc": {Token: "key-1"},
+		"apikey_query_sc":  {Token: "key-2"},
+		"apikey_cookie_sc": {Token: "key-3"},
+	}))
+
+	tests := []string{"nosec", "basic", "bearer", "apikeyHeader", "apikeyQuery", "apikeyCookie"}
+
+	for _, currTest := range tests {
+		if _, err := consumer.ReadProperty(context.Background(), findProperty(t, td, currTest)); err != nil {
+			t.Fatalf("Failed to read property %s: %v", currTest, err)
+		}
+	}
+
+	// credentials which are not part of the store
+	consumer = NewConsumer(WithCredentials(StaticCredentials{}))
+	if _, err := consumer.ReadProperty(context.Background(), findProperty(t, td, "basic")); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("Expected missing credentials error, got %v", err)
+	}
+}
+
+func TestDigestAuthentication(t *testing.T) {
+	var mutex sync.Mutex
+	challenges := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		params := map[string]string{}
+		if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, "Digest ") {
+			params = parseAuthParams(strings.TrimPrefix(authorization, "Digest "))
+		}
+
+		md5Hex := func(s string) string {
+			sum := md5.Sum([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}
+
+		ha1 := md5Hex("admin:lamp:secret")
+		ha2 := md5Hex(r.Method + ":" + params["uri"])
+		expected := md5Hex(strings.Join([]string{ha1, "nonce-1", params["nc"], params["cnonce"], "auth", ha2}, ":"))
+
+		if params["response"] != expected || params["opaque"] != "opaque-1" || params["uri"] != r.URL.RequestURI() {
+			challenges++
+			w.Header().Set("WWW-Authenticate", `Digest realm="lamp", qop="auth,auth-int", nonce="nonce-1", opaque="opaque-1"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	property := findProperty(t, testSecureThing(t, server.URL), "digest")
+
+	consumer := NewConsumer(WithCredentials(StaticCredentials{"digest_sc": {Username: "admin", Password: "secret"}}))
+	for i := 0; i < 3; i++ {
+		if _, err := consumer.ReadProperty(context.Background(), property); err != nil {
+			t.Fatalf("Failed to read property: %v", err)
+		}
+	}
+
+	// only the first request is challenged
+	if challenges != 1 {
+		t.Fatalf("Expected one challenge, got %d", challenges)
+	}
+
+	consumer = NewConsumer(WithCredentials(StaticCredentials{"digest_sc": {Username: "admin", Password: "wrong"}}))
+
+	var httpErr *HTTPError
+	if _, err := consumer.ReadProperty(context.Background(), property); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected unauthorized error, got %v", err)
+	}
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	endpoint := &tokenEndpoint{revoked: map[string]bool{}}
+
+	mux := http.NewServeMux()
+	mux.Handle("/token", endpoint)
+	mux.HandleFunc("/oauth2", func(w http.ResponseWriter, r *http.Request) {
+		if !endpoint.valid(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("1"))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	property := findProperty(t, testSecureThing(t, server.URL), "oauth2")
+	consumer := NewConsumer(WithCredentials(StaticCredentials{"oauth2_sc": {ClientID: "lamp-client", ClientSecret: "s3cret"}}))
+
+	now := time.Now()
+	provider := consumer.bindings[newBindingKey("http", "")].(*httpBinding).auth.providers[wotlib.SecurityOAuth2].(*oauth2Provider)
+	provider.now = func() time.Time { return now }
+
+	read := func() {
+		if _, err := consumer.ReadProperty(context.Background(), property); err != nil {
+			t.Fatalf("Failed to read property: %v", err)
+		}
+	}
+
+	// the token is cached
+	read()
+	read()
+
+	// expired tokens are refreshed
+	now = now.Add(time.Minute)
+	read()
+
+	// revoked tokens are replaced once the resource rejects them
+	endpoint.mutex.Lock()
+	endpoint.revoked["token-2"] = true
+	endpoint.mutex.Unlock()
+	read()
+
+	expected := []string{"client_credentials status", "refresh_token ", "client_credentials status"}
+	if strings.Join(endpoint.grants, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected grants %v", endpoint.grants)
+	}
+
+	consumer = NewConsumer(WithCredentials(StaticCredentials{"oauth2_sc": {ClientID: "lamp-client", ClientSecret: "wrong"}}))
+
+	var httpErr *HTTPError
+	if _, err := consumer.ReadProperty(context.Background(), property); !errors.As(err, &httpErr) || !strings.HasSuffix(httpErr.URL, "/token") {
+		t.Fatalf("Expected error of token endpoint, got %v", err)
+	}
+}
+
+var testSecureTD = `{
+    "@context": "https://www.w3.org/2019/wot/td/v1",
+    "id": "urn:dev:ops:32473-SecureLamp",
+    "title": "SecureLamp",
+    "securityDefinitions": {
+        "nosec_sc": {"scheme": "nosec"},
+        "basic_sc": {"scheme": "basic", "in": "header"},
+        "digest_sc": {"scheme": "digest", "qop": "auth"},
+        "bearer_sc": {"scheme": "bearer", "format": "jwt", "alg": "ES256"},
+        "apikey_header_sc": {"scheme": "apikey", "in": "header", "name": "X-API-Key"},
+        "apikey_query_sc": {"scheme": "apikey", "in": "query", "name": "api_key"},
+        "apikey_cookie_sc": {"scheme": "apikey", "in": "cookie", "name": "session"},
+        "oauth2_sc": {"scheme": "oauth2", "flow": "client", "token": "{{BASE}}/token", "scopes": ["status", "control"]}
+    },
+    "security": ["nosec_sc"],
+    "properties": {
+        "nosec": {"type": "integer", "forms": [{"href": "{{BASE}}/nosec"}]},
+        "basic": {"type": "integer", "forms": [{"href": "{{BASE}}/basic", "security": "basic_sc"}]},
+        "digest": {"type": "integer", "forms": [{"href": "{{BASE}}/digest?unit=percent", "security": "digest_sc"}]},
+        "bearer": {"type": "integer", "forms": [{"href": "{{BASE}}/bearer", "security": "bearer_sc"}]},
+        "apikeyHeader": {"type": "integer", "forms": [{"href": "{{BASE}}/apikey/header", "security": "apikey_header_sc"}]},
+        "apikeyQuery": {"type": "integer", "forms": [{"href": "{{BASE}}/apikey/query", "security": "apikey_query_sc"}]},
+        "apikeyCookie": {"type": "integer", "forms": [{"href": "{{BASE}}/apikey/cookie", "security": "apikey_cookie_sc"}]},
+        "oauth2": {"type": "integer", "forms": [{"href": "{{BASE}}/oauth2", "security": "oauth2_sc", "scopes": "status"}]}
+    }
+}`
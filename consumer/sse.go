@@ -0,0 +1,106 @@
+package consumer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/retry"
+	"github.com/connctd/wotlib/internal/sse"
+)
+
+// streamSSE receives values as server-sent events. The data of each event
+// is decoded according to the content type of the form. Lost connections
+// are reestablished, passing the id of the last event received
+func (b *httpBinding) streamSSE(ctx context.Context, form wotlib.ExpandedForm, op string) (<-chan Message, error) {
+	body, err := b.openEventStream(ctx, form, op, "")
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := contentTypeOf(form)
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == sse.ContentType {
+		contentType = DefaultContentType
+	}
+
+	messages := make(chan Message)
+
+	go func() {
+		defer close(messages)
+
+		reader := sse.NewReader(body)
+		delay := b.retryDelay
+
+		for {
+			event, err := reader.Next()
+			if err == nil {
+				if event.Retry > 0 {
+					delay = event.Retry
+				}
+
+				msg := Message{Payload: Payload{ContentType: contentType, Body: []byte(event.Data)}}
+				if !sendMessage(ctx, messages, msg) {
+					body.Close()
+					return
+				}
+
+				continue
+			}
+
+			body.Close()
+
+			// reconnect until the stream is available again, backing off
+			// while reconnecting fails
+			reconnectDelay := delay
+			for {
+				if !retry.Sleep(ctx, reconnectDelay) {
+					return
+				}
+
+				body, err = b.openEventStream(ctx, form, op, reader.LastEventID())
+				if err == nil {
+					break
+				}
+
+				if ctx.Err() != nil || !sendMessage(ctx, messages, Message{Err: err}) || !retry.Temporary(err) {
+					return
+				}
+
+				reconnectDelay = retry.Backoff(reconnectDelay)
+			}
+
+			reader = sse.NewReaderWithLastEventID(body, reader.LastEventID())
+		}
+	}()
+
+	return messages, nil
+}
+
+func (b *httpBinding) openEventStream(ctx context.Context, form wotlib.ExpandedForm, op string, lastEventID string) (io.ReadCloser, error) {
+	resp, err := b.do(ctx, form, func() (*http.Request, error) {
+		req, err := http.NewRequest(methodOf(form, op), form.Href.Value(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", sse.ContentType)
+		req.Header.Set("Cache-Control", "no-cache")
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, &HTTPError{Method: resp.Request.Method, URL: resp.Request.URL.String(), StatusCode: resp.StatusCode}
+	}
+
+	return resp.Body, nil
+}
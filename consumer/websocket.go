@@ -0,0 +1,69 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/websocket"
+)
+
+// streamWebSocket receives values as messages of a websocket connection.
+// The subprotocol of the form is requested during the handshake
+func (b *httpBinding) streamWebSocket(ctx context.Context, form wotlib.ExpandedForm) (<-chan Message, error) {
+	var subprotocols []string
+	if subprotocol := form.Subprotocol.Value(); subprotocol != "" {
+		subprotocols = append(subprotocols, subprotocol)
+	}
+
+	// the handshake carries the credentials of the form
+	req, err := http.NewRequest(http.MethodGet, form.Href.Value(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.auth.apply(ctx, req, form); err != nil {
+		return nil, err
+	}
+
+	conn, err := websocket.Dial(ctx, req.URL.String(), subprotocols, req.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(chan Message)
+
+	go func() {
+		defer close(messages)
+
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-done:
+			}
+
+			conn.Close()
+		}()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				if ctx.Err() == nil {
+					sendMessage(ctx, messages, Message{Err: err})
+				}
+
+				return
+			}
+
+			msg := Message{Payload: Payload{ContentType: contentTypeOf(form), Body: data}}
+			if !sendMessage(ctx, messages, msg) {
+				return
+			}
+		}
+	}()
+
+	return messages, nil
+}
@@ -24,6 +24,26 @@ var (
 		Prefix: SchemaPrefix("jsonschema"),
 		IRI:    "https://www.w3.org/2019/wot/json-schema#",
 	}
+
+	SchemaMQTT = SchemaMapping{
+		Prefix: SchemaPrefix("mqv"),
+		IRI:    "http://www.example.org/mqtt-binding#",
+	}
+
+	SchemaSecurity = SchemaMapping{
+		Prefix: SchemaPrefix("wotsec"),
+		IRI:    "https://www.w3.org/2019/wot/security#",
+	}
+
+	SchemaCoAP = SchemaMapping{
+		Prefix: SchemaPrefix("cov"),
+		IRI:    "http://www.example.org/coap-binding#",
+	}
+
+	SchemaTM = SchemaMapping{
+		Prefix: SchemaPrefix("tm"),
+		IRI:    "https://www.w3.org/2019/wot/tm#",
+	}
 )
 
 // SchemaMapping defines a prefix iri mapping
@@ -43,6 +63,24 @@ func (p SchemaPrefix) String() string {
 	return string(p)
 }
 
+// ExpandIRI expands a compact iri like "mqv:publish" if its prefix is part
+// of the default context. Other values are returned unchanged
+func ExpandIRI(value string) string {
+	for i := 0; i < len(value); i++ {
+		if value[i] != ':' {
+			continue
+		}
+
+		if iri, ok := DefaultContext[value[:i]].(string); ok {
+			return iri + value[i+1:]
+		}
+
+		break
+	}
+
+	return value
+}
+
 // AppendSchema appends a schema to the default context
 func AppendSchema(m SchemaMapping) {
 	DefaultContext[m.Prefix.String()] = m.IRI
@@ -55,4 +93,8 @@ var DefaultContext = map[string]interface{}{
 	SchemaHypermedia.Prefix.String(): SchemaHypermedia.IRI,
 	SchemaRdfType.Prefix.String():    SchemaRdfType.IRI,
 	SchemaJSON.Prefix.String():       SchemaJSON.IRI,
+	SchemaSecurity.Prefix.String():   SchemaSecurity.IRI,
+	SchemaMQTT.Prefix.String():       SchemaMQTT.IRI,
+	SchemaCoAP.Prefix.String():       SchemaCoAP.IRI,
+	SchemaTM.Prefix.String():         SchemaTM.IRI,
 }
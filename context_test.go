@@ -0,0 +1,9 @@
+package wotlib
+
+import (
+	"github.com/connctd/wotlib/internal/wottest"
+)
+
+func init() {
+	DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
@@ -0,0 +1,516 @@
+package directory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/connctd/wotlib"
+)
+
+// well known errors of the client
+var (
+	ErrNotADirectory = errors.New("not a thing description directory")
+)
+
+// ProblemError is returned if the directory answered with an error status
+type ProblemError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Title      string
+	Detail     string
+}
+
+func (e *ProblemError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s %s failed with status %d", e.Method, e.URL, e.StatusCode)
+	}
+
+	return fmt.Sprintf("%s %s failed with status %d: %s", e.Method, e.URL, e.StatusCode, e.Detail)
+}
+
+// Unwrap maps the status to the errors of the directory
+func (e *ProblemError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case http.StatusBadRequest:
+		return ErrInvalidTD
+	}
+
+	return nil
+}
+
+// Temporary reports whether the request may succeed if it is repeated
+func (e *ProblemError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// Client accesses a thing description directory. It remembers the etags of
+// the things it has seen to revalidate them with conditional requests and
+// to guard updates and deletions against concurrent modifications
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retryDelay time.Duration
+
+	mutex    sync.Mutex
+	things   map[string]Thing
+	listETag string
+}
+
+// ClientOption configures a directory client
+type ClientOption func(c *Client)
+
+// WithHTTPClient sets the http client used for requests
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// NewClient creates a client for the directory at the base url
+func NewClient(baseURL string, opts ...ClientOption) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	c := &Client{baseURL: u, httpClient: http.DefaultClient, retryDelay: defaultRetryDelay, things: map[string]Thing{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Discover fetches the td of a directory and creates a client for it. If the
+// url has no path the td is expected at the well known path
+func Discover(ctx context.Context, tdURL string, opts ...ClientOption) (*Client, error) {
+	u, err := url.Parse(tdURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = WellKnownPath
+	}
+
+	c := &Client{httpClient: http.DefaultClient, retryDelay: defaultRetryDelay, things: map[string]Thing{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, u.String(), "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	td, err := wotlib.FromResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !containsType(td.Type, TypeThingDirectory) {
+		return nil, fmt.Errorf("%w: %s", ErrNotADirectory, u)
+	}
+
+	base := td.Base.Value()
+	if base == "" {
+		base = u.ResolveReference(&url.URL{Path: "/"}).String()
+	}
+
+	if c.baseURL, err = u.Parse(base); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// List fetches all things of the directory, following the pagination links
+func (c *Client) List(ctx context.Context) ([]Thing, error) {
+	things, _, _, err := c.list(ctx, "")
+
+	return things, err
+}
+
+// Get fetches a thing. Known things are revalidated with their etag
+func (c *Client) Get(ctx context.Context, id string) (Thing, error) {
+	c.mutex.Lock()
+	cached, isCached := c.things[id]
+	c.mutex.Unlock()
+
+	header := http.Header{}
+	if isCached && cached.ETag != "" {
+		header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, c.thingURL(id), "", nil, header)
+	if err != nil {
+		return Thing{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return cached, nil
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Thing{}, err
+	}
+
+	return c.remember(id, b, resp.Header.Get("ETag"))
+}
+
+// Register stores a td in the directory. Tds without id are registered as
+// anonymous things and get an id generated by the directory
+func (c *Client) Register(ctx context.Context, td []byte) (Thing, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return Thing{}, fmt.Errorf("%w: %v", ErrInvalidTD, err)
+	}
+
+	id, _ := document["id"].(string)
+	if id != "" {
+		return c.put(ctx, id, td, "")
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, c.thingsURL(), TDContentType, td, nil)
+	if err != nil {
+		return Thing{}, err
+	}
+	resp.Body.Close()
+
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return Thing{}, err
+	}
+
+	prefix := c.thingsURL() + "/"
+	if !strings.HasPrefix(location.String(), prefix) {
+		return Thing{}, fmt.Errorf("unexpected location %s", location)
+	}
+
+	if id, err = url.PathUnescape(strings.TrimPrefix(location.String(), prefix)); err != nil {
+		return Thing{}, err
+	}
+
+	document["id"] = id
+
+	b, err := json.Marshal(document)
+	if err != nil {
+		return Thing{}, err
+	}
+
+	return c.remember(id, b, resp.Header.Get("ETag"))
+}
+
+// Update replaces a registered td. If the thing is known the update only
+// succeeds if it wasn't modified in the meantime, otherwise an error
+// wrapping ErrPreconditionFailed is returned
+func (c *Client) Update(ctx context.Context, td []byte) (Thing, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return Thing{}, fmt.Errorf("%w: %v", ErrInvalidTD, err)
+	}
+
+	id, _ := document["id"].(string)
+	if id == "" {
+		return Thing{}, fmt.Errorf("%w: id is missing", ErrInvalidTD)
+	}
+
+	return c.put(ctx, id, td, c.etag(id))
+}
+
+// Patch applies a json merge patch to a registered td and returns the
+// patched td. Like Update it is guarded by the etag of known things
+func (c *Client) Patch(ctx context.Context, id string, patch []byte) (Thing, error) {
+	resp, err := c.do(ctx, http.MethodPatch, c.thingURL(id), MergePatchContentType, patch, ifMatch(c.etag(id)))
+	if err != nil {
+		return Thing{}, err
+	}
+	resp.Body.Close()
+
+	c.forget(id)
+
+	return c.Get(ctx, id)
+}
+
+// Delete removes a td from the directory. Like Update it is guarded by
+// the etag of known things
+func (c *Client) Delete(ctx context.Context, id string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.thingURL(id), "", nil, ifMatch(c.etag(id)))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	c.forget(id)
+
+	return nil
+}
+
+// Populate adds all things of the directory to the set
+func (c *Client) Populate(ctx context.Context, set wotlib.ExpandedThingDescriptionSet) error {
+	things, err := c.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, currThing := range things {
+		set.Append(currThing.TD)
+	}
+
+	return nil
+}
+
+// Sync makes the set mirror the directory. Things missing in the directory
+// are removed from the set. If the directory didn't change since the last
+// sync nothing is transferred. It returns whether the set was changed
+func (c *Client) Sync(ctx context.Context, set wotlib.ExpandedThingDescriptionSet) (bool, error) {
+	c.mutex.Lock()
+	listETag := c.listETag
+	c.mutex.Unlock()
+
+	things, listETag, modified, err := c.list(ctx, listETag)
+	if err != nil || !modified {
+		return false, err
+	}
+
+	c.mutex.Lock()
+	c.listETag = listETag
+	c.mutex.Unlock()
+
+	mirror(set, things)
+
+	return true, nil
+}
+
+// mirror makes the set contain exactly the listed things
+func mirror(set wotlib.ExpandedThingDescriptionSet, things []Thing) {
+	listed := map[string]bool{}
+	for _, currThing := range things {
+		listed[currThing.TD.ID] = true
+		set.Append(currThing.TD)
+	}
+
+	for id := range set {
+		if !listed[id] {
+			set.Remove(id)
+		}
+	}
+}
+
+// list fetches all pages of things and returns them with the etag of the
+// first page. If the etag matches nothing is returned and modified is false
+func (c *Client) list(ctx context.Context, etag string) ([]Thing, string, bool, error) {
+	var things []Thing
+	var listETag string
+
+	next := c.thingsURL()
+	first := true
+	for next != "" {
+		header := http.Header{}
+		if first && etag != "" {
+			header.Set("If-None-Match", etag)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, next, "", nil, header)
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		if resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			return nil, etag, false, nil
+		}
+
+		var documents []json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&documents)
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		if first {
+			listETag = resp.Header.Get("ETag")
+		}
+
+		for _, currDocument := range documents {
+			td, err := wotlib.FromBytes(currDocument)
+			if err != nil {
+				return nil, "", false, err
+			}
+
+			things = append(things, Thing{ID: td.ID, Document: currDocument, TD: td})
+		}
+
+		if next, err = nextLink(resp); err != nil {
+			return nil, "", false, err
+		}
+
+		first = false
+	}
+
+	return things, listETag, true, nil
+}
+
+func (c *Client) put(ctx context.Context, id string, td []byte, etag string) (Thing, error) {
+	resp, err := c.do(ctx, http.MethodPut, c.thingURL(id), TDContentType, td, ifMatch(etag))
+	if err != nil {
+		return Thing{}, err
+	}
+	resp.Body.Close()
+
+	return c.remember(id, td, resp.Header.Get("ETag"))
+}
+
+// do sends a request and turns unexpected status codes into problem errors
+func (c *Client) do(ctx context.Context, method string, u string, contentType string, body []byte, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+	for key := range header {
+		req.Header.Set(key, header.Get(key))
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	problemErr := &ProblemError{Method: method, URL: u, StatusCode: resp.StatusCode}
+
+	var p problem
+	if b, err := ioutil.ReadAll(resp.Body); err == nil && json.Unmarshal(b, &p) == nil {
+		problemErr.Title = p.Title
+		problemErr.Detail = p.Detail
+	}
+
+	return nil, problemErr
+}
+
+// remember expands a td and stores it with its etag
+func (c *Client) remember(id string, document []byte, etag string) (Thing, error) {
+	td, err := wotlib.FromBytes(document)
+	if err != nil {
+		return Thing{}, err
+	}
+
+	thing := Thing{ID: id, Document: document, TD: td, ETag: etag}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if etag != "" {
+		c.things[id] = thing
+	}
+
+	return thing, nil
+}
+
+func (c *Client) forget(id string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.things, id)
+}
+
+func (c *Client) etag(id string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.things[id].ETag
+}
+
+func (c *Client) thingsURL() string {
+	return c.baseURL.ResolveReference(&url.URL{Path: strings.TrimPrefix(ThingsPath, "/")}).String()
+}
+
+func (c *Client) thingURL(id string) string {
+	return c.thingsURL() + "/" + url.PathEscape(id)
+}
+
+func ifMatch(etag string) http.Header {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-Match", etag)
+	}
+
+	return header
+}
+
+// nextLink returns the target of the next link of a response
+func nextLink(resp *http.Response) (string, error) {
+	for _, currLink := range resp.Header.Values("Link") {
+		for _, currValue := range strings.Split(currLink, ",") {
+			parts := strings.Split(currValue, ";")
+
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, currParam := range parts[1:] {
+				if strings.ReplaceAll(strings.TrimSpace(currParam), `"`, "") != "rel=next" {
+					continue
+				}
+
+				u, err := resp.Request.URL.Parse(strings.Trim(target, "<>"))
+				if err != nil {
+					return "", err
+				}
+
+				return u.String(), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func containsType(types []string, t string) bool {
+	for _, currType := range types {
+		if currType == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,142 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/connctd/wotlib"
+)
+
+func TestClient(t *testing.T) {
+	_, server := newTestServer(t, WithPageSize(2))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	c, err := Discover(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to discover directory: %v", err)
+	}
+
+	lamp, err := c.Register(ctx, []byte(testTD("urn:dev:ops:lamp", "Lamp")))
+	if err != nil || lamp.ETag == "" {
+		t.Fatalf("Failed to register thing: %v", err)
+	}
+
+	anonymous, err := c.Register(ctx, []byte(testTD("", "Anonymous")))
+	if err != nil {
+		t.Fatalf("Failed to register anonymous thing: %v", err)
+	}
+
+	if fetched, err := c.Get(ctx, anonymous.ID); err != nil || fetched.TD.ID != anonymous.ID {
+		t.Fatalf("Failed to fetch anonymous thing %s: %v", anonymous.ID, err)
+	}
+
+	for _, currName := range []string{"a", "b", "c"} {
+		if _, err := c.Register(ctx, []byte(testTD("urn:dev:ops:"+currName, currName))); err != nil {
+			t.Fatalf("Failed to register thing: %v", err)
+		}
+	}
+
+	things, err := c.List(ctx)
+	if err != nil || len(things) != 5 {
+		t.Fatalf("Expected 5 things across all pages, got %d (%v)", len(things), err)
+	}
+
+	// a concurrent modification invalidates the etag known by the client
+	other, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := other.Patch(ctx, "urn:dev:ops:lamp", []byte(`{"description": "changed"}`)); err != nil {
+		t.Fatalf("Failed to patch thing: %v", err)
+	}
+
+	if _, err := c.Update(ctx, []byte(testTD("urn:dev:ops:lamp", "Lamp 2"))); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Expected precondition to fail, got %v", err)
+	}
+
+	lamp, err = c.Get(ctx, "urn:dev:ops:lamp")
+	if err != nil {
+		t.Fatalf("Failed to fetch thing: %v", err)
+	}
+
+	if _, err := c.Update(ctx, []byte(testTD("urn:dev:ops:lamp", "Lamp 2"))); err != nil {
+		t.Fatalf("Failed to update thing: %v", err)
+	}
+
+	if err := c.Delete(ctx, "urn:dev:ops:a"); err != nil {
+		t.Fatalf("Failed to delete thing: %v", err)
+	}
+
+	if _, err := c.Get(ctx, "urn:dev:ops:a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected deleted thing to be missing, got %v", err)
+	}
+}
+
+func TestClientSync(t *testing.T) {
+	_, server := newTestServer(t, WithPageSize(1))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	c, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for _, currName := range []string{"a", "b"} {
+		if _, err := c.Register(ctx, []byte(testTD("urn:dev:ops:"+currName, currName))); err != nil {
+			t.Fatalf("Failed to register thing: %v", err)
+		}
+	}
+
+	set := wotlib.NewExpandedThingDescriptionSet()
+	if err := c.Populate(ctx, set); err != nil || len(set) != 2 {
+		t.Fatalf("Expected 2 things, got %d (%v)", len(set), err)
+	}
+
+	set.Append(wotlib.ExpandedThingDescription{ID: "urn:dev:ops:stale"})
+
+	if changed, err := c.Sync(ctx, set); err != nil || !changed || len(set) != 2 {
+		t.Fatalf("Expected sync to remove stale thing, got %d things (%v)", len(set), err)
+	}
+
+	if changed, err := c.Sync(ctx, set); err != nil || changed {
+		t.Fatalf("Expected unchanged directory, got %v (%v)", changed, err)
+	}
+
+	if err := c.Delete(ctx, "urn:dev:ops:a"); err != nil {
+		t.Fatalf("Failed to delete thing: %v", err)
+	}
+
+	if changed, err := c.Sync(ctx, set); err != nil || !changed || len(set) != 1 || set.Get("urn:dev:ops:b").ID == "" {
+		t.Fatalf("Expected sync to apply deletion, got %v (%v)", set, err)
+	}
+}
+
+func TestDiscoverErrors(t *testing.T) {
+	_, server := newTestServer(t)
+	defer server.Close()
+
+	ctx := context.Background()
+
+	if _, err := Discover(ctx, server.URL+ThingsPath+"/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+
+	c, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := c.Register(ctx, []byte(testTD("urn:dev:ops:lamp", "Lamp"))); err != nil {
+		t.Fatalf("Failed to register thing: %v", err)
+	}
+
+	if _, err := Discover(ctx, server.URL+ThingsPath+"/urn:dev:ops:lamp"); !errors.Is(err, ErrNotADirectory) {
+		t.Fatalf("Expected not a directory error, got %v", err)
+	}
+}
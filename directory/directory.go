@@ -0,0 +1,523 @@
+// Package directory implements a thing description directory as defined by the
+// w3c wot discovery specification. Things are registered, retrieved, updated,
+// patched and deleted via http at /things and kept in an expanded thing
+// description set
+package directory
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/sparql"
+	"github.com/connctd/wotlib/storage"
+)
+
+// WellKnownPath is the path the td of the directory is served at
+const WellKnownPath = "/.well-known/wot"
+
+// ThingsPath is the path of the things api
+const ThingsPath = "/things"
+
+// defaultMaxBodySize is the default limit of request bodies
+const defaultMaxBodySize = 4 << 20
+
+// paths of the search api
+const (
+	SearchConstraintPath = "/search/constraint"
+	SearchJSONPathPath   = "/search/jsonpath"
+	SearchSPARQLPath     = "/search/sparql"
+)
+
+// content types of the directory api
+const (
+	TDContentType         = "application/td+json"
+	LDContentType         = "application/ld+json"
+	MergePatchContentType = "application/merge-patch+json"
+	ProblemContentType    = "application/problem+json"
+	JSONContentType       = "application/json"
+	SPARQLContentType     = "application/sparql-query"
+)
+
+// well known errors
+var (
+	ErrNotFound           = errors.New("thing not found")
+	ErrInvalidTD          = errors.New("invalid thing description")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrInvalidQuery       = errors.New("invalid query")
+)
+
+// Thing is a registered thing description
+type Thing struct {
+	// ID of the thing, generated for anonymous things
+	ID string
+	// Document is the td in its compact json form
+	Document []byte
+	// TD is the expanded td
+	TD wotlib.ExpandedThingDescription
+	// ETag identifies the version of the td
+	ETag string
+
+	triples []sparql.Triple
+}
+
+// Server is a thing description directory
+type Server struct {
+	baseURL      *url.URL
+	pageSize     int
+	maxBodySize  int64
+	eventHistory int
+	storage      storage.Storage
+	// epoch distinguishes event ids and list etags of different runs of the server
+	epoch string
+
+	mutex         sync.RWMutex
+	things        map[string]Thing
+	set           wotlib.ExpandedThingDescriptionSet
+	version       uint64
+	events        []event
+	lastEventID   uint64
+	eventsChanged chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Option configures a directory server
+type Option func(s *Server) error
+
+// WithBaseURL sets the url the directory is reachable at. By default the
+// base url is derived from incoming requests
+func WithBaseURL(baseURL string) Option {
+	return func(s *Server) error {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+
+		if u.Path == "" {
+			u.Path = "/"
+		}
+
+		s.baseURL = u
+		return nil
+	}
+}
+
+// WithPageSize limits the number of things listed per page if the client
+// doesn't ask for a limit. By default all things are listed at once
+func WithPageSize(pageSize int) Option {
+	return func(s *Server) error {
+		if pageSize < 0 {
+			return fmt.Errorf("invalid page size %d", pageSize)
+		}
+
+		s.pageSize = pageSize
+		return nil
+	}
+}
+
+// WithMaxBodySize limits the size of request bodies. Larger requests are
+// rejected with 413 Request Entity Too Large
+func WithMaxBodySize(size int64) Option {
+	return func(s *Server) error {
+		if size <= 0 {
+			return fmt.Errorf("invalid max body size %d", size)
+		}
+
+		s.maxBodySize = size
+		return nil
+	}
+}
+
+// WithStorage persists all things in the storage. Stored things are
+// restored when the directory is created
+func WithStorage(storage storage.Storage) Option {
+	return func(s *Server) error {
+		s.storage = storage
+		return nil
+	}
+}
+
+// NewServer creates a directory, restoring the things of its storage
+func NewServer(opts ...Option) (*Server, error) {
+	s := &Server{
+		maxBodySize:   defaultMaxBodySize,
+		eventHistory:  defaultEventHistory,
+		epoch:         strconv.FormatInt(time.Now().UnixNano(), 36),
+		things:        map[string]Thing{},
+		set:           wotlib.NewExpandedThingDescriptionSet(),
+		eventsChanged: make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.storage != nil {
+		if err := s.restore(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Things returns a copy of the set of all registered things
+func (s *Server) Things() wotlib.ExpandedThingDescriptionSet {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	set := wotlib.NewExpandedThingDescriptionSet()
+	for _, currTD := range s.set {
+		set.Append(currTD)
+	}
+
+	return set
+}
+
+// Get returns a registered thing
+func (s *Server) Get(id string) (Thing, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	thing, ok := s.things[id]
+	if !ok {
+		return Thing{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return thing, nil
+}
+
+// Create registers an anonymous thing description. An id is generated
+// and added to the document
+func (s *Server) Create(td []byte) (Thing, error) {
+	document, err := parseDocument(td)
+	if err != nil {
+		return Thing{}, err
+	}
+
+	if _, ok := document["id"]; ok {
+		return Thing{}, fmt.Errorf("%w: anonymous things must not have an id", ErrInvalidTD)
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return Thing{}, err
+	}
+
+	document["id"] = id
+
+	thing, err := prepare(id, document)
+	if err != nil {
+		return Thing{}, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.store(thing)
+}
+
+// Put creates or replaces the thing description with the given id. The id
+// of the document has to match. If ifMatch is not empty the thing has to
+// exist with this etag. It returns whether the thing was created
+func (s *Server) Put(id string, td []byte, ifMatch string) (Thing, bool, error) {
+	document, err := parseDocument(td)
+	if err != nil {
+		return Thing{}, false, err
+	}
+
+	if documentID, _ := document["id"].(string); documentID != id {
+		return Thing{}, false, fmt.Errorf("%w: id %q does not match %q", ErrInvalidTD, documentID, id)
+	}
+
+	thing, err := prepare(id, document)
+	if err != nil {
+		return Thing{}, false, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, exists := s.things[id]
+	if err := checkPrecondition(existing, exists, ifMatch); err != nil {
+		return Thing{}, false, err
+	}
+
+	thing, err = s.store(thing)
+
+	return thing, !exists, err
+}
+
+// Patch applies a json merge patch (RFC 7396) to a registered thing description.
+// If ifMatch is not empty the thing has to have this etag
+func (s *Server) Patch(id string, patch []byte, ifMatch string) (Thing, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return Thing{}, fmt.Errorf("%w: %v", ErrInvalidTD, err)
+	}
+
+	for {
+		existing, err := s.Get(id)
+		if err != nil {
+			return Thing{}, err
+		}
+
+		if err := checkPrecondition(existing, true, ifMatch); err != nil {
+			return Thing{}, err
+		}
+
+		var document interface{}
+		if err := json.Unmarshal(existing.Document, &document); err != nil {
+			return Thing{}, err
+		}
+
+		patched, ok := mergePatch(document, patchValue).(map[string]interface{})
+		if !ok {
+			return Thing{}, fmt.Errorf("%w: patch replaces the document", ErrInvalidTD)
+		}
+
+		if patchedID, _ := patched["id"].(string); patchedID != id {
+			return Thing{}, fmt.Errorf("%w: the id can't be patched", ErrInvalidTD)
+		}
+
+		thing, err := prepare(id, patched)
+		if err != nil {
+			return Thing{}, err
+		}
+
+		s.mutex.Lock()
+		current, exists := s.things[id]
+		if exists && current.ETag == existing.ETag {
+			thing, err = s.store(thing)
+			s.mutex.Unlock()
+
+			return thing, err
+		}
+		s.mutex.Unlock()
+
+		// the thing changed while the patch was applied, patch the current version
+	}
+}
+
+// Delete removes a thing description. If ifMatch is not empty the thing
+// has to have this etag
+func (s *Server) Delete(id string, ifMatch string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, exists := s.things[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	if err := checkPrecondition(existing, exists, ifMatch); err != nil {
+		return err
+	}
+
+	if s.storage != nil {
+		if err := s.storage.Delete(id); err != nil {
+			return err
+		}
+	}
+
+	delete(s.things, id)
+	s.set.Remove(id)
+	s.version++
+	s.emit(EventThingDeleted, id, existing.Document, nil)
+
+	return nil
+}
+
+// List returns a page of things ordered by id and the total number of things.
+// A limit of zero lists all things after the offset
+func (s *Server) List(offset, limit int) ([]Thing, int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ids := make([]string, 0, len(s.things))
+	for id := range s.things {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	if offset > len(ids) {
+		offset = len(ids)
+	}
+
+	end := len(ids)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	things := make([]Thing, 0, end-offset)
+	for _, currID := range ids[offset:end] {
+		things = append(things, s.things[currID])
+	}
+
+	return things, len(ids)
+}
+
+// listETag identifies the current state of all things. The version is
+// prefixed with the epoch of the server as it isn't persisted, so etags of
+// a restarted directory don't match the ones clients already saw
+func (s *Server) listETag() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return fmt.Sprintf(`"%s-v%d"`, s.epoch, s.version)
+}
+
+// prepare expands a document and derives its triples and etag. Expanding
+// is expensive and may fetch remote contexts, so the mutex mustn't be locked
+func prepare(id string, document map[string]interface{}) (Thing, error) {
+	b, err := json.Marshal(document)
+	if err != nil {
+		return Thing{}, err
+	}
+
+	td, err := wotlib.FromBytes(b)
+	if err != nil {
+		return Thing{}, fmt.Errorf("%w: %v", ErrInvalidTD, err)
+	}
+
+	triples, err := toTriples(b)
+	if err != nil {
+		return Thing{}, fmt.Errorf("%w: %v", ErrInvalidTD, err)
+	}
+
+	etag, err := documentETag(b)
+	if err != nil {
+		return Thing{}, fmt.Errorf("%w: %v", ErrInvalidTD, err)
+	}
+
+	return Thing{ID: id, Document: b, TD: td, ETag: etag, triples: triples}, nil
+}
+
+// store stores a prepared thing. Things with the etag of the stored one
+// are equivalent and don't change the directory. The mutex has to be locked
+func (s *Server) store(thing Thing) (Thing, error) {
+	existing, exists := s.things[thing.ID]
+	if exists && existing.ETag == thing.ETag {
+		return existing, nil
+	}
+
+	if s.storage != nil {
+		if err := s.storage.Put(storage.Record{ID: thing.ID, Raw: thing.Document, TD: thing.TD}); err != nil {
+			return Thing{}, err
+		}
+	}
+
+	s.things[thing.ID] = thing
+	s.set.Append(thing.TD)
+	s.version++
+
+	if exists {
+		s.emit(EventThingUpdated, thing.ID, existing.Document, thing.Document)
+	} else {
+		s.emit(EventThingCreated, thing.ID, nil, thing.Document)
+	}
+
+	return thing, nil
+}
+
+// restore loads all things of the storage
+func (s *Server) restore() error {
+	records, err := s.storage.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, currRecord := range records {
+		currRecord.TD = currRecord.TD.WithDocument(currRecord.Raw)
+
+		triples, err := toTriples(currRecord.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", currRecord.ID, err)
+		}
+
+		etag, err := documentETag(currRecord.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", currRecord.ID, err)
+		}
+
+		s.things[currRecord.ID] = Thing{
+			ID:       currRecord.ID,
+			Document: currRecord.Raw,
+			TD:       currRecord.TD,
+			ETag:     etag,
+			triples:  triples,
+		}
+		s.set.Append(currRecord.TD)
+	}
+
+	return nil
+}
+
+// documentETag derives the etag of a thing from the canonical hash of its
+// document, which doesn't change with the order of members
+func documentETag(document []byte) (string, error) {
+	hash, err := wotlib.HashBytes(document)
+	if err != nil {
+		return "", err
+	}
+
+	return `"` + hash + `"`, nil
+}
+
+// parseDocument parses a compact td and checks its mandatory members
+func parseDocument(td []byte) (map[string]interface{}, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTD, err)
+	}
+
+	if _, ok := document["@context"]; !ok {
+		return nil, fmt.Errorf("%w: @context is missing", ErrInvalidTD)
+	}
+
+	if title, _ := document["title"].(string); title == "" {
+		return nil, fmt.Errorf("%w: title is missing", ErrInvalidTD)
+	}
+
+	return document, nil
+}
+
+// checkPrecondition compares the etag of a thing with the expected one
+func checkPrecondition(thing Thing, exists bool, ifMatch string) error {
+	if ifMatch == "" || ifMatch == "*" && exists {
+		return nil
+	}
+
+	if !exists || thing.ETag != ifMatch {
+		return fmt.Errorf("%w: etag does not match", ErrPreconditionFailed)
+	}
+
+	return nil
+}
+
+// generateID generates a random uuid urn
+func generateID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	// version 4, variant 10
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,435 @@
+package directory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/wottest"
+	"github.com/connctd/wotlib/storage"
+)
+
+func init() {
+	wotlib.DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
+
+func testTD(id string, title string) string {
+	td := map[string]interface{}{
+		"@context":            "https://www.w3.org/2019/wot/td/v1",
+		"title":               title,
+		"securityDefinitions": map[string]interface{}{"nosec_sc": map[string]interface{}{"scheme": "nosec"}},
+		"security":            []interface{}{"nosec_sc"},
+		"properties": map[string]interface{}{
+			"on": map[string]interface{}{"type": "boolean", "forms": []interface{}{map[string]interface{}{"href": "http://lamp.local/on"}}},
+		},
+	}
+
+	if id != "" {
+		td["id"] = id
+	}
+
+	b, _ := json.Marshal(td)
+	return string(b)
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *httptest.Server) {
+	s, err := NewServer(opts...)
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	return s, httptest.NewServer(s)
+}
+
+func doRequest(t *testing.T, method string, u string, contentType string, body string, header ...string) *http.Response {
+	req, err := http.NewRequest(method, u, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	return resp
+}
+
+func expectStatus(t *testing.T, resp *http.Response, status int) []byte {
+	defer resp.Body.Close()
+
+	b, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != status {
+		t.Fatalf("Expected status %d for %s %s, got %d: %s", status, resp.Request.Method, resp.Request.URL, resp.StatusCode, b)
+	}
+
+	return b
+}
+
+func TestDirectory(t *testing.T) {
+	s, server := newTestServer(t)
+	defer server.Close()
+
+	thingURL := server.URL + ThingsPath + "/" + url.PathEscape("urn:dev:ops:lamp")
+
+	resp := doRequest(t, http.MethodPut, thingURL, TDContentType, testTD("urn:dev:ops:lamp", "Lamp"))
+	expectStatus(t, resp, http.StatusCreated)
+	etag := resp.Header.Get("ETag")
+
+	resp = doRequest(t, http.MethodGet, thingURL, "", "")
+	b := expectStatus(t, resp, http.StatusOK)
+	if resp.Header.Get("ETag") != etag || resp.Header.Get("Content-Type") != TDContentType {
+		t.Fatalf("Unexpected headers %v", resp.Header)
+	}
+
+	td, err := wotlib.FromBytes(b)
+	if err != nil || td.ID != "urn:dev:ops:lamp" {
+		t.Fatalf("Unexpected td %s (%v)", td.ID, err)
+	}
+
+	expectStatus(t, doRequest(t, http.MethodGet, thingURL, "", "", "If-None-Match", etag), http.StatusNotModified)
+
+	// updates require the current etag if given
+	expectStatus(t, doRequest(t, http.MethodPut, thingURL, TDContentType, testTD("urn:dev:ops:lamp", "Lamp 2"), "If-Match", `"outdated"`), http.StatusPreconditionFailed)
+	resp = doRequest(t, http.MethodPut, thingURL, TDContentType, testTD("urn:dev:ops:lamp", "Lamp 2"), "If-Match", etag)
+	expectStatus(t, resp, http.StatusNoContent)
+	if resp.Header.Get("ETag") == etag {
+		t.Fatalf("Expected etag to change")
+	}
+
+	resp = doRequest(t, http.MethodPatch, thingURL, MergePatchContentType, `{"description": "patched", "properties": {"on": null}}`)
+	expectStatus(t, resp, http.StatusNoContent)
+
+	thing, err := s.Get("urn:dev:ops:lamp")
+	if err != nil {
+		t.Fatalf("Failed to get thing: %v", err)
+	}
+
+	var document map[string]interface{}
+	json.Unmarshal(thing.Document, &document)
+	if document["title"] != "Lamp 2" || document["description"] != "patched" || len(document["properties"].(map[string]interface{})) != 0 {
+		t.Fatalf("Unexpected patched document %v", document)
+	}
+
+	if things := s.Things(); len(things) != 1 || len(things["urn:dev:ops:lamp"].Properties) != 0 {
+		t.Fatalf("Unexpected set %v", things)
+	}
+
+	expectStatus(t, doRequest(t, http.MethodPatch, thingURL, MergePatchContentType, `{"id": "urn:dev:ops:other"}`), http.StatusBadRequest)
+	expectStatus(t, doRequest(t, http.MethodPatch, thingURL, TDContentType, `{}`), http.StatusUnsupportedMediaType)
+
+	// anonymous things get generated ids
+	resp = doRequest(t, http.MethodPost, server.URL+ThingsPath, TDContentType, testTD("", "Anonymous"))
+	expectStatus(t, resp, http.StatusCreated)
+
+	location := resp.Header.Get("Location")
+	if !strings.HasPrefix(location, server.URL+ThingsPath+"/urn:uuid:") {
+		t.Fatalf("Unexpected location %s", location)
+	}
+
+	expectStatus(t, doRequest(t, http.MethodGet, location, "", ""), http.StatusOK)
+	expectStatus(t, doRequest(t, http.MethodPost, server.URL+ThingsPath, TDContentType, testTD("urn:dev:ops:lamp", "Lamp")), http.StatusBadRequest)
+
+	expectStatus(t, doRequest(t, http.MethodDelete, location, "", ""), http.StatusNoContent)
+	expectStatus(t, doRequest(t, http.MethodGet, location, "", ""), http.StatusNotFound)
+	expectStatus(t, doRequest(t, http.MethodDelete, location, "", ""), http.StatusNotFound)
+}
+
+func TestDirectoryErrors(t *testing.T) {
+	_, server := newTestServer(t, WithMaxBodySize(1024))
+	defer server.Close()
+
+	thingURL := server.URL + ThingsPath + "/urn:dev:ops:lamp"
+
+	testCases := []struct {
+		method      string
+		u           string
+		contentType string
+		body        string
+		status      int
+	}{
+		{http.MethodPut, thingURL, TDContentType, testTD("urn:dev:ops:other", "Lamp"), http.StatusBadRequest},
+		{http.MethodPut, thingURL, TDContentType, `{"id": "urn:dev:ops:lamp"}`, http.StatusBadRequest},
+		{http.MethodPut, thingURL, TDContentType, `not json`, http.StatusBadRequest},
+		{http.MethodPut, thingURL, "text/plain", testTD("urn:dev:ops:lamp", "Lamp"), http.StatusUnsupportedMediaType},
+		{http.MethodPut, thingURL, TDContentType, testTD("urn:dev:ops:lamp", strings.Repeat("Lamp", 256)), http.StatusRequestEntityTooLarge},
+		{http.MethodPatch, thingURL, MergePatchContentType, `{}`, http.StatusNotFound},
+		{http.MethodPost, thingURL, TDContentType, `{}`, http.StatusMethodNotAllowed},
+		{http.MethodGet, server.URL + ThingsPath + "?limit=-1", "", "", http.StatusBadRequest},
+		{http.MethodGet, server.URL + "/unknown", "", "", http.StatusNotFound},
+	}
+
+	for _, currTestCase := range testCases {
+		resp := doRequest(t, currTestCase.method, currTestCase.u, currTestCase.contentType, currTestCase.body)
+		expectStatus(t, resp, currTestCase.status)
+
+		if resp.Header.Get("Content-Type") != ProblemContentType {
+			t.Fatalf("Expected problem response, got %s", resp.Header.Get("Content-Type"))
+		}
+	}
+
+	// bodies of unknown length are limited while reading
+	req, err := http.NewRequest(http.MethodPut, thingURL, ioutil.NopCloser(strings.NewReader(testTD("urn:dev:ops:lamp", strings.Repeat("Lamp", 256)))))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", TDContentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	expectStatus(t, resp, http.StatusRequestEntityTooLarge)
+}
+
+func TestDirectoryList(t *testing.T) {
+	_, server := newTestServer(t, WithPageSize(2))
+	defer server.Close()
+
+	for _, currName := range []string{"c", "a", "e", "b", "d"} {
+		id := "urn:dev:ops:" + currName
+		expectStatus(t, doRequest(t, http.MethodPut, server.URL+ThingsPath+"/"+id, TDContentType, testTD(id, currName)), http.StatusCreated)
+	}
+
+	expected := []string{"urn:dev:ops:a", "urn:dev:ops:b", "urn:dev:ops:c", "urn:dev:ops:d", "urn:dev:ops:e"}
+
+	var ids []string
+	var etag string
+	next := server.URL + ThingsPath
+	for next != "" {
+		resp := doRequest(t, http.MethodGet, next, "", "")
+		b := expectStatus(t, resp, http.StatusOK)
+
+		var page []map[string]interface{}
+		if err := json.Unmarshal(b, &page); err != nil {
+			t.Fatalf("Failed to decode page: %v", err)
+		}
+
+		if len(page) > 2 || resp.Header.Get("X-Total-Count") != "5" {
+			t.Fatalf("Unexpected page of %d things", len(page))
+		}
+
+		for _, currThing := range page {
+			ids = append(ids, currThing["id"].(string))
+		}
+
+		etag = resp.Header.Get("ETag")
+		next = ""
+		if link := resp.Header.Get("Link"); link != "" {
+			next = strings.TrimPrefix(strings.SplitN(link, ">", 2)[0], "<")
+		}
+	}
+
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Unexpected ids %v", ids)
+	}
+
+	expectStatus(t, doRequest(t, http.MethodGet, server.URL+ThingsPath, "", "", "If-None-Match", etag), http.StatusNotModified)
+	expectStatus(t, doRequest(t, http.MethodDelete, server.URL+ThingsPath+"/urn:dev:ops:a", "", ""), http.StatusNoContent)
+	expectStatus(t, doRequest(t, http.MethodGet, server.URL+ThingsPath, "", "", "If-None-Match", etag), http.StatusOK)
+}
+
+func TestDirectoryETag(t *testing.T) {
+	s, server := newTestServer(t)
+	defer server.Close()
+
+	u := server.URL + ThingsPath + "/urn:dev:ops:lamp"
+	td := testTD("urn:dev:ops:lamp", "Lamp")
+
+	expectStatus(t, doRequest(t, http.MethodPut, u, TDContentType, td), http.StatusCreated)
+
+	resp := doRequest(t, http.MethodGet, u, "", "")
+	b := expectStatus(t, resp, http.StatusOK)
+
+	hash, err := wotlib.HashBytes(b)
+	if err != nil || resp.Header.Get("ETag") != `"`+hash+`"` {
+		t.Fatalf("Expected canonical hash %s as etag, got %s (%v)", hash, resp.Header.Get("ETag"), err)
+	}
+
+	// a single security scheme may be given without array, which is the same td
+	equivalent := strings.Replace(td, `"security":["nosec_sc"]`, `"security":"nosec_sc"`, 1)
+	if equivalent == td {
+		t.Fatalf("Failed to build equivalent td")
+	}
+
+	listETag := doRequest(t, http.MethodGet, server.URL+ThingsPath, "", "").Header.Get("ETag")
+
+	expectStatus(t, doRequest(t, http.MethodPut, u, TDContentType, equivalent, "If-Match", resp.Header.Get("ETag")), http.StatusNoContent)
+
+	thing, err := s.Get("urn:dev:ops:lamp")
+	if err != nil || thing.ETag != resp.Header.Get("ETag") || s.lastEventID != 1 {
+		t.Fatalf("Expected equivalent td to keep etag %s without event, got %s after %d events", resp.Header.Get("ETag"), thing.ETag, s.lastEventID)
+	}
+
+	// the list didn't change either
+	expectStatus(t, doRequest(t, http.MethodGet, server.URL+ThingsPath, "", "", "If-None-Match", listETag), http.StatusNotModified)
+}
+
+func TestDirectoryConcurrentPatch(t *testing.T) {
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	if _, _, err := s.Put("urn:dev:ops:lamp", []byte(testTD("urn:dev:ops:lamp", "Lamp")), ""); err != nil {
+		t.Fatalf("Failed to store thing: %v", err)
+	}
+
+	// patches are applied outside of the lock and must not overwrite each other
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := s.Patch("urn:dev:ops:lamp", []byte(fmt.Sprintf(`{"description%d": "patched"}`, i)), "")
+			errs <- err
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to patch thing: %v", err)
+		}
+	}
+
+	thing, err := s.Get("urn:dev:ops:lamp")
+	if err != nil {
+		t.Fatalf("Failed to get thing: %v", err)
+	}
+
+	var document map[string]interface{}
+	json.Unmarshal(thing.Document, &document)
+	for i := 0; i < 10; i++ {
+		if document[fmt.Sprintf("description%d", i)] != "patched" {
+			t.Fatalf("Expected patch %d to be applied, got %s", i, thing.Document)
+		}
+	}
+}
+
+func TestDirectoryStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wotlib-directory")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	open := func() (*storage.File, *httptest.Server) {
+		f, err := storage.OpenFile(dir)
+		if err != nil {
+			t.Fatalf("Failed to open storage: %v", err)
+		}
+
+		_, server := newTestServer(t, WithStorage(f))
+		return f, server
+	}
+
+	f, server := open()
+
+	resp := doRequest(t, http.MethodGet, server.URL+ThingsPath, "", "")
+	expectStatus(t, resp, http.StatusOK)
+	listETag := resp.Header.Get("ETag")
+
+	for _, currName := range []string{"a", "b"} {
+		id := "urn:dev:ops:" + currName
+		expectStatus(t, doRequest(t, http.MethodPut, server.URL+ThingsPath+"/"+id, TDContentType, testTD(id, currName)), http.StatusCreated)
+	}
+
+	expectStatus(t, doRequest(t, http.MethodDelete, server.URL+ThingsPath+"/urn:dev:ops:a", "", ""), http.StatusNoContent)
+
+	resp = doRequest(t, http.MethodGet, server.URL+ThingsPath+"/urn:dev:ops:b", "", "")
+	expectStatus(t, resp, http.StatusOK)
+	etag := resp.Header.Get("ETag")
+
+	server.Close()
+	f.Close()
+
+	// a restarted directory restores the things with their etags
+	f, server = open()
+	defer f.Close()
+	defer server.Close()
+
+	expectStatus(t, doRequest(t, http.MethodGet, server.URL+ThingsPath+"/urn:dev:ops:a", "", ""), http.StatusNotFound)
+	expectStatus(t, doRequest(t, http.MethodGet, server.URL+ThingsPath+"/urn:dev:ops:b", "", "", "If-None-Match", etag), http.StatusNotModified)
+
+	// the version of the list isn't persisted, its etags must not repeat the ones of the previous run
+	expectStatus(t, doRequest(t, http.MethodGet, server.URL+ThingsPath, "", "", "If-None-Match", listETag), http.StatusOK)
+
+	b := expectStatus(t, doRequest(t, http.MethodGet, server.URL+SearchSPARQLPath+"?query="+url.QueryEscape(`SELECT ?title WHERE { ?thing <https://www.w3.org/2019/wot/td#title> ?title }`), "", ""), http.StatusOK)
+	if !strings.Contains(string(b), `"value":"b"`) {
+		t.Fatalf("Expected restored thing to be searchable, got %s", b)
+	}
+}
+
+func TestDirectoryTD(t *testing.T) {
+	_, server := newTestServer(t)
+	defer server.Close()
+
+	resp := doRequest(t, http.MethodGet, server.URL+WellKnownPath, "", "")
+	b := expectStatus(t, resp, http.StatusOK)
+
+	td, err := wotlib.FromBytes(b)
+	if err != nil {
+		t.Fatalf("Failed to expand directory td: %v", err)
+	}
+
+	if !reflect.DeepEqual(td.Type, []string{TypeThingDirectory}) {
+		t.Fatalf("Unexpected types %v", td.Type)
+	}
+
+	if td.Base.Value() != server.URL+"/" || len(td.Properties) != 1 || len(td.Actions) != 8 || len(td.Events) != 3 {
+		t.Fatalf("Unexpected directory td %s", bytes.TrimSpace(b))
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	testCases := []struct {
+		document string
+		patch    string
+		expected string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+	}
+
+	for _, currTestCase := range testCases {
+		var document, patch, expected interface{}
+		json.Unmarshal([]byte(currTestCase.document), &document)
+		json.Unmarshal([]byte(currTestCase.patch), &patch)
+		json.Unmarshal([]byte(currTestCase.expected), &expected)
+
+		if result := mergePatch(document, patch); !reflect.DeepEqual(result, expected) {
+			t.Fatalf("Expected %s patched with %s to be %s, got %v", currTestCase.document, currTestCase.patch, currTestCase.expected, result)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package directory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/connctd/wotlib/internal/sse"
+)
+
+// EventsPath is the path of the event stream of lifecycle changes
+const EventsPath = "/events"
+
+// types of lifecycle events
+const (
+	EventThingCreated = "thing_created"
+	EventThingUpdated = "thing_updated"
+	EventThingDeleted = "thing_deleted"
+)
+
+// EventReset tells clients that events were missed, e.g. because the history
+// was truncated or the directory restarted. Clients have to list all things again
+const EventReset = "reset"
+
+// defaultEventHistory is the number of events kept to resume interrupted streams
+const defaultEventHistory = 1000
+
+// event is a lifecycle change of a thing. The diff is the created td,
+// a merge patch of an update or the id of a deleted thing
+type event struct {
+	id      uint64
+	typ     string
+	thingID string
+	diff    []byte
+}
+
+// WithEventHistory sets the number of events kept to resume interrupted
+// event streams. Clients missing older events receive an EventReset. With
+// 0 every event is dropped, so streams don't deliver events and each
+// resuming client receives an EventReset
+func WithEventHistory(events int) Option {
+	return func(s *Server) error {
+		if events < 0 {
+			return fmt.Errorf("invalid event history %d", events)
+		}
+
+		s.eventHistory = events
+		return nil
+	}
+}
+
+// Close ends all event streams
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// emit records a lifecycle event and wakes up all streams. The mutex has to be locked
+func (s *Server) emit(typ string, thingID string, old []byte, new []byte) {
+	var diff []byte
+	switch typ {
+	case EventThingCreated:
+		diff = new
+	case EventThingUpdated:
+		var oldDocument, newDocument interface{}
+		json.Unmarshal(old, &oldDocument)
+		json.Unmarshal(new, &newDocument)
+
+		patch, _ := createMergePatch(oldDocument, newDocument).(map[string]interface{})
+		if patch == nil {
+			patch = map[string]interface{}{}
+		}
+
+		patch["id"] = thingID
+		diff, _ = json.Marshal(patch)
+	case EventThingDeleted:
+		diff, _ = json.Marshal(map[string]interface{}{"id": thingID})
+	}
+
+	s.lastEventID++
+	s.events = append(s.events, event{id: s.lastEventID, typ: typ, thingID: thingID, diff: diff})
+	if len(s.events) > s.eventHistory {
+		s.events = append([]event(nil), s.events[len(s.events)-s.eventHistory:]...)
+	}
+
+	close(s.eventsChanged)
+	s.eventsChanged = make(chan struct{})
+}
+
+// eventID returns the id of an event as sent to clients. Ids are prefixed with
+// the epoch of the server, so they aren't reused after a restart
+func (s *Server) eventID(id uint64) string {
+	return s.epoch + "-" + strconv.FormatUint(id, 10)
+}
+
+// resumeAfter returns the event a stream resumes after. If events after the
+// given one are unknown or no longer kept, the stream starts after the current
+// event and needs to be reset
+func (s *Server) resumeAfter(lastEventID string) (uint64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if lastEventID == "" {
+		return s.lastEventID, false
+	}
+
+	i := strings.LastIndex(lastEventID, "-")
+	if i < 0 || lastEventID[:i] != s.epoch {
+		return s.lastEventID, true
+	}
+
+	id, err := strconv.ParseUint(lastEventID[i+1:], 10, 64)
+	if err != nil || id > s.lastEventID {
+		return s.lastEventID, true
+	}
+
+	if id < s.lastEventID && (len(s.events) == 0 || s.events[0].id > id+1) {
+		return s.lastEventID, true
+	}
+
+	return id, false
+}
+
+// eventsSince returns all kept events after the one with the given id and
+// a channel which is closed on the next event
+func (s *Server) eventsSince(id uint64) ([]event, <-chan struct{}) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var result []event
+	for _, currEvent := range s.events {
+		if currEvent.id > id {
+			result = append(result, currEvent)
+		}
+	}
+
+	return result, s.eventsChanged
+}
+
+// serveEvents streams lifecycle events as server-sent events. The path may
+// restrict the stream to one type of events. With the query parameter
+// diff=true events carry the created td or the merge patch of an update,
+// otherwise only the id of the thing. Streams resume after the event
+// given by the Last-Event-ID header or start with an EventReset if
+// events after it are lost
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request, typ string) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, "GET")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	diff := r.URL.Query().Get("diff") == "true"
+
+	// ids of events are sequential, newer streams start after the current event
+	last, reset := s.resumeAfter(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", sse.ContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// clients get a position to resume from even if no event follows
+	var err error
+	if reset {
+		err = sse.Write(w, sse.Event{ID: s.eventID(last), Type: EventReset, Data: "{}"})
+	} else {
+		err = sse.WriteID(w, s.eventID(last))
+	}
+
+	if err != nil {
+		return
+	}
+
+	flusher.Flush()
+
+	for {
+		events, changed := s.eventsSince(last)
+
+		for _, currEvent := range events {
+			last = currEvent.id
+			if typ != "" && currEvent.typ != typ {
+				continue
+			}
+
+			data := currEvent.diff
+			if !diff || currEvent.typ == EventThingDeleted {
+				data, _ = json.Marshal(map[string]interface{}{"id": currEvent.thingID})
+			}
+
+			if err := sse.Write(w, sse.Event{ID: s.eventID(currEvent.id), Type: currEvent.typ, Data: string(data)}); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-s.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// eventType returns the type of events selected by a path below EventsPath
+func eventType(path string) (string, bool) {
+	typ := strings.TrimPrefix(strings.TrimPrefix(path, EventsPath), "/")
+
+	switch typ {
+	case "", EventThingCreated, EventThingUpdated, EventThingDeleted:
+		return typ, true
+	}
+
+	return "", false
+}
+
+// createMergePatch returns a json merge patch turning the old into the new document
+func createMergePatch(old interface{}, new interface{}) interface{} {
+	oldObject, oldIsObject := old.(map[string]interface{})
+	newObject, newIsObject := new.(map[string]interface{})
+	if !oldIsObject || !newIsObject {
+		return new
+	}
+
+	patch := map[string]interface{}{}
+	for key, currValue := range newObject {
+		oldValue, exists := oldObject[key]
+		if !exists {
+			patch[key] = currValue
+			continue
+		}
+
+		if reflect.DeepEqual(oldValue, currValue) {
+			continue
+		}
+
+		patch[key] = createMergePatch(oldValue, currValue)
+	}
+
+	for key := range oldObject {
+		if _, exists := newObject[key]; !exists {
+			patch[key] = nil
+		}
+	}
+
+	return patch
+}
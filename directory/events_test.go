@@ -0,0 +1,346 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/sse"
+)
+
+func openStream(t *testing.T, ctx context.Context, u string, lastEventID string) *sse.Reader {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != sse.ContentType {
+		t.Fatalf("Expected event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	return sse.NewReader(resp.Body)
+}
+
+func TestEvents(t *testing.T) {
+	s, server := newTestServer(t)
+	defer server.Close()
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, _, err := s.Put("urn:dev:ops:old", []byte(testTD("urn:dev:ops:old", "Old")), ""); err != nil {
+		t.Fatalf("Failed to store thing: %v", err)
+	}
+
+	// new streams start after the current event
+	stream := openStream(t, ctx, server.URL+EventsPath+"?diff=true", "")
+
+	if _, _, err := s.Put("urn:dev:ops:lamp", []byte(testTD("urn:dev:ops:lamp", "Lamp")), ""); err != nil {
+		t.Fatalf("Failed to store thing: %v", err)
+	}
+
+	if _, err := s.Patch("urn:dev:ops:lamp", []byte(`{"title": "Lamp 2", "properties": null}`), ""); err != nil {
+		t.Fatalf("Failed to patch thing: %v", err)
+	}
+
+	if err := s.Delete("urn:dev:ops:lamp", ""); err != nil {
+		t.Fatalf("Failed to delete thing: %v", err)
+	}
+
+	tests := []struct {
+		typ      string
+		expected map[string]interface{}
+	}{
+		{EventThingCreated, map[string]interface{}{"id": "urn:dev:ops:lamp", "title": "Lamp"}},
+		{EventThingUpdated, map[string]interface{}{"id": "urn:dev:ops:lamp", "title": "Lamp 2", "properties": nil}},
+		{EventThingDeleted, map[string]interface{}{"id": "urn:dev:ops:lamp"}},
+	}
+
+	for i, currTest := range tests {
+		e, err := stream.Next()
+		if err != nil {
+			t.Fatalf("Failed to read event %d: %v", i, err)
+		}
+
+		if e.Type != currTest.typ {
+			t.Fatalf("Expected event %d to be %s, got %s", i, currTest.typ, e.Type)
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+			t.Fatalf("Failed to decode event %d: %v", i, err)
+		}
+
+		for key, expected := range currTest.expected {
+			if value, ok := data[key]; !ok || value != expected {
+				t.Fatalf("Expected %s of event %d to be %v, got %v", key, i, expected, data)
+			}
+		}
+
+		if currTest.typ == EventThingUpdated && len(data) != len(currTest.expected) {
+			t.Fatalf("Expected merge patch %v, got %v", currTest.expected, data)
+		}
+	}
+
+	// streams of one type without diff resume after the given event
+	stream = openStream(t, ctx, server.URL+EventsPath+"/"+EventThingCreated, s.eventID(0))
+
+	for _, currID := range []string{"urn:dev:ops:old", "urn:dev:ops:lamp"} {
+		e, err := stream.Next()
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+
+		if e.Type != EventThingCreated || e.Data != `{"id":"`+currID+`"}` {
+			t.Fatalf("Expected creation of %s, got %v", currID, e)
+		}
+	}
+
+	resp := doRequest(t, http.MethodGet, server.URL+EventsPath+"/unknown", "", "")
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestEventsReset(t *testing.T) {
+	s, server := newTestServer(t, WithEventHistory(2))
+	defer server.Close()
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, currID := range []string{"urn:dev:ops:a", "urn:dev:ops:b", "urn:dev:ops:c"} {
+		if _, _, err := s.Put(currID, []byte(testTD(currID, "Lamp")), ""); err != nil {
+			t.Fatalf("Failed to store thing: %v", err)
+		}
+	}
+
+	tests := []struct {
+		lastEventID string
+		expected    string
+	}{
+		// the first event is no longer kept
+		{s.eventID(0), EventReset},
+		{s.eventID(1), EventThingCreated},
+		// ids of another run or from the future are unknown
+		{"0", EventReset},
+		{"restarted-1", EventReset},
+		{s.eventID(4), EventReset},
+	}
+
+	for i, currTest := range tests {
+		streamCtx, stop := context.WithCancel(ctx)
+		stream := openStream(t, streamCtx, server.URL+EventsPath, currTest.lastEventID)
+
+		e, err := stream.Next()
+		if err != nil {
+			t.Fatalf("Test %d: failed to read event: %v", i, err)
+		}
+
+		if e.Type != currTest.expected {
+			t.Fatalf("Test %d: expected %s, got %v", i, currTest.expected, e)
+		}
+
+		// streams continue after the current event after a reset
+		if e.Type == EventReset && e.ID != s.eventID(3) {
+			t.Fatalf("Test %d: expected reset to the current event, got %s", i, e.ID)
+		}
+
+		stop()
+	}
+}
+
+func TestEventHistory(t *testing.T) {
+	if _, err := NewServer(WithEventHistory(-1)); err == nil {
+		t.Fatalf("Expected negative event history to be rejected")
+	}
+
+	s, server := newTestServer(t, WithEventHistory(0))
+	defer server.Close()
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, _, err := s.Put("urn:dev:ops:a", []byte(testTD("urn:dev:ops:a", "Lamp")), ""); err != nil {
+		t.Fatalf("Failed to store thing: %v", err)
+	}
+
+	// without history every resuming client is reset
+	stream := openStream(t, ctx, server.URL+EventsPath, s.eventID(0))
+
+	if e, err := stream.Next(); err != nil || e.Type != EventReset || e.ID != s.eventID(1) {
+		t.Fatalf("Expected reset, got %v (%v)", e, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s, server := newTestServer(t)
+	defer server.Close()
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c, err := NewClient(server.URL, WithRetryDelay(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := c.Register(ctx, []byte(testTD("urn:dev:ops:a", "a"))); err != nil {
+		t.Fatalf("Failed to register thing: %v", err)
+	}
+
+	var mutex sync.Mutex
+	set := wotlib.NewExpandedThingDescriptionSet()
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- c.Watch(watchCtx, set, &mutex)
+	}()
+
+	waitFor := func(description string, condition func() bool) {
+		for {
+			mutex.Lock()
+			ok := condition()
+			mutex.Unlock()
+
+			if ok {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Timed out waiting for %s", description)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	waitFor("initial things", func() bool { return len(set) == 1 })
+
+	if _, err := c.Register(ctx, []byte(testTD("urn:dev:ops:b", "b"))); err != nil {
+		t.Fatalf("Failed to register thing: %v", err)
+	}
+
+	waitFor("created thing", func() bool { return set.Get("urn:dev:ops:b").ID != "" })
+
+	if _, err := c.Patch(ctx, "urn:dev:ops:b", []byte(`{"properties": null}`)); err != nil {
+		t.Fatalf("Failed to patch thing: %v", err)
+	}
+
+	waitFor("updated thing", func() bool { return len(set.Get("urn:dev:ops:b").Properties) == 0 })
+
+	if err := c.Delete(ctx, "urn:dev:ops:a"); err != nil {
+		t.Fatalf("Failed to delete thing: %v", err)
+	}
+
+	waitFor("deleted thing", func() bool { return len(set) == 1 })
+
+	stopWatch()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected watch to end with the context, got %v", err)
+	}
+}
+
+func TestWatchRestart(t *testing.T) {
+	var mutex sync.Mutex
+	current, err := NewServer()
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer current.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		s := current
+		mutex.Unlock()
+
+		s.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, _, err := current.Put("urn:dev:ops:a", []byte(testTD("urn:dev:ops:a", "a")), ""); err != nil {
+		t.Fatalf("Failed to store thing: %v", err)
+	}
+
+	c, err := NewClient(server.URL, WithRetryDelay(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var setMutex sync.Mutex
+	set := wotlib.NewExpandedThingDescriptionSet()
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- c.Watch(watchCtx, set, &setMutex)
+	}()
+
+	waitFor := func(description string, condition func() bool) {
+		for {
+			setMutex.Lock()
+			ok := condition()
+			setMutex.Unlock()
+
+			if ok {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Timed out waiting for %s", description)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	waitFor("initial things", func() bool { return set.Get("urn:dev:ops:a").ID != "" })
+
+	// the restarted directory starts with new event ids and other things
+	restarted, err := NewServer()
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer restarted.Close()
+
+	if _, _, err := restarted.Put("urn:dev:ops:b", []byte(testTD("urn:dev:ops:b", "b")), ""); err != nil {
+		t.Fatalf("Failed to store thing: %v", err)
+	}
+
+	mutex.Lock()
+	previous := current
+	current = restarted
+	mutex.Unlock()
+
+	// ending the streams lets the client reconnect to the restarted directory
+	previous.Close()
+
+	waitFor("listed things", func() bool {
+		return len(set) == 1 && set.Get("urn:dev:ops:b").ID != ""
+	})
+
+	stopWatch()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected watch to end with the context, got %v", err)
+	}
+}
@@ -0,0 +1,405 @@
+package directory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/connctd/wotlib"
+)
+
+// problem is an error response as defined by RFC 7807
+type problem struct {
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ServeHTTP serves the td of the directory at the well known path and the
+// things api below ThingsPath
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, s.basePath())
+
+	switch {
+	case r.URL.Path == WellKnownPath || path == WellKnownPath:
+		s.serveTD(w, r)
+	case path == ThingsPath || path == ThingsPath+"/":
+		s.serveThings(w, r)
+	case strings.HasPrefix(path, ThingsPath+"/"):
+		s.serveThing(w, r, strings.TrimPrefix(path, ThingsPath+"/"))
+	case path == EventsPath || strings.HasPrefix(path, EventsPath+"/"):
+		typ, ok := eventType(path)
+		if !ok {
+			writeProblem(w, http.StatusNotFound, "")
+			return
+		}
+
+		s.serveEvents(w, r, typ)
+	case path == SearchConstraintPath:
+		s.searchConstraint(w, r)
+	case path == SearchJSONPathPath:
+		s.searchJSONPath(w, r)
+	case path == SearchSPARQLPath:
+		s.searchSPARQL(w, r)
+	default:
+		writeProblem(w, http.StatusNotFound, "")
+	}
+}
+
+func (s *Server) serveTD(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, "GET, HEAD")
+		return
+	}
+
+	b, err := s.Document(s.requestBaseURL(r))
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", TDContentType)
+	w.Write(b)
+}
+
+func (s *Server) serveThings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.listThings(w, r)
+	case http.MethodPost:
+		s.createThing(w, r)
+	default:
+		writeMethodNotAllowed(w, "GET, HEAD, POST")
+	}
+}
+
+func (s *Server) serveThing(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.retrieveThing(w, r, id)
+	case http.MethodPut:
+		s.putThing(w, r, id)
+	case http.MethodPatch:
+		s.patchThing(w, r, id)
+	case http.MethodDelete:
+		s.deleteThing(w, r, id)
+	default:
+		writeMethodNotAllowed(w, "DELETE, GET, HEAD, PATCH, PUT")
+	}
+}
+
+func (s *Server) listThings(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := queryInt(r, "limit", s.pageSize)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	etag := s.listETag()
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	things, total := s.List(offset, limit)
+
+	documents := make([]json.RawMessage, len(things))
+	for i := range things {
+		documents[i] = things[i].Document
+	}
+
+	b, err := json.Marshal(documents)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if next := offset + len(things); next < total {
+		u := s.thingsURL(r)
+		u.RawQuery = url.Values{"offset": {strconv.Itoa(next)}, "limit": {strconv.Itoa(limit)}}.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u))
+	}
+
+	w.Header().Set("Content-Type", LDContentType)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.Write(b)
+}
+
+func (s *Server) createThing(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.readBody(w, r, TDContentType, LDContentType)
+	if !ok {
+		return
+	}
+
+	thing, err := s.Create(b)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", s.thingsURL(r).String()+"/"+url.PathEscape(thing.ID))
+	w.Header().Set("ETag", thing.ETag)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) retrieveThing(w http.ResponseWriter, r *http.Request, id string) {
+	thing, err := s.Get(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", thing.ETag)
+	if r.Header.Get("If-None-Match") == thing.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", TDContentType)
+	w.Write(thing.Document)
+}
+
+func (s *Server) putThing(w http.ResponseWriter, r *http.Request, id string) {
+	b, ok := s.readBody(w, r, TDContentType, LDContentType)
+	if !ok {
+		return
+	}
+
+	thing, created, err := s.Put(id, b, r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", thing.ETag)
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) patchThing(w http.ResponseWriter, r *http.Request, id string) {
+	b, ok := s.readBody(w, r, MergePatchContentType)
+	if !ok {
+		return
+	}
+
+	thing, err := s.Patch(id, b, r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", thing.ETag)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteThing(w http.ResponseWriter, r *http.Request, id string) {
+	if err := s.Delete(id, r.Header.Get("If-Match")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) searchConstraint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, "POST")
+		return
+	}
+
+	b, ok := s.readBody(w, r, JSONContentType)
+	if !ok {
+		return
+	}
+
+	var constraint wotlib.ThingConstraint
+	if err := json.Unmarshal(b, &constraint); err != nil {
+		writeError(w, fmt.Errorf("%w: %v", ErrInvalidQuery, err))
+		return
+	}
+
+	writeResults(w, s.SearchConstraint(constraint), nil)
+}
+
+func (s *Server) searchJSONPath(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, "GET, HEAD")
+		return
+	}
+
+	results, err := s.SearchJSONPath(r.URL.Query().Get("query"))
+	writeResults(w, results, err)
+}
+
+func (s *Server) searchSPARQL(w http.ResponseWriter, r *http.Request) {
+	var query string
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		query = r.URL.Query().Get("query")
+	case http.MethodPost:
+		b, ok := s.readBody(w, r, SPARQLContentType)
+		if !ok {
+			return
+		}
+
+		query = string(b)
+	default:
+		writeMethodNotAllowed(w, "GET, HEAD, POST")
+		return
+	}
+
+	results, err := s.SearchSPARQL(query)
+	writeResults(w, results, err)
+}
+
+// requestBaseURL returns the configured base url or derives it from the request
+func (s *Server) requestBaseURL(r *http.Request) string {
+	if s.baseURL != nil {
+		return s.baseURL.String()
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + "/"
+}
+
+// thingsURL returns the absolute url of the things api
+func (s *Server) thingsURL(r *http.Request) *url.URL {
+	u, _ := url.Parse(s.requestBaseURL(r))
+	u.Path = strings.TrimSuffix(u.Path, "/") + ThingsPath
+
+	return u
+}
+
+// basePath returns the path the directory is served at without trailing slash
+func (s *Server) basePath() string {
+	if s.baseURL == nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(s.baseURL.Path, "/")
+}
+
+// readBody reads the body of a request with one of the given content types.
+// Bodies exceeding the maximum size are rejected
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request, contentTypes ...string) ([]byte, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	supported := false
+	for _, currContentType := range contentTypes {
+		if mediaType == currContentType || mediaType == JSONContentType && currContentType == TDContentType {
+			supported = true
+		}
+	}
+
+	if !supported {
+		writeProblem(w, http.StatusUnsupportedMediaType, fmt.Sprintf("content type has to be one of %s", strings.Join(contentTypes, ", ")))
+		return nil, false
+	}
+
+	tooLarge := fmt.Sprintf("body exceeds %d bytes", s.maxBodySize)
+	if r.ContentLength > s.maxBodySize {
+		writeProblem(w, http.StatusRequestEntityTooLarge, tooLarge)
+		return nil, false
+	}
+
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodySize))
+	if err != nil && int64(len(b)) >= s.maxBodySize {
+		writeProblem(w, http.StatusRequestEntityTooLarge, tooLarge)
+		return nil, false
+	}
+
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	return b, true
+}
+
+// queryInt parses a non negative integer query parameter
+func queryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	return i, nil
+}
+
+// writeResults writes search results or the error of the search
+func writeResults(w http.ResponseWriter, results []Result, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if results == nil {
+		results = []Result{}
+	}
+
+	b, err := json.Marshal(results)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", JSONContentType)
+	w.Write(b)
+}
+
+// writeError maps errors of the directory to problem responses
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidTD), errors.Is(err, ErrInvalidQuery):
+		writeProblem(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrPreconditionFailed):
+		writeProblem(w, http.StatusPreconditionFailed, err.Error())
+	default:
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeProblem(w, http.StatusMethodNotAllowed, "")
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	b, _ := json.Marshal(problem{Title: http.StatusText(status), Status: status, Detail: detail})
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	w.Write(b)
+}
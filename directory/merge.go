@@ -0,0 +1,32 @@
+package directory
+
+// mergePatch applies a json merge patch as defined by RFC 7396 to a document.
+// Null members of the patch remove members of the document, objects are
+// merged recursively and all other values replace the ones of the document
+func mergePatch(document interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	documentObject, ok := document.(map[string]interface{})
+	if !ok {
+		documentObject = map[string]interface{}{}
+	}
+
+	result := make(map[string]interface{}, len(documentObject))
+	for key, currValue := range documentObject {
+		result[key] = currValue
+	}
+
+	for key, currValue := range patchObject {
+		if currValue == nil {
+			delete(result, key)
+			continue
+		}
+
+		result[key] = mergePatch(result[key], currValue)
+	}
+
+	return result
+}
@@ -0,0 +1,232 @@
+package directory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/jsonpath"
+	"github.com/connctd/wotlib/internal/sparql"
+	"github.com/piprate/json-gold/ld"
+)
+
+// predicates linking things with their affordances
+var affordancePredicates = map[string]string{
+	wotlib.SchemaWoT.IRIPrefix("hasPropertyAffordance"): wotlib.ResultKindProperty,
+	wotlib.SchemaWoT.IRIPrefix("hasActionAffordance"):   wotlib.ResultKindAction,
+	wotlib.SchemaWoT.IRIPrefix("hasEventAffordance"):    wotlib.ResultKindEvent,
+}
+
+// members of compacted tds containing affordances
+var affordanceMembers = map[string]string{
+	"properties": wotlib.ResultKindProperty,
+	"actions":    wotlib.ResultKindAction,
+	"events":     wotlib.ResultKindEvent,
+}
+
+// Result is a thing or an affordance found by a search
+type Result struct {
+	wotlib.SearchResult
+	// Value is the value selected by a jsonpath query
+	Value interface{} `json:"value,omitempty"`
+	// Bindings are the selected variables of a sparql query
+	Bindings map[string]Binding `json:"bindings,omitempty"`
+}
+
+// Binding is an rdf term bound to a variable, formatted like in
+// the sparql json results format
+type Binding struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Datatype string `json:"datatype,omitempty"`
+	Language string `json:"xml:lang,omitempty"`
+}
+
+// SearchConstraint returns the things or affordances matching the constraint
+func (s *Server) SearchConstraint(constraint wotlib.ThingConstraint) []Result {
+	set := s.Things()
+
+	var results []Result
+	for _, currResult := range set.Search(constraint) {
+		results = append(results, Result{SearchResult: currResult})
+	}
+
+	return results
+}
+
+// SearchJSONPath evaluates a jsonpath expression against the compacted td of
+// each thing. Values within properties, actions or events are reported as
+// results for the affordance, all other values as results for the thing
+func (s *Server) SearchJSONPath(expression string) ([]Result, error) {
+	path, err := jsonpath.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+
+	things, _ := s.List(0, 0)
+
+	var results []Result
+	for _, currThing := range things {
+		var document interface{}
+		if err := json.Unmarshal(currThing.Document, &document); err != nil {
+			return nil, err
+		}
+
+		for _, currMatch := range path.Evaluate(document) {
+			result := Result{SearchResult: wotlib.SearchResult{ThingID: currThing.ID, Kind: wotlib.ResultKindThing}, Value: currMatch.Value}
+
+			if len(currMatch.Location) >= 2 {
+				member, _ := currMatch.Location[0].(string)
+				name, _ := currMatch.Location[1].(string)
+				if kind, ok := affordanceMembers[member]; ok && name != "" {
+					result.Kind = kind
+					result.Name = name
+				}
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// SearchSPARQL evaluates a sparql select query against the rdf form of each
+// thing. Solutions binding an affordance node to a selected variable are
+// reported as results for the first such affordance, all others as results
+// for the thing. LIMIT and OFFSET apply to the results of all things
+func (s *Server) SearchSPARQL(query string) ([]Result, error) {
+	q, err := sparql.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+
+	limit, offset := q.Limit, q.Offset
+	q.Limit, q.Offset = -1, 0
+
+	things, _ := s.List(0, 0)
+
+	var results []Result
+	for _, currThing := range things {
+		affordances := findAffordances(currThing.triples)
+
+		for _, currSolution := range q.Evaluate(currThing.triples) {
+			result := Result{
+				SearchResult: wotlib.SearchResult{ThingID: currThing.ID, Kind: wotlib.ResultKindThing},
+				Bindings:     map[string]Binding{},
+			}
+
+			for _, currVariable := range q.Variables {
+				term, ok := currSolution[currVariable]
+				if !ok {
+					continue
+				}
+
+				result.Bindings[currVariable] = newBinding(term)
+
+				if affordance, ok := affordances[term]; ok && result.Kind == wotlib.ResultKindThing {
+					result.Kind = affordance.Kind
+					result.Name = affordance.Name
+				}
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	if offset > len(results) {
+		offset = len(results)
+	}
+
+	results = results[offset:]
+	if limit >= 0 && limit < len(results) {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// findAffordances maps the nodes of the affordances of a thing to their kind and name
+func findAffordances(triples []sparql.Triple) map[sparql.Term]wotlib.SearchResult {
+	names := map[sparql.Term]string{}
+	for _, currTriple := range triples {
+		if currTriple.Predicate.Value == wotlib.SchemaWoT.IRIPrefix("name") {
+			names[currTriple.Subject] = currTriple.Object.Value
+		}
+	}
+
+	affordances := map[sparql.Term]wotlib.SearchResult{}
+	for _, currTriple := range triples {
+		if kind, ok := affordancePredicates[currTriple.Predicate.Value]; ok {
+			affordances[currTriple.Object] = wotlib.SearchResult{Kind: kind, Name: names[currTriple.Object]}
+		}
+	}
+
+	return affordances
+}
+
+// toTriples converts a compacted td into rdf triples
+func toTriples(document []byte) ([]sparql.Triple, error) {
+	var input interface{}
+	if err := json.Unmarshal(document, &input); err != nil {
+		return nil, err
+	}
+
+	rdf, err := ld.NewJsonLdProcessor().ToRDF(input, wotlib.DefaultJSONDLDOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset, ok := rdf.(*ld.RDFDataset)
+	if !ok {
+		return nil, fmt.Errorf("unexpected rdf result %T", rdf)
+	}
+
+	graphs := make([]string, 0, len(dataset.Graphs))
+	for name := range dataset.Graphs {
+		graphs = append(graphs, name)
+	}
+
+	sort.Strings(graphs)
+
+	var triples []sparql.Triple
+	for _, currGraph := range graphs {
+		for _, currQuad := range dataset.Graphs[currGraph] {
+			triples = append(triples, sparql.Triple{
+				Subject:   newTerm(currQuad.Subject),
+				Predicate: newTerm(currQuad.Predicate),
+				Object:    newTerm(currQuad.Object),
+			})
+		}
+	}
+
+	return triples, nil
+}
+
+func newTerm(node ld.Node) sparql.Term {
+	switch n := node.(type) {
+	case *ld.BlankNode:
+		return sparql.Term{Kind: sparql.BlankNode, Value: n.Attribute}
+	case *ld.Literal:
+		return sparql.Term{Kind: sparql.Literal, Value: n.Value, Datatype: n.Datatype, Language: n.Language}
+	}
+
+	return sparql.Term{Kind: sparql.IRI, Value: node.GetValue()}
+}
+
+func newBinding(term sparql.Term) Binding {
+	switch term.Kind {
+	case sparql.BlankNode:
+		return Binding{Type: "bnode", Value: term.Value}
+	case sparql.Literal:
+		b := Binding{Type: "literal", Value: term.Value, Language: term.Language}
+		if term.Language == "" && term.Datatype != sparql.XSDString {
+			b.Datatype = term.Datatype
+		}
+
+		return b
+	}
+
+	return Binding{Type: "uri", Value: term.Value}
+}
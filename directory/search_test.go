@@ -0,0 +1,146 @@
+package directory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/connctd/wotlib"
+)
+
+const testLamp = `{
+	"@context": ["https://www.w3.org/2019/wot/td/v1", {"iot": "http://iotschema.org/"}],
+	"@type": "iot:Light",
+	"id": "urn:dev:ops:lamp",
+	"title": "Lamp",
+	"securityDefinitions": {"nosec_sc": {"scheme": "nosec"}},
+	"security": ["nosec_sc"],
+	"properties": {
+		"on": {"@type": "iot:SwitchStatus", "type": "boolean", "forms": [{"href": "http://lamp.local/on"}]},
+		"brightness": {"type": "integer", "forms": [{"href": "http://lamp.local/brightness"}]}
+	},
+	"actions": {
+		"toggle": {"forms": [{"href": "http://lamp.local/toggle"}]}
+	}
+}`
+
+func newSearchServer(t *testing.T) (*Server, string) {
+	s, server := newTestServer(t)
+	t.Cleanup(server.Close)
+
+	expectStatus(t, doRequest(t, http.MethodPut, server.URL+ThingsPath+"/urn:dev:ops:lamp", TDContentType, testLamp), http.StatusCreated)
+	expectStatus(t, doRequest(t, http.MethodPut, server.URL+ThingsPath+"/urn:dev:ops:switch", TDContentType, testTD("urn:dev:ops:switch", "Switch")), http.StatusCreated)
+
+	return s, server.URL
+}
+
+func searchResults(t *testing.T, resp *http.Response) []Result {
+	var results []Result
+	if err := json.Unmarshal(expectStatus(t, resp, http.StatusOK), &results); err != nil {
+		t.Fatalf("Failed to decode results: %v", err)
+	}
+
+	return results
+}
+
+func reference(thingID string, kind string, name string) wotlib.SearchResult {
+	return wotlib.SearchResult{ThingID: thingID, Kind: kind, Name: name}
+}
+
+func references(results []Result) []wotlib.SearchResult {
+	var result []wotlib.SearchResult
+	for _, currResult := range results {
+		result = append(result, currResult.SearchResult)
+	}
+
+	return result
+}
+
+func TestSearchConstraint(t *testing.T) {
+	_, u := newSearchServer(t)
+
+	testCases := []struct {
+		constraint string
+		expected   []wotlib.SearchResult
+	}{
+		{`{"ID": "urn:dev:ops:switch"}`, []wotlib.SearchResult{reference("urn:dev:ops:switch", wotlib.ResultKindThing, "")}},
+		{`{}`, []wotlib.SearchResult{reference("urn:dev:ops:lamp", wotlib.ResultKindThing, ""), reference("urn:dev:ops:switch", wotlib.ResultKindThing, "")}},
+		{
+			`{"Type": ["http://iotschema.org/Light"], "PropertyConstraint": {"DataType": "` + wotlib.DataTypeBoolean + `"}}`,
+			[]wotlib.SearchResult{reference("urn:dev:ops:lamp", wotlib.ResultKindProperty, "on")},
+		},
+	}
+
+	for _, currTestCase := range testCases {
+		results := searchResults(t, doRequest(t, http.MethodPost, u+SearchConstraintPath, JSONContentType, currTestCase.constraint))
+		if found := references(results); !reflect.DeepEqual(found, currTestCase.expected) {
+			t.Fatalf("Expected %v for %s, got %v", currTestCase.expected, currTestCase.constraint, found)
+		}
+	}
+
+	expectStatus(t, doRequest(t, http.MethodPost, u+SearchConstraintPath, JSONContentType, `{"ID": 1}`), http.StatusBadRequest)
+}
+
+func TestSearchJSONPath(t *testing.T) {
+	_, u := newSearchServer(t)
+
+	results := searchResults(t, doRequest(t, http.MethodGet, u+SearchJSONPathPath+"?query="+url.QueryEscape("$.properties[?(@.type == 'boolean')]"), "", ""))
+
+	expected := []wotlib.SearchResult{
+		reference("urn:dev:ops:lamp", wotlib.ResultKindProperty, "on"),
+		reference("urn:dev:ops:switch", wotlib.ResultKindProperty, "on"),
+	}
+
+	if found := references(results); !reflect.DeepEqual(found, expected) {
+		t.Fatalf("Unexpected results %v", found)
+	}
+
+	results = searchResults(t, doRequest(t, http.MethodGet, u+SearchJSONPathPath+"?query="+url.QueryEscape("$.title"), "", ""))
+	if len(results) != 2 || results[0].Kind != wotlib.ResultKindThing || results[0].Value != "Lamp" {
+		t.Fatalf("Unexpected results %v", results)
+	}
+
+	expectStatus(t, doRequest(t, http.MethodGet, u+SearchJSONPathPath+"?query=title", "", ""), http.StatusBadRequest)
+}
+
+func TestSearchSPARQL(t *testing.T) {
+	_, u := newSearchServer(t)
+
+	query := `PREFIX td: <https://www.w3.org/2019/wot/td#>
+		PREFIX iot: <http://iotschema.org/>
+		SELECT ?property ?name WHERE {
+			?thing a iot:Light ;
+				td:hasPropertyAffordance ?property .
+			?property td:name ?name .
+		}`
+
+	results := searchResults(t, doRequest(t, http.MethodPost, u+SearchSPARQLPath, SPARQLContentType, query))
+
+	expected := []wotlib.SearchResult{
+		reference("urn:dev:ops:lamp", wotlib.ResultKindProperty, "brightness"),
+		reference("urn:dev:ops:lamp", wotlib.ResultKindProperty, "on"),
+	}
+
+	found := references(results)
+	if len(found) == 2 && found[0].Name == "on" {
+		found[0], found[1] = found[1], found[0]
+	}
+
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("Unexpected results %v", found)
+	}
+
+	if binding := results[0].Bindings["name"]; binding.Type != "literal" || binding.Datatype != "" {
+		t.Fatalf("Unexpected binding %+v", binding)
+	}
+
+	query = `PREFIX td: <https://www.w3.org/2019/wot/td#> SELECT ?thing WHERE { ?thing td:title ?title } LIMIT 1`
+	results = searchResults(t, doRequest(t, http.MethodGet, u+SearchSPARQLPath+"?query="+url.QueryEscape(query), "", ""))
+	if len(results) != 1 || results[0].Kind != wotlib.ResultKindThing || results[0].Bindings["thing"].Value != "urn:dev:ops:lamp" {
+		t.Fatalf("Unexpected results %v", results)
+	}
+
+	expectStatus(t, doRequest(t, http.MethodGet, u+SearchSPARQLPath+"?query=SELECT", "", ""), http.StatusBadRequest)
+}
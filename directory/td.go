@@ -0,0 +1,182 @@
+package directory
+
+import (
+	"encoding/json"
+
+	"github.com/connctd/wotlib"
+)
+
+// TypeThingDirectory is the semantic type of thing description directories
+const TypeThingDirectory = "https://www.w3.org/2021/wot/discovery#ThingDirectory"
+
+// Document returns the thing description of the directory as served
+// for the given base url
+func (s *Server) Document(baseURL string) ([]byte, error) {
+	idVariable := map[string]interface{}{
+		"id": map[string]interface{}{"type": "string", "format": "iri-reference"},
+	}
+
+	queryVariable := map[string]interface{}{
+		"query": map[string]interface{}{"type": "string"},
+	}
+
+	thingSchema := map[string]interface{}{"type": "object"}
+
+	resultsSchema := map[string]interface{}{
+		"type": "array",
+		"items": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"thingId":  map[string]interface{}{"type": "string"},
+				"kind":     map[string]interface{}{"type": "string", "enum": []interface{}{"thing", "property", "action", "event"}},
+				"name":     map[string]interface{}{"type": "string"},
+				"value":    map[string]interface{}{},
+				"bindings": map[string]interface{}{"type": "object"},
+			},
+		},
+	}
+
+	document := map[string]interface{}{
+		"@context":            []interface{}{wotlib.TDContextURL, map[string]interface{}{"discovery": "https://www.w3.org/2021/wot/discovery#"}},
+		"@type":               "discovery:ThingDirectory",
+		"title":               "Thing Description Directory",
+		"base":                baseURL,
+		"securityDefinitions": map[string]interface{}{"nosec_sc": map[string]interface{}{"scheme": "nosec"}},
+		"security":            []interface{}{"nosec_sc"},
+		"properties": map[string]interface{}{
+			"things": map[string]interface{}{
+				"description": "Retrieve all thing descriptions, paginated with offset and limit",
+				"type":        "array",
+				"items":       thingSchema,
+				"readOnly":    true,
+				"uriVariables": map[string]interface{}{
+					"offset": map[string]interface{}{"type": "integer", "minimum": 0},
+					"limit":  map[string]interface{}{"type": "integer", "minimum": 1},
+				},
+				"forms": []interface{}{
+					map[string]interface{}{
+						"href":                "things{?offset,limit}",
+						"op":                  "readproperty",
+						"htv:methodName":      "GET",
+						"contentType":         LDContentType,
+						"additionalResponses": problemResponses(),
+						"response":            map[string]interface{}{"contentType": LDContentType},
+					},
+				},
+			},
+		},
+		"events": map[string]interface{}{
+			EventThingCreated: lifecycleEvent("Notifies about registered things, with diff the data is the created td", EventThingCreated),
+			EventThingUpdated: lifecycleEvent("Notifies about updated things, with diff the data is the merge patch of the update", EventThingUpdated),
+			EventThingDeleted: lifecycleEvent("Notifies about deleted things", EventThingDeleted),
+		},
+		"actions": map[string]interface{}{
+			"createAnonymousThing": map[string]interface{}{
+				"description": "Register an anonymous thing description, the generated id is returned in the location header",
+				"input":       thingSchema,
+				"forms":       []interface{}{actionForm("things", "POST", TDContentType)},
+			},
+			"retrieveThing": map[string]interface{}{
+				"description":  "Retrieve a thing description",
+				"output":       thingSchema,
+				"safe":         true,
+				"idempotent":   true,
+				"uriVariables": idVariable,
+				"forms":        []interface{}{actionForm("things/{id}", "GET", TDContentType)},
+			},
+			"createThing": map[string]interface{}{
+				"description":  "Register or replace a thing description",
+				"input":        thingSchema,
+				"idempotent":   true,
+				"uriVariables": idVariable,
+				"forms":        []interface{}{actionForm("things/{id}", "PUT", TDContentType)},
+			},
+			"updatePartialThing": map[string]interface{}{
+				"description":  "Update a thing description with a json merge patch",
+				"input":        thingSchema,
+				"uriVariables": idVariable,
+				"forms":        []interface{}{actionForm("things/{id}", "PATCH", MergePatchContentType)},
+			},
+			"deleteThing": map[string]interface{}{
+				"description":  "Delete a thing description",
+				"idempotent":   true,
+				"uriVariables": idVariable,
+				"forms":        []interface{}{actionForm("things/{id}", "DELETE", "")},
+			},
+			"searchConstraint": map[string]interface{}{
+				"description": "Search things and affordances matching a serialized thing constraint",
+				"input":       map[string]interface{}{"type": "object"},
+				"output":      resultsSchema,
+				"safe":        true,
+				"idempotent":  true,
+				"forms":       []interface{}{actionForm("search/constraint", "POST", JSONContentType)},
+			},
+			"searchJSONPath": map[string]interface{}{
+				"description":  "Search things and affordances with a jsonpath expression over the compacted tds",
+				"output":       resultsSchema,
+				"safe":         true,
+				"idempotent":   true,
+				"uriVariables": queryVariable,
+				"forms":        []interface{}{actionForm("search/jsonpath{?query}", "GET", JSONContentType)},
+			},
+			"searchSPARQL": map[string]interface{}{
+				"description":  "Search things and affordances with a sparql select query over the rdf form of the tds",
+				"output":       resultsSchema,
+				"safe":         true,
+				"idempotent":   true,
+				"uriVariables": queryVariable,
+				"forms": []interface{}{
+					actionForm("search/sparql{?query}", "GET", JSONContentType),
+					actionForm("search/sparql", "POST", SPARQLContentType),
+				},
+			},
+		},
+	}
+
+	return json.Marshal(document)
+}
+
+// lifecycleEvent describes a stream of lifecycle events of the given type
+func lifecycleEvent(description string, typ string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"data": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"id": map[string]interface{}{"type": "string"}},
+		},
+		"uriVariables": map[string]interface{}{
+			"diff": map[string]interface{}{"type": "boolean"},
+		},
+		"forms": []interface{}{
+			map[string]interface{}{
+				"href":        "events/" + typ + "{?diff}",
+				"op":          "subscribeevent",
+				"subprotocol": "sse",
+				"contentType": "text/event-stream",
+			},
+		},
+	}
+}
+
+// actionForm creates the form of a directory action
+func actionForm(href string, method string, contentType string) map[string]interface{} {
+	form := map[string]interface{}{
+		"href":                href,
+		"op":                  "invokeaction",
+		"htv:methodName":      method,
+		"additionalResponses": problemResponses(),
+	}
+
+	if contentType != "" {
+		form["contentType"] = contentType
+	}
+
+	return form
+}
+
+// problemResponses describes the error responses of the directory
+func problemResponses() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"success": false, "contentType": ProblemContentType},
+	}
+}
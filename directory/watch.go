@@ -0,0 +1,234 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/retry"
+	"github.com/connctd/wotlib/internal/sse"
+)
+
+// defaultRetryDelay is the delay before reconnecting to an event stream
+const defaultRetryDelay = time.Second
+
+// Event is a lifecycle change of a thing received from a directory
+type Event struct {
+	// ID of the event, used to resume the stream
+	ID string
+	// Type is one of EventThingCreated, EventThingUpdated, EventThingDeleted
+	// or EventReset
+	Type    string
+	ThingID string
+	// Thing is the current td of created and updated things
+	Thing Thing
+	// Err is set if the stream was interrupted
+	Err error
+}
+
+// Apply applies the change to a set. A reset can't be applied, the things
+// have to be listed again instead
+func (e Event) Apply(set wotlib.ExpandedThingDescriptionSet) {
+	switch e.Type {
+	case EventThingCreated, EventThingUpdated:
+		set.Append(e.Thing.TD)
+	case EventThingDeleted:
+		set.Remove(e.ThingID)
+	}
+}
+
+// WithRetryDelay sets the delay before reconnecting to interrupted event streams
+func WithRetryDelay(delay time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retryDelay = delay
+	}
+}
+
+// Events subscribes to the lifecycle events of the directory. The tds of
+// created and updated things are fetched before the event is delivered.
+// Interrupted streams are resumed with the id of the last event received,
+// the interruption is reported as event with an error. If events were lost
+// in the meantime an EventReset is delivered. The channel is
+// closed when the context is done or the stream can't be resumed
+func (c *Client) Events(ctx context.Context) (<-chan Event, error) {
+	body, err := c.openEvents(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		reader := sse.NewReader(body)
+		delay := c.retryDelay
+
+		for {
+			e, err := reader.Next()
+			if err == nil {
+				if e.Retry > 0 {
+					delay = e.Retry
+				}
+
+				event, ok := c.resolveEvent(ctx, e)
+				if ok && !sendEvent(ctx, events, event) {
+					body.Close()
+					return
+				}
+
+				continue
+			}
+
+			body.Close()
+
+			if ctx.Err() != nil || !sendEvent(ctx, events, Event{Err: err}) {
+				return
+			}
+
+			// reconnect until the stream is available again, backing off
+			// while reconnecting fails
+			reconnectDelay := delay
+			for {
+				if !retry.Sleep(ctx, reconnectDelay) {
+					return
+				}
+
+				body, err = c.openEvents(ctx, reader.LastEventID())
+				if err == nil {
+					break
+				}
+
+				if ctx.Err() != nil || !sendEvent(ctx, events, Event{Err: err}) || !retry.Temporary(err) {
+					return
+				}
+
+				reconnectDelay = retry.Backoff(reconnectDelay)
+			}
+
+			reader = sse.NewReaderWithLastEventID(body, reader.LastEventID())
+		}
+	}()
+
+	return events, nil
+}
+
+// Watch populates the set and applies all lifecycle events to it until the
+// context is done. If events were lost the set is made to mirror the
+// directory again. The locker guards the set against concurrent access
+func (c *Client) Watch(ctx context.Context, set wotlib.ExpandedThingDescriptionSet, locker sync.Locker) error {
+	// subscribing first ensures no change gets lost between listing and streaming
+	events, err := c.Events(ctx)
+	if err != nil {
+		return err
+	}
+
+	things, err := c.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	locker.Lock()
+	for _, currThing := range things {
+		set.Append(currThing.TD)
+	}
+	locker.Unlock()
+
+	var lastErr error
+	for currEvent := range events {
+		if currEvent.Err != nil {
+			lastErr = currEvent.Err
+			continue
+		}
+
+		if currEvent.Type == EventReset {
+			things, err := c.List(ctx)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			locker.Lock()
+			mirror(set, things)
+			locker.Unlock()
+
+			continue
+		}
+
+		locker.Lock()
+		currEvent.Apply(set)
+		locker.Unlock()
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return lastErr
+}
+
+// resolveEvent decodes an event and fetches the td of created and updated
+// things. Events of things deleted in the meantime are skipped
+func (c *Client) resolveEvent(ctx context.Context, e sse.Event) (Event, bool) {
+	var data struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+		return Event{ID: e.ID, Err: err}, true
+	}
+
+	event := Event{ID: e.ID, Type: e.Type, ThingID: data.ID}
+	if e.Type == EventReset {
+		return event, true
+	}
+
+	if e.Type == EventThingDeleted {
+		c.forget(data.ID)
+		return event, true
+	}
+
+	thing, err := c.Get(ctx, data.ID)
+	if errors.Is(err, ErrNotFound) {
+		return Event{}, false
+	}
+
+	event.Thing = thing
+	event.Err = err
+
+	return event, true
+}
+
+func (c *Client) openEvents(ctx context.Context, lastEventID string) (io.ReadCloser, error) {
+	header := http.Header{}
+	header.Set("Accept", sse.ContentType)
+	header.Set("Cache-Control", "no-cache")
+	if lastEventID != "" {
+		header.Set("Last-Event-ID", lastEventID)
+	}
+
+	u := c.baseURL.ResolveReference(&url.URL{Path: strings.TrimPrefix(EventsPath, "/")})
+
+	resp, err := c.do(ctx, http.MethodGet, u.String(), "", nil, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func sendEvent(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -1,5 +1,9 @@
 package wotlib
 
+import (
+	"sort"
+)
+
 // contains function for evaluation based on expanded thing descriptions
 
 // GetPropertyAffordances searches within a set for all property affordances where constraints match
@@ -44,6 +48,61 @@ func (s *ExpandedThingDescriptionSet) GetActionAffordances(constraint ThingConst
 	return result
 }
 
+// kinds of search results
+const (
+	ResultKindThing    = "thing"
+	ResultKindProperty = "property"
+	ResultKindAction   = "action"
+	ResultKindEvent    = "event"
+)
+
+// SearchResult references a thing or one of its affordances
+type SearchResult struct {
+	ThingID string `json:"thingId"`
+	Kind    string `json:"kind"`
+	// Name of the affordance, empty for things
+	Name string `json:"name,omitempty"`
+}
+
+// Search returns references to all things matching the constraint, ordered by id.
+// If the constraint contains a property or action constraint the matching
+// affordances of these things are returned instead
+func (s *ExpandedThingDescriptionSet) Search(constraint ThingConstraint) []SearchResult {
+	ids := make([]string, 0, len(*s))
+	for id := range *s {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	var result []SearchResult
+	for _, currID := range ids {
+		currTD := (*s)[currID]
+		if !currTD.Fulfills(constraint) {
+			continue
+		}
+
+		if constraint.PropertyConstraint == nil && constraint.ActionConstraint == nil {
+			result = append(result, SearchResult{ThingID: currTD.ID, Kind: ResultKindThing})
+			continue
+		}
+
+		if constraint.PropertyConstraint != nil {
+			for _, currProperty := range currTD.GetPropertyAffordances(*constraint.PropertyConstraint) {
+				result = append(result, SearchResult{ThingID: currTD.ID, Kind: ResultKindProperty, Name: currProperty.Name.Value()})
+			}
+		}
+
+		if constraint.ActionConstraint != nil {
+			for _, currAction := range currTD.GetActionAffordances(*constraint.ActionConstraint) {
+				result = append(result, SearchResult{ThingID: currTD.ID, Kind: ResultKindAction, Name: currAction.Name.Value()})
+			}
+		}
+	}
+
+	return result
+}
+
 // GetPropertyAffordances searches for a property affordance with specific criteria
 func (t *ExpandedThingDescription) GetPropertyAffordances(constraint PropertyConstraint) []ExpandedPropertyAffordance {
 	var result []ExpandedPropertyAffordance
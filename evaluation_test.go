@@ -1,6 +1,7 @@
 package wotlib
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -240,6 +241,45 @@ func TestFindActionAffordancesInSet(t *testing.T) {
 	}
 }
 
+func TestSearch(t *testing.T) {
+	expandedTD, err := FromBytes(testTDOne)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	set := NewExpandedThingDescriptionSet(expandedTD)
+
+	testCases := []struct {
+		constraint ThingConstraint
+		expected   []SearchResult
+	}{
+		{
+			ThingConstraint{Name: asStringPointer("LightOne")},
+			[]SearchResult{{ThingID: expandedTD.ID, Kind: ResultKindThing}},
+		},
+		{
+			ThingConstraint{Name: asStringPointer("LightTwo")},
+			nil,
+		},
+		{
+			ThingConstraint{
+				PropertyConstraint: &PropertyConstraint{Type: &[]string{iotSchema.IRIPrefix("SwitchStatus")}},
+				ActionConstraint:   &ActionConstraint{Type: &[]string{iotSchema.IRIPrefix("TurnOn")}},
+			},
+			[]SearchResult{
+				{ThingID: expandedTD.ID, Kind: ResultKindProperty, Name: "lamp-on"},
+				{ThingID: expandedTD.ID, Kind: ResultKindAction, Name: "lamp-setOn"},
+			},
+		},
+	}
+
+	for _, currTestCase := range testCases {
+		if result := set.Search(currTestCase.constraint); !reflect.DeepEqual(result, currTestCase.expected) {
+			t.Fatalf("Expected %v, got %v", currTestCase.expected, result)
+		}
+	}
+}
+
 func asStringPointer(input string) *string {
 	return &input
 }
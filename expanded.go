@@ -1,5 +1,46 @@
 package wotlib
 
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrConflict is returned if a td with the same id is already in a set
+var ErrConflict = errors.New("thing description already exists")
+
+// ConflictPolicy decides how a set handles a td with an id which is already in the set
+type ConflictPolicy int
+
+// conflict policies of a set
+const (
+	// ConflictReplace replaces the td in the set
+	ConflictReplace ConflictPolicy = iota
+	// ConflictReject keeps the td in the set and fails with ErrConflict
+	ConflictReject
+	// ConflictKeepNewest keeps the td which was modified last. Tds without
+	// modification date are older than all others, on equal dates the
+	// appended td replaces the one in the set
+	ConflictKeepNewest
+)
+
+// AppendResult tells what appending a td did to a set
+type AppendResult int
+
+// results of appending a td to a set
+const (
+	// AppendInserted means the id wasn't in the set before
+	AppendInserted AppendResult = iota + 1
+	// AppendReplaced means the td replaced one with the same id
+	AppendReplaced
+	// AppendSkipped means the td in the set was kept
+	AppendSkipped
+	// AppendUnchanged means the td in the set has the same content
+	AppendUnchanged
+)
+
 // ExpandedThingDescriptionSet set of thing descriptions with some convenience functions
 type ExpandedThingDescriptionSet map[string]ExpandedThingDescription
 
@@ -14,9 +55,52 @@ func NewExpandedThingDescriptionSet(tds ...ExpandedThingDescription) ExpandedThi
 	return s
 }
 
-// Append appends an expanded td to the list
-func (s *ExpandedThingDescriptionSet) Append(td ExpandedThingDescription) {
+// Append appends an expanded td to the list, replacing a td with the same id.
+// Anonymous tds are stored under the id returned by GenerateID and skipped if
+// it fails
+func (s *ExpandedThingDescriptionSet) Append(td ExpandedThingDescription) AppendResult {
+	result, _ := s.AppendWithPolicy(td, ConflictReplace)
+	return result
+}
+
+// AppendWithPolicy appends an expanded td to the list and resolves conflicts
+// with a td of the same id by the policy. Tds equal to the one in the set
+// are no conflict. Tds returned by FromBytes are compared by the hash of their
+// document, which is computed once, as long as they aren't modified. Anonymous
+// tds are stored under the id returned by GenerateID
+func (s *ExpandedThingDescriptionSet) AppendWithPolicy(td ExpandedThingDescription, policy ConflictPolicy) (AppendResult, error) {
+	if td.IsAnonymous() {
+		id, err := GenerateID(td)
+		if err != nil {
+			return AppendSkipped, err
+		}
+
+		td.ID = id
+	}
+
+	existing, exists := (*s)[td.ID]
+	if !exists {
+		(*s)[td.ID] = td
+		return AppendInserted, nil
+	}
+
+	if equalContent(existing, td) {
+		return AppendUnchanged, nil
+	}
+
+	switch policy {
+	case ConflictReject:
+		return AppendSkipped, fmt.Errorf("%w: %s", ErrConflict, td.ID)
+	case ConflictKeepNewest:
+		if existingModified, ok := existing.ModifiedTime(); ok {
+			if modified, ok := td.ModifiedTime(); !ok || modified.Before(existingModified) {
+				return AppendSkipped, nil
+			}
+		}
+	}
+
 	(*s)[td.ID] = td
+	return AppendReplaced, nil
 }
 
 // Remove removes a td from the list
@@ -29,14 +113,80 @@ func (s *ExpandedThingDescriptionSet) Get(id string) ExpandedThingDescription {
 	return (*s)[id]
 }
 
+// GenerateID derives the id of an anonymous td from the hash of the document
+// it was expanded from or, without document, from the hash of its canonical
+// form. Equal tds get the same id, which is a name based uuid urn
+func GenerateID(td ExpandedThingDescription) (string, error) {
+	td.ID = ""
+
+	hash, _, err := td.contentHash()
+	if err != nil {
+		return "", err
+	}
+
+	sum, err := hex.DecodeString(hash)
+	if err != nil {
+		return "", err
+	}
+
+	// version 8, variant 10
+	sum[6] = sum[6]&0x0f | 0x80
+	sum[8] = sum[8]&0x3f | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]), nil
+}
+
+// equalContent checks if two tds have the same content hash. Tds expanded
+// from a document are only equal to tds expanded from an equal document
+func equalContent(a ExpandedThingDescription, b ExpandedThingDescription) bool {
+	hashA, fromSourceA, err := a.contentHash()
+	if err != nil {
+		return false
+	}
+
+	hashB, fromSourceB, err := b.contentHash()
+
+	return err == nil && fromSourceA == fromSourceB && hashA == hashB
+}
+
+// IsAnonymous checks if the td has no id. Anonymous tds have an empty id
+// or a blank node identifier after expansion
+func (t ExpandedThingDescription) IsAnonymous() bool {
+	return t.ID == "" || strings.HasPrefix(t.ID, "_:")
+}
+
+// ModifiedTime returns the time the td was modified last and whether it is known
+func (t ExpandedThingDescription) ModifiedTime() (time.Time, bool) {
+	if t.Modified.Value() == "" {
+		return time.Time{}, false
+	}
+
+	modified, err := time.Parse(time.RFC3339, t.Modified.Value())
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return modified, true
+}
+
 // ExpandedThingDescription reflects a thing description in its expanded format
 // Note: currently this lib only supports a small sub set of fields
 type ExpandedThingDescription struct {
 	ID         string                       `json:"@id"`
 	Type       []string                     `json:"@type,omitempty"`
 	Name       StringNode                   `json:"https://www.w3.org/2019/wot/td#name"`
+	Title      StringNode                   `json:"https://www.w3.org/2019/wot/td#title"`
+	Base       IDNode                       `json:"https://www.w3.org/2019/wot/td#baseURI"`
+	Modified   StringNode                   `json:"http://purl.org/dc/terms/modified"`
 	Actions    []ExpandedActionAffordance   `json:"https://www.w3.org/2019/wot/td#hasActionAffordance"`
 	Properties []ExpandedPropertyAffordance `json:"https://www.w3.org/2019/wot/td#hasPropertyAffordance"`
+	Events     []ExpandedEventAffordance    `json:"https://www.w3.org/2019/wot/td#hasEventAffordance"`
+
+	Security            IDNode                   `json:"https://www.w3.org/2019/wot/td#hasSecurityConfiguration"`
+	SecurityDefinitions []ExpandedSecurityScheme `json:"https://www.w3.org/2019/wot/td#securityDefinitions"`
+
+	// source is the document the td was expanded from, if known
+	source *source
 }
 
 // ExpandedActionAffordance defines an expanded action affordance within a td
@@ -45,18 +195,29 @@ type ExpandedActionAffordance struct {
 	Type         []string               `json:"@type,omitempty"`
 	Form         ExpandedFormNode       `json:"https://www.w3.org/2019/wot/td#hasForm"`
 	Input        ExpandedDataSchemaNode `json:"https://www.w3.org/2019/wot/td#hasInputSchema"`
+	Output       ExpandedDataSchemaNode `json:"https://www.w3.org/2019/wot/td#hasOutputSchema"`
 	IsIdempotent BooleanNode            `json:"https://www.w3.org/2019/wot/td#isIdempotent"`
 	IsSafe       BooleanNode            `json:"https://www.w3.org/2019/wot/td#isSafe"`
 }
 
-// ExpandedPropertyAffordance defines an expanded property affordance within a td
+// ExpandedPropertyAffordance defines an expanded property affordance within a td.
+// A property affordance is a data schema itself
 type ExpandedPropertyAffordance struct {
+	Name         StringNode       `json:"https://www.w3.org/2019/wot/td#name"`
+	Type         []string         `json:"@type,omitempty"`
+	Form         ExpandedFormNode `json:"https://www.w3.org/2019/wot/td#hasForm"`
+	IsObservable BooleanNode      `json:"https://www.w3.org/2019/wot/td#isObservable"`
+	ExpandedDataSchema
+}
+
+// ExpandedEventAffordance defines an expanded event affordance within a td
+type ExpandedEventAffordance struct {
 	Name         StringNode             `json:"https://www.w3.org/2019/wot/td#name"`
 	Type         []string               `json:"@type,omitempty"`
-	DataType     IDNode                 `json:"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"`
-	Properties   []ExpandedDataProperty `json:"https://www.w3.org/2019/wot/json-schema#properties"`
 	Form         ExpandedFormNode       `json:"https://www.w3.org/2019/wot/td#hasForm"`
-	IsObservable BooleanNode            `json:"https://www.w3.org/2019/wot/td#isObservable"`
+	Data         ExpandedDataSchemaNode `json:"https://www.w3.org/2019/wot/td#hasNotificationSchema"`
+	Subscription ExpandedDataSchemaNode `json:"https://www.w3.org/2019/wot/td#hasSubscriptionSchema"`
+	Cancellation ExpandedDataSchemaNode `json:"https://www.w3.org/2019/wot/td#hasCancellationSchema"`
 }
 
 // ExpandedDataSchemaNode is an array of expanded data schema
@@ -77,13 +238,36 @@ func (s ExpandedDataSchemaNode) Value() ExpandedDataSchema {
 type ExpandedDataSchema struct {
 	DataType   IDNode                 `json:"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"`
 	Properties []ExpandedDataProperty `json:"https://www.w3.org/2019/wot/json-schema#properties"`
+	Items      ExpandedDataSchemaNode `json:"https://www.w3.org/2019/wot/json-schema#items"`
+	Required   StringNode             `json:"https://www.w3.org/2019/wot/json-schema#required"`
+	Enum       LiteralNode            `json:"https://www.w3.org/2019/wot/json-schema#enum"`
+	Const      LiteralNode            `json:"https://www.w3.org/2019/wot/json-schema#const"`
+	Minimum    NumberNode             `json:"https://www.w3.org/2019/wot/json-schema#minimum"`
+	Maximum    NumberNode             `json:"https://www.w3.org/2019/wot/json-schema#maximum"`
+	MinLength  NumberNode             `json:"https://www.w3.org/2019/wot/json-schema#minLength"`
+	MaxLength  NumberNode             `json:"https://www.w3.org/2019/wot/json-schema#maxLength"`
+	MinItems   NumberNode             `json:"https://www.w3.org/2019/wot/json-schema#minItems"`
+	MaxItems   NumberNode             `json:"https://www.w3.org/2019/wot/json-schema#maxItems"`
+	ReadOnly   BooleanNode            `json:"https://www.w3.org/2019/wot/json-schema#readOnly"`
+	WriteOnly  BooleanNode            `json:"https://www.w3.org/2019/wot/json-schema#writeOnly"`
 }
 
+// well known data types of data schemas
+var (
+	DataTypeObject  = SchemaJSON.IRIPrefix("ObjectSchema")
+	DataTypeArray   = SchemaJSON.IRIPrefix("ArraySchema")
+	DataTypeString  = SchemaJSON.IRIPrefix("StringSchema")
+	DataTypeNumber  = SchemaJSON.IRIPrefix("NumberSchema")
+	DataTypeInteger = SchemaJSON.IRIPrefix("IntegerSchema")
+	DataTypeBoolean = SchemaJSON.IRIPrefix("BooleanSchema")
+	DataTypeNull    = SchemaJSON.IRIPrefix("NullSchema")
+)
+
 // ExpandedDataProperty is part of a data schema
 type ExpandedDataProperty struct {
-	Name     StringNode `json:"https://www.w3.org/2019/wot/json-schema#propertyName"`
-	Type     []string   `json:"@type,omitempty"`
-	DataType IDNode     `json:"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"`
+	Name StringNode `json:"https://www.w3.org/2019/wot/json-schema#propertyName"`
+	Type []string   `json:"@type,omitempty"`
+	ExpandedDataSchema
 }
 
 // ExpandedFormNode is an array of expanded forms
@@ -104,6 +288,73 @@ type ExpandedForm struct {
 	ContentType StringNode `json:"https://www.w3.org/2019/wot/hypermedia#forContentType"`
 	Op          IDNode     `json:"https://www.w3.org/2019/wot/hypermedia#hasOperationType"`
 	Href        IDNode     `json:"https://www.w3.org/2019/wot/hypermedia#hasTarget"`
+	Subprotocol StringNode `json:"https://www.w3.org/2019/wot/hypermedia#forSubProtocol"`
+	Method      StringNode `json:"http://www.w3.org/2011/http#methodName"`
+	Security    IDNode     `json:"https://www.w3.org/2019/wot/td#hasSecurityConfiguration"`
+	Scopes      StringNode `json:"https://www.w3.org/2019/wot/security#scopes"`
+
+	// EffectiveSecurity contains the security schemes which apply to the form.
+	// It is resolved by ResolveSecurity and not part of the td itself
+	EffectiveSecurity []ExpandedSecurityScheme `json:"-"`
+
+	// vocabulary of the mqtt protocol binding
+	ControlPacket TermNode    `json:"http://www.example.org/mqtt-binding#controlPacket"`
+	QoS           LiteralNode `json:"http://www.example.org/mqtt-binding#qos"`
+	Retain        BooleanNode `json:"http://www.example.org/mqtt-binding#retain"`
+	Topic         StringNode  `json:"http://www.example.org/mqtt-binding#topic"`
+	Filter        StringNode  `json:"http://www.example.org/mqtt-binding#filter"`
+
+	// vocabulary of the coap protocol binding
+	CoAPMethod StringNode `json:"http://www.example.org/coap-binding#method"`
+}
+
+// well known operation types of forms
+var (
+	OpReadProperty     = SchemaWoT.IRIPrefix("readProperty")
+	OpWriteProperty    = SchemaWoT.IRIPrefix("writeProperty")
+	OpObserveProperty  = SchemaWoT.IRIPrefix("observeProperty")
+	OpInvokeAction     = SchemaWoT.IRIPrefix("invokeAction")
+	OpSubscribeEvent   = SchemaWoT.IRIPrefix("subscribeEvent")
+	OpUnsubscribeEvent = SchemaWoT.IRIPrefix("unsubscribeEvent")
+)
+
+// HasOp checks if the form supports the given operation type. Forms without
+// any operation type are treated as supporting every operation
+func (f ExpandedForm) HasOp(op string) bool {
+	if len(f.Op) == 0 {
+		return true
+	}
+
+	for _, currOp := range f.Op {
+		if currOp.ID == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// control packets of the mqtt protocol binding
+var (
+	MQTTPublish     = SchemaMQTT.IRIPrefix("publish")
+	MQTTSubscribe   = SchemaMQTT.IRIPrefix("subscribe")
+	MQTTUnsubscribe = SchemaMQTT.IRIPrefix("unsubscribe")
+)
+
+// subprotocols of the coap protocol binding
+var (
+	CoAPObserve = SchemaCoAP.IRIPrefix("observe")
+)
+
+// Find returns the first form supporting the given operation type
+func (s ExpandedFormNode) Find(op string) (ExpandedForm, bool) {
+	for _, currForm := range s {
+		if currForm.HasOp(op) {
+			return currForm, true
+		}
+	}
+
+	return ExpandedForm{}, false
 }
 
 // StringNode defines an array of string values
@@ -119,6 +370,16 @@ func (s StringNode) Value() string {
 	return s[0].Value
 }
 
+// Values returns all elements inside the string node
+func (s StringNode) Values() []string {
+	result := make([]string, len(s))
+	for i := range s {
+		result[i] = s[i].Value
+	}
+
+	return result
+}
+
 // BooleanNode defines an array of boolean values
 type BooleanNode []BooleanValue
 
@@ -145,6 +406,50 @@ func (s IDNode) Value() string {
 	return s[0].ID
 }
 
+// NumberNode defines an array of number values
+type NumberNode []NumberValue
+
+// Value returns the first element inside the number node
+// and whether such an element exists
+func (n NumberNode) Value() (float64, bool) {
+	if len(n) == 0 {
+		return 0, false
+	}
+
+	return n[0].Value, true
+}
+
+// LiteralNode defines an array of literal values of arbitrary json type
+type LiteralNode []LiteralValue
+
+// Values returns all literals inside the node
+func (l LiteralNode) Values() []interface{} {
+	result := make([]interface{}, len(l))
+	for i := range l {
+		result[i] = l[i].Value
+	}
+
+	return result
+}
+
+// TermNode defines an array of vocabulary terms. Terms may be given as id or,
+// if they are not known to the context of a td, as plain string
+type TermNode []TermValue
+
+// Value returns the first term inside the node as expanded iri
+// or an empty string if no such element exists
+func (t TermNode) Value() string {
+	if len(t) == 0 {
+		return ""
+	}
+
+	if t[0].ID != "" {
+		return ExpandIRI(t[0].ID)
+	}
+
+	return ExpandIRI(t[0].Value)
+}
+
 // StringValue describes a string value
 type StringValue struct {
 	Value string `json:"@value"`
@@ -155,6 +460,22 @@ type BooleanValue struct {
 	Value bool `json:"@value"`
 }
 
+// NumberValue describes a number value
+type NumberValue struct {
+	Value float64 `json:"@value"`
+}
+
+// LiteralValue describes a value of arbitrary json type
+type LiteralValue struct {
+	Value interface{} `json:"@value"`
+}
+
+// TermValue describes a term which is either an id or a string value
+type TermValue struct {
+	ID    string `json:"@id,omitempty"`
+	Value string `json:"@value,omitempty"`
+}
+
 // IDValue describes an id value
 type IDValue struct {
 	ID string `json:"@id"`
@@ -1,6 +1,8 @@
 package wotlib
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -30,3 +32,155 @@ func TestTDSet(t *testing.T) {
 		t.Fatalf("Set should be empty")
 	}
 }
+
+func anonymousTD(t *testing.T, title string, modified string) ExpandedThingDescription {
+	td := `{"@context": "https://www.w3.org/2019/wot/td/v1", "title": "` + title + `"`
+	if modified != "" {
+		td += `, "modified": "` + modified + `"`
+	}
+
+	expanded, err := FromBytes([]byte(td + "}"))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	return expanded
+}
+
+func TestTDSetAnonymous(t *testing.T) {
+	lamp := anonymousTD(t, "Lamp", "")
+	if !lamp.IsAnonymous() {
+		t.Fatalf("Expected td without id to be anonymous, got %q", lamp.ID)
+	}
+
+	id, err := GenerateID(lamp)
+	if err != nil {
+		t.Fatalf("Failed to generate id: %v", err)
+	}
+
+	again, err := GenerateID(anonymousTD(t, "Lamp", ""))
+	if !strings.HasPrefix(id, "urn:uuid:") || id != again || err != nil {
+		t.Fatalf("Expected deterministic uuid urn, got %s and %s (%v)", id, again, err)
+	}
+
+	set := NewExpandedThingDescriptionSet()
+	if result := set.Append(lamp); result != AppendInserted {
+		t.Fatalf("Expected anonymous td to be inserted, got %v", result)
+	}
+
+	if result := set.Append(anonymousTD(t, "Sensor", "")); result != AppendInserted {
+		t.Fatalf("Expected different anonymous td to be inserted, got %v", result)
+	}
+
+	if result := set.Append(anonymousTD(t, "Lamp", "")); result != AppendUnchanged {
+		t.Fatalf("Expected equal anonymous td to be unchanged, got %v", result)
+	}
+
+	if len(set) != 2 || set.Get(id).ID != id {
+		t.Fatalf("Expected 2 tds including %s, got %v", id, set)
+	}
+
+	// members which aren't supported distinguish anonymous tds as well
+	kitchen, err := FromBytes([]byte(`{"@context": "https://www.w3.org/2019/wot/td/v1", "title": "Lamp", "description": "kitchen"}`))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	bedroom, err := FromBytes([]byte(`{"@context": "https://www.w3.org/2019/wot/td/v1", "title": "Lamp", "description": "bedroom"}`))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	if result := set.Append(kitchen); result != AppendInserted {
+		t.Fatalf("Expected kitchen lamp to be inserted, got %v", result)
+	}
+
+	if result, err := set.AppendWithPolicy(bedroom, ConflictReject); result != AppendInserted || err != nil {
+		t.Fatalf("Expected bedroom lamp to be inserted, got %v (%v)", result, err)
+	}
+
+	if len(set) != 4 {
+		t.Fatalf("Expected 4 tds, got %d", len(set))
+	}
+}
+
+func TestTDSetConflicts(t *testing.T) {
+	withID := func(td ExpandedThingDescription) ExpandedThingDescription {
+		td.ID = "urn:dev:ops:lamp"
+		return td
+	}
+
+	older := withID(anonymousTD(t, "Older", "2020-01-01T00:00:00Z"))
+	newer := withID(anonymousTD(t, "Newer", "2021-01-01T00:00:00+01:00"))
+	undated := withID(anonymousTD(t, "Undated", ""))
+
+	tests := []struct {
+		existing ExpandedThingDescription
+		td       ExpandedThingDescription
+		policy   ConflictPolicy
+		result   AppendResult
+		err      error
+		expected string
+	}{
+		{older, newer, ConflictReplace, AppendReplaced, nil, "Newer"},
+		{newer, older, ConflictReplace, AppendReplaced, nil, "Older"},
+		{older, newer, ConflictReject, AppendSkipped, ErrConflict, "Older"},
+		{older, newer, ConflictKeepNewest, AppendReplaced, nil, "Newer"},
+		{newer, older, ConflictKeepNewest, AppendSkipped, nil, "Newer"},
+		{older, older, ConflictKeepNewest, AppendUnchanged, nil, "Older"},
+		{older, older, ConflictReject, AppendUnchanged, nil, "Older"},
+		{newer, undated, ConflictKeepNewest, AppendSkipped, nil, "Newer"},
+		{undated, older, ConflictKeepNewest, AppendReplaced, nil, "Older"},
+	}
+
+	for i, currTest := range tests {
+		set := NewExpandedThingDescriptionSet(currTest.existing)
+
+		result, err := set.AppendWithPolicy(currTest.td, currTest.policy)
+		if result != currTest.result || !errors.Is(err, currTest.err) {
+			t.Fatalf("Test %d: expected %v (%v), got %v (%v)", i, currTest.result, currTest.err, result, err)
+		}
+
+		if title := set.Get("urn:dev:ops:lamp").Title.Value(); len(set) != 1 || title != currTest.expected {
+			t.Fatalf("Test %d: expected %s to be kept, got %s", i, currTest.expected, title)
+		}
+	}
+}
+
+func TestTDSetContent(t *testing.T) {
+	lamp := func(members string) ExpandedThingDescription {
+		td, err := FromBytes([]byte(`{
+			"@context": "https://www.w3.org/2019/wot/td/v1",
+			"id": "urn:dev:ops:lamp",
+			"title": "Lamp"` + members + `
+		}`))
+		if err != nil {
+			t.Fatalf("Failed to build expanded td: %v", err)
+		}
+
+		return td
+	}
+
+	modified := lamp("")
+	modified.Title = StringNode{{Value: "Modified"}}
+
+	tests := []struct {
+		td     ExpandedThingDescription
+		result AppendResult
+		err    error
+	}{
+		{lamp(""), AppendUnchanged, nil},
+		// members the td doesn't support are part of the content
+		{lamp(`, "description": "A lamp"`), AppendSkipped, ErrConflict},
+		{modified, AppendSkipped, ErrConflict},
+	}
+
+	for i, currTest := range tests {
+		set := NewExpandedThingDescriptionSet(lamp(""))
+
+		result, err := set.AppendWithPolicy(currTest.td, ConflictReject)
+		if result != currTest.result || !errors.Is(err, currTest.err) {
+			t.Fatalf("Test %d: expected %v (%v), got %v (%v)", i, currTest.result, currTest.err, result, err)
+		}
+	}
+}
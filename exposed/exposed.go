@@ -0,0 +1,294 @@
+// Package exposed implements the server side of thing descriptions. An exposed
+// thing serves its td and dispatches requests for the forms of its affordances
+// to handlers, validating payloads against the data schemas of the affordances
+package exposed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/connctd/wotlib"
+)
+
+// WellKnownPath is the path the td is served at
+const WellKnownPath = "/.well-known/wot"
+
+// TDContentType is the media type of thing descriptions
+const TDContentType = "application/td+json"
+
+// well known errors
+var (
+	ErrUnknownAffordance = errors.New("unknown affordance")
+)
+
+// PropertyReadHandler returns the current value of a property
+type PropertyReadHandler func(ctx context.Context) (interface{}, error)
+
+// PropertyWriteHandler applies a validated value to a property
+type PropertyWriteHandler func(ctx context.Context, value interface{}) error
+
+// ActionHandler performs an action with a validated input. The output is
+// validated against the output schema of the action
+type ActionHandler func(ctx context.Context, input interface{}) (interface{}, error)
+
+// ExposedThing serves a thing description and the forms of its affordances
+type ExposedThing struct {
+	document map[string]interface{}
+	td       wotlib.ExpandedThingDescription
+	baseURL  *url.URL
+	routes   []route
+
+	mutex          sync.RWMutex
+	readHandlers   map[string]PropertyReadHandler
+	writeHandlers  map[string]PropertyWriteHandler
+	actionHandlers map[string]ActionHandler
+}
+
+// Option configures an exposed thing
+type Option func(t *ExposedThing) error
+
+// WithBaseURL sets the url the thing is reachable at. Hrefs of forms are
+// relative to it. By default the base of the td is used or, without base,
+// the base url is derived from incoming requests and the thing is served at
+// the root path. A td with another base is rejected
+func WithBaseURL(baseURL string) Option {
+	return func(t *ExposedThing) error {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+
+		if u.Path == "" {
+			u.Path = "/"
+		}
+
+		t.baseURL = u
+		return nil
+	}
+}
+
+// NewExposedThing creates an exposed thing from a thing description. Affordances
+// without forms get http forms with hrefs like "properties/{name}" relative to
+// the base url. Properties are read via GET and written via PUT, actions are
+// invoked via POST unless the forms define another method
+func NewExposedThing(td []byte, opts ...Option) (*ExposedThing, error) {
+	t := &ExposedThing{
+		readHandlers:   map[string]PropertyReadHandler{},
+		writeHandlers:  map[string]PropertyWriteHandler{},
+		actionHandlers: map[string]ActionHandler{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(td, &t.document); err != nil {
+		return nil, err
+	}
+
+	// the base of the td is served, so routes have to be below it as well
+	if base, ok := t.ownBase(); ok {
+		configured := t.baseURL
+		if err := WithBaseURL(base)(t); err != nil {
+			return nil, err
+		}
+
+		if configured != nil && configured.String() != t.baseURL.String() {
+			return nil, fmt.Errorf("base url %s doesn't match the base %s of the td", configured, base)
+		}
+	}
+
+	generateForms(t.document)
+
+	// the td of the thing has hrefs resolved if the base url is known
+	document := t.document
+	if t.baseURL != nil {
+		document = t.withBase(t.baseURL.String())
+	}
+
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	t.td, err = wotlib.FromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	t.routes, err = buildRoutes(t.document, t.basePath())
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// TD returns the expanded thing description of the thing. Hrefs are
+// absolute if the base url is known before serving requests
+func (t *ExposedThing) TD() wotlib.ExpandedThingDescription {
+	return t.td
+}
+
+// SetPropertyReadHandler sets the handler reading a property
+func (t *ExposedThing) SetPropertyReadHandler(name string, handler PropertyReadHandler) error {
+	if _, ok := t.property(name); !ok {
+		return fmt.Errorf("%w: property %s", ErrUnknownAffordance, name)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.readHandlers[name] = handler
+
+	return nil
+}
+
+// SetPropertyWriteHandler sets the handler writing a property
+func (t *ExposedThing) SetPropertyWriteHandler(name string, handler PropertyWriteHandler) error {
+	if _, ok := t.property(name); !ok {
+		return fmt.Errorf("%w: property %s", ErrUnknownAffordance, name)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.writeHandlers[name] = handler
+
+	return nil
+}
+
+// SetActionHandler sets the handler performing an action
+func (t *ExposedThing) SetActionHandler(name string, handler ActionHandler) error {
+	if _, ok := t.action(name); !ok {
+		return fmt.Errorf("%w: action %s", ErrUnknownAffordance, name)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.actionHandlers[name] = handler
+
+	return nil
+}
+
+// Document returns the thing description as served for the given base url.
+// An absolute base of the td itself is kept
+func (t *ExposedThing) Document(baseURL string) ([]byte, error) {
+	if _, ok := t.ownBase(); ok {
+		return json.Marshal(t.document)
+	}
+
+	return json.Marshal(t.withBase(baseURL))
+}
+
+// ownBase returns the base of the td if it is an absolute url
+func (t *ExposedThing) ownBase() (string, bool) {
+	base, _ := t.document["base"].(string)
+
+	u, err := url.Parse(base)
+	if err != nil || !u.IsAbs() {
+		return "", false
+	}
+
+	return base, true
+}
+
+// withBase returns a copy of the document with the given base
+func (t *ExposedThing) withBase(baseURL string) map[string]interface{} {
+	document := make(map[string]interface{}, len(t.document)+1)
+	for key, value := range t.document {
+		document[key] = value
+	}
+
+	document["base"] = baseURL
+
+	return document
+}
+
+func (t *ExposedThing) property(name string) (wotlib.ExpandedPropertyAffordance, bool) {
+	for _, currProperty := range t.td.Properties {
+		if currProperty.Name.Value() == name {
+			return currProperty, true
+		}
+	}
+
+	return wotlib.ExpandedPropertyAffordance{}, false
+}
+
+func (t *ExposedThing) action(name string) (wotlib.ExpandedActionAffordance, bool) {
+	for _, currAction := range t.td.Actions {
+		if currAction.Name.Value() == name {
+			return currAction, true
+		}
+	}
+
+	return wotlib.ExpandedActionAffordance{}, false
+}
+
+func (t *ExposedThing) basePath() string {
+	if t.baseURL == nil {
+		return "/"
+	}
+
+	return t.baseURL.Path
+}
+
+// generateForms adds http forms to affordances without forms
+func generateForms(document map[string]interface{}) {
+	if properties, ok := document["properties"].(map[string]interface{}); ok {
+		for name, currProperty := range properties {
+			property, ok := currProperty.(map[string]interface{})
+			if !ok || hasForms(property) {
+				continue
+			}
+
+			var ops []interface{}
+			for _, currOp := range propertyOps(property) {
+				ops = append(ops, currOp)
+			}
+
+			property["forms"] = []interface{}{
+				map[string]interface{}{"href": "properties/" + url.PathEscape(name), "op": ops},
+			}
+		}
+	}
+
+	if actions, ok := document["actions"].(map[string]interface{}); ok {
+		for name, currAction := range actions {
+			action, ok := currAction.(map[string]interface{})
+			if !ok || hasForms(action) {
+				continue
+			}
+
+			action["forms"] = []interface{}{
+				map[string]interface{}{"href": "actions/" + url.PathEscape(name), "op": []interface{}{"invokeaction"}},
+			}
+		}
+	}
+}
+
+// propertyOps returns the operations a property supports by default
+func propertyOps(property map[string]interface{}) []string {
+	var ops []string
+	if writeOnly, _ := property["writeOnly"].(bool); !writeOnly {
+		ops = append(ops, opReadProperty)
+	}
+
+	if readOnly, _ := property["readOnly"].(bool); !readOnly {
+		ops = append(ops, opWriteProperty)
+	}
+
+	return ops
+}
+
+func hasForms(affordance map[string]interface{}) bool {
+	forms, _ := affordance["forms"].([]interface{})
+	return len(forms) > 0
+}
@@ -0,0 +1,261 @@
+package exposed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/consumer"
+	"github.com/connctd/wotlib/internal/wottest"
+)
+
+func init() {
+	wotlib.DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
+
+func newTestThing(t *testing.T, opts ...Option) *ExposedThing {
+	thing, err := NewExposedThing([]byte(testTD), opts...)
+	if err != nil {
+		t.Fatalf("Failed to create exposed thing: %v", err)
+	}
+
+	var mutex sync.Mutex
+	brightness := 50.0
+
+	thing.SetPropertyReadHandler("brightness", func(ctx context.Context) (interface{}, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return brightness, nil
+	})
+
+	thing.SetPropertyWriteHandler("brightness", func(ctx context.Context, value interface{}) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		brightness = value.(float64)
+		return nil
+	})
+
+	thing.SetActionHandler("fade", func(ctx context.Context, input interface{}) (interface{}, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		brightness = input.(map[string]interface{})["to"].(float64)
+		return map[string]interface{}{"brightness": brightness}, nil
+	})
+
+	return thing
+}
+
+func fetchTD(t *testing.T, url string) wotlib.ExpandedThingDescription {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to fetch td: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != TDContentType {
+		t.Fatalf("Unexpected response %d (%s)", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	td, err := wotlib.FromResponse(resp)
+	if err != nil {
+		t.Fatalf("Failed to expand td: %v", err)
+	}
+
+	return td
+}
+
+func findForm(t *testing.T, forms wotlib.ExpandedFormNode, op string) wotlib.ExpandedForm {
+	form, ok := forms.Find(op)
+	if !ok {
+		t.Fatalf("No form for %s", op)
+	}
+
+	return form
+}
+
+func TestExposedThing(t *testing.T) {
+	server := httptest.NewServer(newTestThing(t))
+	defer server.Close()
+
+	td := fetchTD(t, server.URL+WellKnownPath)
+
+	if td.Base.Value() != server.URL+"/" {
+		t.Fatalf("Unexpected base %s", td.Base.Value())
+	}
+
+	brightness := td.GetPropertyAffordances(wotlib.PropertyConstraint{})[0]
+	if href := findForm(t, brightness.Form, wotlib.OpWriteProperty).Href.Value(); href != server.URL+"/properties/brightness" {
+		t.Fatalf("Unexpected href %s", href)
+	}
+
+	c := consumer.NewConsumer()
+	ctx := context.Background()
+
+	if err := c.WriteProperty(ctx, brightness, 80); err != nil {
+		t.Fatalf("Failed to write property: %v", err)
+	}
+
+	if value, err := c.ReadProperty(ctx, brightness); err != nil || value != float64(80) {
+		t.Fatalf("Unexpected value %v (%v)", value, err)
+	}
+
+	fade := td.Actions[0]
+	output, err := c.InvokeAction(ctx, fade, map[string]interface{}{"to": 10})
+	if err != nil {
+		t.Fatalf("Failed to invoke action: %v", err)
+	}
+
+	if output.(map[string]interface{})["brightness"] != float64(10) {
+		t.Fatalf("Unexpected output %v", output)
+	}
+
+	// the td is also served at the url of the thing itself
+	fetchTD(t, server.URL)
+}
+
+func TestExposedThingErrors(t *testing.T) {
+	thing := newTestThing(t, WithBaseURL("http://lamp.local/things/lamp/"))
+
+	if err := thing.SetActionHandler("unknown", nil); !errors.Is(err, ErrUnknownAffordance) {
+		t.Fatalf("Expected unknown affordance error, got %v", err)
+	}
+
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{method: http.MethodGet, path: "/things/lamp/", status: http.StatusOK},
+		{method: http.MethodGet, path: "/things/lamp/properties/brightness", status: http.StatusOK},
+		{method: http.MethodPut, path: "/things/lamp/properties/brightness", body: "120", status: http.StatusBadRequest},
+		{method: http.MethodPut, path: "/things/lamp/properties/brightness", body: "{", status: http.StatusBadRequest},
+		{method: http.MethodPut, path: "/things/lamp/properties/brightness", contentType: "application/xml", body: "<a/>", status: http.StatusUnsupportedMediaType},
+		{method: http.MethodPut, path: "/things/lamp/properties/brightness", body: "20", status: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/things/lamp/properties/brightness", status: http.StatusMethodNotAllowed},
+		{method: http.MethodGet, path: "/things/lamp/properties/status", status: http.StatusOK},
+		{method: http.MethodPut, path: "/things/lamp/properties/status", body: `"on"`, status: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, path: "/things/lamp/actions/fade", body: `{"to":"dark"}`, status: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/things/lamp/actions/fade", status: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/things/lamp/actions/reset", status: http.StatusNotImplemented},
+		{method: http.MethodGet, path: "/things/lamp/unknown", status: http.StatusNotFound},
+	}
+
+	thing.SetPropertyReadHandler("status", func(ctx context.Context) (interface{}, error) {
+		return "on", nil
+	})
+
+	for _, currTest := range tests {
+		req := httptest.NewRequest(currTest.method, currTest.path, bytes.NewBufferString(currTest.body))
+		if currTest.contentType != "" {
+			req.Header.Set("Content-Type", currTest.contentType)
+		}
+
+		rec := httptest.NewRecorder()
+		thing.ServeHTTP(rec, req)
+
+		if rec.Code != currTest.status {
+			body, _ := ioutil.ReadAll(rec.Body)
+			t.Fatalf("Expected status %d for %s %s, got %d: %s", currTest.status, currTest.method, currTest.path, rec.Code, body)
+		}
+	}
+}
+
+func TestExposedThingBase(t *testing.T) {
+	withBase := strings.Replace(testTD, `"title"`, `"base": "http://lamp.local/things/lamp/", "title"`, 1)
+
+	tests := []struct {
+		td       string
+		opts     []Option
+		base     string
+		href     string
+		tdHref   string
+		basePath string
+	}{
+		{testTD, nil, "http://example.com/", "http://example.com/properties/status", "properties/status", "/"},
+		{testTD, []Option{WithBaseURL("http://lamp.local/things/lamp/")}, "http://lamp.local/things/lamp/", "http://lamp.local/things/lamp/properties/status", "http://lamp.local/things/lamp/properties/status", "/things/lamp/"},
+		{withBase, nil, "http://lamp.local/things/lamp/", "http://lamp.local/things/lamp/properties/status", "http://lamp.local/things/lamp/properties/status", "/things/lamp/"},
+		{withBase, []Option{WithBaseURL("http://lamp.local/things/lamp/")}, "http://lamp.local/things/lamp/", "http://lamp.local/things/lamp/properties/status", "http://lamp.local/things/lamp/properties/status", "/things/lamp/"},
+	}
+
+	if _, err := NewExposedThing([]byte(withBase), WithBaseURL("http://other.local/")); err == nil {
+		t.Fatalf("Expected base url not matching the base of the td to be rejected")
+	}
+
+	name := "status"
+	for i, currTest := range tests {
+		thing, err := NewExposedThing([]byte(currTest.td), currTest.opts...)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create exposed thing: %v", i, err)
+		}
+
+		rec := httptest.NewRecorder()
+		thing.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, WellKnownPath, nil))
+
+		td, err := wotlib.FromBytes(rec.Body.Bytes())
+		if err != nil {
+			t.Fatalf("Test %d: failed to expand td: %v", i, err)
+		}
+
+		status := td.GetPropertyAffordances(wotlib.PropertyConstraint{Name: &name})[0]
+		if td.Base.Value() != currTest.base || status.Form.Value().Href.Value() != currTest.href {
+			t.Fatalf("Test %d: unexpected base %s and href %s", i, td.Base.Value(), status.Form.Value().Href.Value())
+		}
+
+		own := thing.TD()
+		status = own.GetPropertyAffordances(wotlib.PropertyConstraint{Name: &name})[0]
+		if href := status.Form.Value().Href.Value(); href != currTest.tdHref {
+			t.Fatalf("Test %d: unexpected href %s of the td", i, href)
+		}
+
+		rec = httptest.NewRecorder()
+		thing.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, currTest.basePath+"actions/reset", nil))
+
+		if rec.Code != http.StatusNotImplemented {
+			t.Fatalf("Test %d: expected action to be served below %s, got %d", i, currTest.basePath, rec.Code)
+		}
+	}
+}
+
+var testTD = `{
+    "@context": "https://www.w3.org/2019/wot/td/v1",
+    "id": "urn:dev:ops:32473-ExposedLamp",
+    "title": "ExposedLamp",
+    "properties": {
+        "brightness": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 100
+        },
+        "status": {
+            "type": "string",
+            "readOnly": true,
+            "forms": [{"href": "properties/status"}]
+        }
+    },
+    "actions": {
+        "fade": {
+            "input": {
+                "type": "object",
+                "properties": {"to": {"type": "integer", "minimum": 0, "maximum": 100}},
+                "required": ["to"]
+            },
+            "output": {
+                "type": "object",
+                "properties": {"brightness": {"type": "integer"}}
+            }
+        },
+        "reset": {}
+    }
+}`
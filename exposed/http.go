@@ -0,0 +1,170 @@
+package exposed
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/connctd/wotlib/internal/payload"
+)
+
+// ServeHTTP serves the thing description at the well known path and the base
+// path of the thing and dispatches requests for forms to the handlers
+func (t *ExposedThing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == WellKnownPath || r.URL.Path == t.basePath() {
+		t.serveTD(w, r)
+		return
+	}
+
+	var allowed []string
+	for _, currRoute := range t.routes {
+		if currRoute.path != r.URL.Path {
+			continue
+		}
+
+		if currRoute.method == r.Method {
+			t.serveRoute(w, r, currRoute)
+			return
+		}
+
+		allowed = append(allowed, currRoute.method)
+	}
+
+	if len(allowed) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	sort.Strings(allowed)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+func (t *ExposedThing) serveTD(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := t.Document(t.requestBaseURL(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", TDContentType)
+	w.Write(b)
+}
+
+// requestBaseURL returns the configured base url or derives it from the request
+func (t *ExposedThing) requestBaseURL(r *http.Request) string {
+	if t.baseURL != nil {
+		return t.baseURL.String()
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + "/"
+}
+
+func (t *ExposedThing) serveRoute(w http.ResponseWriter, r *http.Request, rt route) {
+	switch rt.op {
+	case opReadProperty:
+		t.readProperty(w, r, rt)
+	case opWriteProperty:
+		t.writeProperty(w, r, rt)
+	case opInvokeAction:
+		t.invokeAction(w, r, rt)
+	}
+}
+
+func (t *ExposedThing) readProperty(w http.ResponseWriter, r *http.Request, rt route) {
+	property, _ := t.property(rt.name)
+
+	t.mutex.RLock()
+	handler := t.readHandlers[rt.name]
+	t.mutex.RUnlock()
+
+	if handler == nil {
+		http.Error(w, "property is not readable", http.StatusNotImplemented)
+		return
+	}
+
+	value, err := handler(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := property.Validate(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payload.Write(w, rt.contentType, http.StatusOK, value)
+}
+
+func (t *ExposedThing) writeProperty(w http.ResponseWriter, r *http.Request, rt route) {
+	property, _ := t.property(rt.name)
+
+	t.mutex.RLock()
+	handler := t.writeHandlers[rt.name]
+	t.mutex.RUnlock()
+
+	if handler == nil {
+		http.Error(w, "property is not writable", http.StatusNotImplemented)
+		return
+	}
+
+	value, ok := payload.Read(w, r, rt.contentType, property.ExpandedDataSchema)
+	if !ok {
+		return
+	}
+
+	if err := handler(r.Context(), value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (t *ExposedThing) invokeAction(w http.ResponseWriter, r *http.Request, rt route) {
+	action, _ := t.action(rt.name)
+
+	t.mutex.RLock()
+	handler := t.actionHandlers[rt.name]
+	t.mutex.RUnlock()
+
+	if handler == nil {
+		http.Error(w, "action is not implemented", http.StatusNotImplemented)
+		return
+	}
+
+	input, ok := payload.Read(w, r, rt.contentType, action.Input.Value())
+	if !ok {
+		return
+	}
+
+	output, err := handler(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if output == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := action.Output.Validate(output); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payload.Write(w, rt.contentType, http.StatusOK, output)
+}
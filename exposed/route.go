@@ -0,0 +1,118 @@
+package exposed
+
+import (
+	"net/url"
+
+	"github.com/connctd/wotlib/internal/codec"
+	"github.com/connctd/wotlib/internal/payload"
+)
+
+// operation types handled by an exposed thing
+const (
+	opReadProperty  = "readproperty"
+	opWriteProperty = "writeproperty"
+	opInvokeAction  = "invokeaction"
+)
+
+// route maps a request to an operation on an affordance
+type route struct {
+	path        string
+	method      string
+	op          string
+	name        string
+	contentType string
+}
+
+// buildRoutes creates the routes of all forms with http or relative hrefs
+func buildRoutes(document map[string]interface{}, basePath string) ([]route, error) {
+	var routes []route
+
+	add := func(affordances interface{}, defaultOps func(affordance map[string]interface{}) []string) error {
+		byName, _ := affordances.(map[string]interface{})
+		for name, currAffordance := range byName {
+			affordance, _ := currAffordance.(map[string]interface{})
+			forms, _ := affordance["forms"].([]interface{})
+
+			for _, currForm := range forms {
+				form, _ := currForm.(map[string]interface{})
+
+				formRoutes, err := formRoutes(form, name, basePath, defaultOps(affordance))
+				if err != nil {
+					return err
+				}
+
+				routes = append(routes, formRoutes...)
+			}
+		}
+
+		return nil
+	}
+
+	if err := add(document["properties"], propertyOps); err != nil {
+		return nil, err
+	}
+
+	actionOps := func(map[string]interface{}) []string { return []string{opInvokeAction} }
+	if err := add(document["actions"], actionOps); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// formRoutes creates a route for each supported operation of a form
+func formRoutes(form map[string]interface{}, name string, basePath string, defaultOps []string) ([]route, error) {
+	href, _ := form["href"].(string)
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.IsAbs() && u.Scheme != "http" && u.Scheme != "https" {
+		return nil, nil
+	}
+
+	path := (&url.URL{Path: basePath}).ResolveReference(u).Path
+
+	contentType, _ := form["contentType"].(string)
+	if contentType == "" {
+		contentType = codec.DefaultContentType
+	}
+
+	ops := defaultOps
+	switch op := form["op"].(type) {
+	case string:
+		ops = []string{op}
+	case []interface{}:
+		ops = nil
+		for _, currOp := range op {
+			if s, ok := currOp.(string); ok {
+				ops = append(ops, s)
+			}
+		}
+	}
+
+	var routes []route
+	for _, currOp := range ops {
+		if currOp != opReadProperty && currOp != opWriteProperty && currOp != opInvokeAction {
+			continue
+		}
+
+		method, _ := payload.DefaultMethod(currOp)
+
+		if formMethod, ok := form["htv:methodName"].(string); ok {
+			method = formMethod
+		}
+
+		routes = append(routes, route{
+			path:        path,
+			method:      method,
+			op:          currOp,
+			name:        name,
+			contentType: contentType,
+		})
+	}
+
+	return routes, nil
+}
@@ -4,5 +4,6 @@ go 1.14
 
 require (
 	github.com/piprate/json-gold v0.3.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9 // indirect
 	gopkg.in/square/go-jose.v2 v2.5.0
 )
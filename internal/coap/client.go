@@ -0,0 +1,312 @@
+package coap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// transmission parameters
+const (
+	ackTimeout     = 2 * time.Second
+	maxRetransmit  = 4
+	maxMessageSize = 1152
+)
+
+// well known errors
+var (
+	ErrClosed  = errors.New("coap: connection closed")
+	ErrReset   = errors.New("coap: message was reset by peer")
+	ErrTimeout = errors.New("coap: no acknowledgement received")
+)
+
+// Client exchanges messages with a single endpoint
+type Client struct {
+	conn net.Conn
+
+	mutex         sync.Mutex
+	nextMessageID uint16
+	byToken       map[string]*exchange
+	byMessageID   map[uint16]*exchange
+	err           error
+	done          chan struct{}
+}
+
+// exchange tracks a request and its responses
+type exchange struct {
+	request   *Message
+	acked     chan struct{}
+	ackOnce   sync.Once
+	responses chan *Message
+	failed    chan error
+}
+
+func (e *exchange) ack() {
+	e.ackOnce.Do(func() { close(e.acked) })
+}
+
+// Dial creates a client for the endpoint at the given address (host:port)
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var id [2]byte
+	rand.Read(id[:])
+
+	c := &Client{
+		conn:          conn,
+		nextMessageID: binary.BigEndian.Uint16(id[:]),
+		byToken:       map[string]*exchange{},
+		byMessageID:   map[uint16]*exchange{},
+		done:          make(chan struct{}),
+	}
+
+	go c.readLoop()
+
+	return c, nil
+}
+
+// Do sends a confirmable request and waits for its response
+func (c *Client) Do(ctx context.Context, req *Message) (*Message, error) {
+	e, err := c.start(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer c.finish(e)
+
+	select {
+	case resp := <-e.responses:
+		return resp, nil
+	case err := <-e.failed:
+		return nil, err
+	case <-c.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Observe sends a request registering an observation. The response and
+// all notifications are delivered on the returned channel until the context
+// is done
+func (c *Client) Observe(ctx context.Context, req *Message) (<-chan *Message, error) {
+	req.SetUintOption(OptionObserve, 0)
+
+	e, err := c.start(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make(chan *Message)
+
+	go func() {
+		defer close(notifications)
+		defer c.finish(e)
+
+		for {
+			select {
+			case resp := <-e.responses:
+				select {
+				case notifications <- resp:
+				case <-ctx.Done():
+					c.deregister(req)
+					return
+				}
+
+				if resp.Code.Class() != 2 {
+					return
+				}
+			case <-e.failed:
+				return
+			case <-c.done:
+				return
+			case <-ctx.Done():
+				c.deregister(req)
+				return
+			}
+		}
+	}()
+
+	return notifications, nil
+}
+
+// Close closes the client
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// start sends a request and retransmits it until it is acknowledged
+func (c *Client) start(ctx context.Context, req *Message) (*exchange, error) {
+	token := make([]byte, 4)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	req.Type = Confirmable
+	req.Token = token
+
+	e := &exchange{
+		request:   req,
+		acked:     make(chan struct{}),
+		responses: make(chan *Message, 8),
+		failed:    make(chan error, 1),
+	}
+
+	c.mutex.Lock()
+	if c.err != nil {
+		c.mutex.Unlock()
+		return nil, c.err
+	}
+
+	req.MessageID = c.nextMessageID
+	c.nextMessageID++
+	c.byToken[string(token)] = e
+	c.byMessageID[req.MessageID] = e
+	c.mutex.Unlock()
+
+	encoded := req.Encode()
+	if _, err := c.conn.Write(encoded); err != nil {
+		c.finish(e)
+		return nil, err
+	}
+
+	go func() {
+		timeout := ackTimeout
+		for attempt := 0; attempt < maxRetransmit; attempt++ {
+			select {
+			case <-e.acked:
+				return
+			case <-ctx.Done():
+				return
+			case <-c.done:
+				return
+			case <-time.After(timeout):
+			}
+
+			c.conn.Write(encoded)
+			timeout *= 2
+		}
+
+		select {
+		case <-e.acked:
+		case <-ctx.Done():
+		case <-c.done:
+		case <-time.After(timeout):
+			e.failed <- ErrTimeout
+		}
+	}()
+
+	return e, nil
+}
+
+func (c *Client) finish(e *exchange) {
+	e.ack()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.byToken, string(e.request.Token))
+	delete(c.byMessageID, e.request.MessageID)
+}
+
+// deregister cancels an observation
+func (c *Client) deregister(req *Message) {
+	c.mutex.Lock()
+	cancel := &Message{
+		Type:      NonConfirmable,
+		Code:      req.Code,
+		MessageID: c.nextMessageID,
+		Token:     req.Token,
+		Options:   append([]Option{}, req.Options...),
+	}
+	c.nextMessageID++
+	c.mutex.Unlock()
+
+	cancel.SetUintOption(OptionObserve, 1)
+	c.conn.Write(cancel.Encode())
+}
+
+func (c *Client) readLoop() {
+	buf := make([]byte, maxMessageSize)
+
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				continue
+			}
+
+			break
+		}
+
+		msg, err := Decode(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		c.handle(msg)
+	}
+
+	c.mutex.Lock()
+	c.err = ErrClosed
+	c.mutex.Unlock()
+
+	close(c.done)
+}
+
+func (c *Client) handle(msg *Message) {
+	c.mutex.Lock()
+	byID := c.byMessageID[msg.MessageID]
+	byToken := c.byToken[string(msg.Token)]
+	c.mutex.Unlock()
+
+	switch msg.Type {
+	case Reset:
+		if byID != nil {
+			byID.ack()
+			select {
+			case byID.failed <- ErrReset:
+			default:
+			}
+		}
+
+		return
+	case Acknowledgement:
+		if byID != nil {
+			byID.ack()
+		}
+
+		if msg.Code == Empty {
+			// separate response follows
+			return
+		}
+	case Confirmable:
+		if byToken == nil {
+			c.conn.Write((&Message{Type: Reset, MessageID: msg.MessageID}).Encode())
+			return
+		}
+
+		c.conn.Write((&Message{Type: Acknowledgement, MessageID: msg.MessageID}).Encode())
+	case NonConfirmable:
+		if byToken == nil {
+			return
+		}
+	}
+
+	if byToken != nil {
+		byToken.ack()
+		select {
+		case byToken.responses <- msg:
+		default:
+			// slow receivers miss notifications
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package coap
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, handler Handler) (*Server, string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := NewServer(handler)
+	go server.Serve(conn)
+
+	return server, conn.LocalAddr().String()
+}
+
+func TestEncodeDecode(t *testing.T) {
+	msg := &Message{
+		Type:      Confirmable,
+		Code:      PUT,
+		MessageID: 4711,
+		Token:     []byte{1, 2, 3, 4},
+		Payload:   []byte(`{"on":true}`),
+	}
+	msg.SetPath("/lamp/properties/brightness")
+	msg.SetUintOption(OptionContentFormat, 50)
+	msg.SetUintOption(OptionAccept, 50)
+
+	decoded, err := Decode(msg.Encode())
+	if err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+
+	if decoded.Type != msg.Type || decoded.Code != msg.Code || decoded.MessageID != msg.MessageID {
+		t.Fatalf("Unexpected header %+v", decoded)
+	}
+
+	if !bytes.Equal(decoded.Token, msg.Token) || !bytes.Equal(decoded.Payload, msg.Payload) {
+		t.Fatalf("Unexpected token or payload %+v", decoded)
+	}
+
+	if decoded.Path() != "/lamp/properties/brightness" {
+		t.Fatalf("Unexpected path %s", decoded.Path())
+	}
+
+	if format, ok := decoded.UintOption(OptionContentFormat); !ok || format != 50 {
+		t.Fatalf("Unexpected content format %d", format)
+	}
+
+	if _, err := Decode([]byte{0x40}); err == nil {
+		t.Fatalf("Expected error for truncated message")
+	}
+}
+
+func TestDo(t *testing.T) {
+	server, addr := startServer(t, func(req *Message) *Message {
+		if req.Path() != "/echo" {
+			return &Message{Code: NotFound}
+		}
+
+		return &Message{Code: Changed, Payload: req.Payload}
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := Dial(ctx, addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	req := &Message{Code: POST, Payload: []byte("hello")}
+	req.SetPath("/echo")
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	if resp.Code != Changed || string(resp.Payload) != "hello" {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+
+	req = &Message{Code: GET}
+	req.SetPath("/unknown")
+
+	resp, err = client.Do(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	if resp.Code != NotFound {
+		t.Fatalf("Unexpected response code %s", resp.Code)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	server, addr := startServer(t, func(req *Message) *Message {
+		return &Message{Code: Content, Payload: []byte("1")}
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := Dial(ctx, addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	req := &Message{Code: GET}
+	req.SetPath("/counter")
+
+	notifications, err := client.Observe(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to observe: %v", err)
+	}
+
+	if msg := <-notifications; string(msg.Payload) != "1" {
+		t.Fatalf("Unexpected response %+v", msg)
+	}
+
+	server.Notify("/counter", 0, []byte("2"))
+
+	msg := <-notifications
+	if string(msg.Payload) != "2" {
+		t.Fatalf("Unexpected notification %+v", msg)
+	}
+
+	if _, ok := msg.UintOption(OptionObserve); !ok {
+		t.Fatalf("Expected observe option in notification")
+	}
+}
@@ -0,0 +1,319 @@
+// Package coap implements the parts of the constrained application protocol
+// (RFC 7252) and its observe extension (RFC 7641) needed by the protocol
+// bindings of this module: a client and a small server for tests
+package coap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// message types
+const (
+	Confirmable     = 0
+	NonConfirmable  = 1
+	Acknowledgement = 2
+	Reset           = 3
+)
+
+// Code of a request or response. The upper three bits are the class
+type Code byte
+
+// well known codes
+const (
+	Empty Code = 0
+
+	GET    Code = 1
+	POST   Code = 2
+	PUT    Code = 3
+	DELETE Code = 4
+
+	Created              Code = 2<<5 | 1
+	Deleted              Code = 2<<5 | 2
+	Valid                Code = 2<<5 | 3
+	Changed              Code = 2<<5 | 4
+	Content              Code = 2<<5 | 5
+	BadRequest           Code = 4<<5 | 0
+	NotFound             Code = 4<<5 | 4
+	MethodNotAllowed     Code = 4<<5 | 5
+	UnsupportedMediaType Code = 4<<5 | 15
+	InternalServerError  Code = 5<<5 | 0
+	ServiceUnavailable   Code = 5<<5 | 3
+)
+
+// Class returns the class of the code, e.g. 2 for success
+func (c Code) Class() int {
+	return int(c >> 5)
+}
+
+func (c Code) String() string {
+	return fmt.Sprintf("%d.%02d", c>>5, c&0x1f)
+}
+
+// ParseMethod returns the code of a method name like "GET"
+func ParseMethod(method string) (Code, bool) {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return GET, true
+	case "POST":
+		return POST, true
+	case "PUT":
+		return PUT, true
+	case "DELETE":
+		return DELETE, true
+	}
+
+	return Empty, false
+}
+
+// option numbers
+const (
+	OptionObserve       = 6
+	OptionURIPath       = 11
+	OptionContentFormat = 12
+	OptionURIQuery      = 15
+	OptionAccept        = 17
+)
+
+// Option of a message
+type Option struct {
+	Number int
+	Value  []byte
+}
+
+// Message is a coap message
+type Message struct {
+	Type      int
+	Code      Code
+	MessageID uint16
+	Token     []byte
+	Options   []Option
+	Payload   []byte
+}
+
+var errMalformedMessage = errors.New("coap: malformed message")
+
+// Path returns the uri path of the message
+func (m *Message) Path() string {
+	var segments []string
+	for _, o := range m.Options {
+		if o.Number == OptionURIPath {
+			segments = append(segments, string(o.Value))
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+// SetPath sets the uri path options of the message
+func (m *Message) SetPath(path string) {
+	m.RemoveOption(OptionURIPath)
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment != "" {
+			m.Options = append(m.Options, Option{Number: OptionURIPath, Value: []byte(segment)})
+		}
+	}
+}
+
+// Option returns the first value of an option
+func (m *Message) Option(number int) ([]byte, bool) {
+	for _, o := range m.Options {
+		if o.Number == number {
+			return o.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// UintOption returns the value of an option as unsigned integer
+func (m *Message) UintOption(number int) (uint32, bool) {
+	value, ok := m.Option(number)
+	if !ok {
+		return 0, false
+	}
+
+	var result uint32
+	for _, b := range value {
+		result = result<<8 | uint32(b)
+	}
+
+	return result, true
+}
+
+// SetUintOption sets an option to an unsigned integer value
+func (m *Message) SetUintOption(number int, value uint32) {
+	m.RemoveOption(number)
+
+	var encoded []byte
+	for value > 0 {
+		encoded = append([]byte{byte(value)}, encoded...)
+		value >>= 8
+	}
+
+	m.Options = append(m.Options, Option{Number: number, Value: encoded})
+}
+
+// RemoveOption removes all values of an option
+func (m *Message) RemoveOption(number int) {
+	options := m.Options[:0]
+	for _, o := range m.Options {
+		if o.Number != number {
+			options = append(options, o)
+		}
+	}
+
+	m.Options = options
+}
+
+// Encode serializes the message
+func (m *Message) Encode() []byte {
+	b := []byte{
+		1<<6 | byte(m.Type)<<4 | byte(len(m.Token)),
+		byte(m.Code),
+		0, 0,
+	}
+	binary.BigEndian.PutUint16(b[2:], m.MessageID)
+	b = append(b, m.Token...)
+
+	options := append([]Option{}, m.Options...)
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Number < options[j].Number
+	})
+
+	previous := 0
+	for _, o := range options {
+		delta, deltaExt := encodeOptionNibble(o.Number - previous)
+		length, lengthExt := encodeOptionNibble(len(o.Value))
+
+		b = append(b, delta<<4|length)
+		b = append(b, deltaExt...)
+		b = append(b, lengthExt...)
+		b = append(b, o.Value...)
+
+		previous = o.Number
+	}
+
+	if len(m.Payload) > 0 {
+		b = append(b, 0xff)
+		b = append(b, m.Payload...)
+	}
+
+	return b
+}
+
+func encodeOptionNibble(v int) (byte, []byte) {
+	switch {
+	case v < 13:
+		return byte(v), nil
+	case v < 269:
+		return 13, []byte{byte(v - 13)}
+	}
+
+	ext := make([]byte, 2)
+	binary.BigEndian.PutUint16(ext, uint16(v-269))
+
+	return 14, ext
+}
+
+// Decode parses a message
+func Decode(b []byte) (*Message, error) {
+	if len(b) < 4 || b[0]>>6 != 1 {
+		return nil, errMalformedMessage
+	}
+
+	tokenLength := int(b[0] & 0x0f)
+	if tokenLength > 8 || len(b) < 4+tokenLength {
+		return nil, errMalformedMessage
+	}
+
+	m := &Message{
+		Type:      int(b[0]>>4) & 0x03,
+		Code:      Code(b[1]),
+		MessageID: binary.BigEndian.Uint16(b[2:]),
+		Token:     append([]byte{}, b[4:4+tokenLength]...),
+	}
+
+	b = b[4+tokenLength:]
+	number := 0
+	for len(b) > 0 {
+		if b[0] == 0xff {
+			m.Payload = append([]byte{}, b[1:]...)
+			break
+		}
+
+		delta := int(b[0] >> 4)
+		length := int(b[0] & 0x0f)
+		b = b[1:]
+
+		var err error
+		if delta, b, err = decodeOptionNibble(delta, b); err != nil {
+			return nil, err
+		}
+
+		if length, b, err = decodeOptionNibble(length, b); err != nil {
+			return nil, err
+		}
+
+		if len(b) < length {
+			return nil, errMalformedMessage
+		}
+
+		number += delta
+		m.Options = append(m.Options, Option{Number: number, Value: append([]byte{}, b[:length]...)})
+		b = b[length:]
+	}
+
+	return m, nil
+}
+
+func decodeOptionNibble(v int, b []byte) (int, []byte, error) {
+	switch v {
+	case 13:
+		if len(b) < 1 {
+			return 0, nil, errMalformedMessage
+		}
+
+		return int(b[0]) + 13, b[1:], nil
+	case 14:
+		if len(b) < 2 {
+			return 0, nil, errMalformedMessage
+		}
+
+		return int(binary.BigEndian.Uint16(b)) + 269, b[2:], nil
+	case 15:
+		return 0, nil, errMalformedMessage
+	}
+
+	return v, b, nil
+}
+
+// content formats of well known media types
+var contentFormats = map[string]uint32{
+	"text/plain":               0,
+	"application/link-format":  40,
+	"application/xml":          41,
+	"application/octet-stream": 42,
+	"application/json":         50,
+	"application/cbor":         60,
+}
+
+// ContentFormat returns the content format number of a media type
+func ContentFormat(mediaType string) (uint32, bool) {
+	format, ok := contentFormats[mediaType]
+	return format, ok
+}
+
+// MediaType returns the media type of a content format number
+func MediaType(format uint32) (string, bool) {
+	for mediaType, f := range contentFormats {
+		if f == format {
+			return mediaType, true
+		}
+	}
+
+	return "", false
+}
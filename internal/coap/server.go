@@ -0,0 +1,128 @@
+package coap
+
+import (
+	"net"
+	"sync"
+)
+
+// Handler answers requests. The message type, id and token of the
+// response are set by the server
+type Handler func(req *Message) *Message
+
+// Server is a small coap server supporting observations
+type Server struct {
+	handler Handler
+
+	mutex         sync.Mutex
+	conn          net.PacketConn
+	nextMessageID uint16
+	sequence      uint32
+	observers     map[string]map[string]observer
+}
+
+type observer struct {
+	addr  net.Addr
+	token []byte
+}
+
+// NewServer creates a new server
+func NewServer(handler Handler) *Server {
+	return &Server{
+		handler:   handler,
+		observers: map[string]map[string]observer{},
+	}
+}
+
+// Serve answers requests received by the connection until it is closed
+func (s *Server) Serve(conn net.PacketConn) error {
+	s.mutex.Lock()
+	s.conn = conn
+	s.mutex.Unlock()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		req, err := Decode(buf[:n])
+		if err != nil || req.Code == Empty || req.Code.Class() != 0 {
+			continue
+		}
+
+		s.serve(conn, addr, req)
+	}
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.Close()
+}
+
+// Notify sends a notification to all observers of a path
+func (s *Server) Notify(path string, contentFormat uint32, payload []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sequence++
+	for _, o := range s.observers[path] {
+		msg := &Message{
+			Type:      NonConfirmable,
+			Code:      Content,
+			MessageID: s.nextMessageID,
+			Token:     o.token,
+			Payload:   payload,
+		}
+		s.nextMessageID++
+
+		msg.SetUintOption(OptionObserve, s.sequence)
+		msg.SetUintOption(OptionContentFormat, contentFormat)
+		s.conn.WriteTo(msg.Encode(), o.addr)
+	}
+}
+
+func (s *Server) serve(conn net.PacketConn, addr net.Addr, req *Message) {
+	resp := s.handler(req)
+	if resp == nil {
+		resp = &Message{Code: NotFound}
+	}
+
+	resp.Token = req.Token
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if req.Type == Confirmable {
+		resp.Type = Acknowledgement
+		resp.MessageID = req.MessageID
+	} else {
+		resp.Type = NonConfirmable
+		resp.MessageID = s.nextMessageID
+		s.nextMessageID++
+	}
+
+	key := addr.String() + "/" + string(req.Token)
+	if observe, ok := req.UintOption(OptionObserve); ok && req.Code == GET {
+		path := req.Path()
+		if observe == 0 && resp.Code.Class() == 2 {
+			if s.observers[path] == nil {
+				s.observers[path] = map[string]observer{}
+			}
+
+			s.observers[path][key] = observer{addr: addr, token: req.Token}
+			resp.SetUintOption(OptionObserve, s.sequence)
+		} else if observe == 1 {
+			delete(s.observers[path], key)
+		}
+	}
+
+	conn.WriteTo(resp.Encode(), addr)
+}
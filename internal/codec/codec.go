@@ -0,0 +1,70 @@
+// Package codec serializes values according to content types of forms
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// DefaultContentType is used if a form does not define a content type
+const DefaultContentType = "application/json"
+
+// ErrUnsupportedContentType is returned for content types which can not be serialized
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Marshal serializes a value according to the given content type
+func Marshal(contentType string, value interface{}) ([]byte, error) {
+	switch mediaType(contentType) {
+	case "json":
+		return json.Marshal(value)
+	case "text":
+		if s, ok := value.(string); ok {
+			return []byte(s), nil
+		}
+
+		return []byte(fmt.Sprint(value)), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+}
+
+// Unmarshal deserializes a payload according to the given content type
+func Unmarshal(contentType string, b []byte) (interface{}, error) {
+	switch mediaType(contentType) {
+	case "json":
+		var result interface{}
+		if err := json.Unmarshal(b, &result); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	case "text":
+		return string(b), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+}
+
+// mediaType reduces a content type to the supported serialization
+func mediaType(contentType string) string {
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case parsed == "application/json", strings.HasSuffix(parsed, "+json"):
+		return "json"
+	case parsed == "text/plain":
+		return "text"
+	}
+
+	return ""
+}
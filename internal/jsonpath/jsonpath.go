@@ -0,0 +1,484 @@
+// Package jsonpath implements a subset of JSONPath (RFC 9535) over decoded
+// json values. Supported are the root "$", member access via ".name" and
+// "['name']", array indices, wildcards, recursive descent via ".." and
+// filters like "[?(@.type == 'boolean')]" comparing a relative path with
+// a literal or testing for its existence
+package jsonpath
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError is returned if an expression can't be parsed
+type SyntaxError struct {
+	Offset int
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid jsonpath at offset %d: %s", e.Offset, e.Reason)
+}
+
+// Match is a value selected by a path. Location contains the member
+// names and array indices leading to the value
+type Match struct {
+	Location []interface{}
+	Value    interface{}
+}
+
+// Path is a parsed jsonpath expression
+type Path struct {
+	segments []segment
+}
+
+type segment struct {
+	recursive bool
+	selectors []selector
+}
+
+type selectorKind int
+
+const (
+	selectName selectorKind = iota
+	selectIndex
+	selectWildcard
+	selectFilter
+)
+
+type selector struct {
+	kind   selectorKind
+	name   string
+	index  int
+	filter *filter
+}
+
+// filter compares the values selected by a relative path with a literal.
+// Without operator the filter tests whether the path selects anything
+type filter struct {
+	path     Path
+	operator string
+	literal  interface{}
+}
+
+// Parse parses a jsonpath expression
+func Parse(expression string) (Path, error) {
+	p := &parser{input: expression}
+	p.skipSpace()
+
+	if !p.consume("$") {
+		return Path{}, p.errorf("expression has to start with $")
+	}
+
+	path, err := p.parseSegments()
+	if err != nil {
+		return Path{}, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return Path{}, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+
+	return path, nil
+}
+
+// Evaluate returns all values of the document selected by the path in
+// document order. Members of objects are visited in lexical order
+func (p Path) Evaluate(document interface{}) []Match {
+	matches := []Match{{Value: document}}
+
+	for _, currSegment := range p.segments {
+		var next []Match
+		for _, currMatch := range matches {
+			candidates := []Match{currMatch}
+			if currSegment.recursive {
+				candidates = descendants(currMatch)
+			}
+
+			for _, currCandidate := range candidates {
+				for _, currSelector := range currSegment.selectors {
+					next = append(next, currSelector.apply(currCandidate)...)
+				}
+			}
+		}
+
+		matches = next
+	}
+
+	return matches
+}
+
+// apply returns the children of a match selected by the selector
+func (s selector) apply(m Match) []Match {
+	var result []Match
+
+	switch v := m.Value.(type) {
+	case map[string]interface{}:
+		switch s.kind {
+		case selectName:
+			if value, ok := v[s.name]; ok {
+				result = append(result, child(m, s.name, value))
+			}
+		case selectWildcard, selectFilter:
+			for _, currKey := range sortedKeys(v) {
+				if s.kind == selectWildcard || s.filter.matches(v[currKey]) {
+					result = append(result, child(m, currKey, v[currKey]))
+				}
+			}
+		}
+	case []interface{}:
+		switch s.kind {
+		case selectIndex:
+			index := s.index
+			if index < 0 {
+				index += len(v)
+			}
+
+			if index >= 0 && index < len(v) {
+				result = append(result, child(m, index, v[index]))
+			}
+		case selectWildcard, selectFilter:
+			for i := range v {
+				if s.kind == selectWildcard || s.filter.matches(v[i]) {
+					result = append(result, child(m, i, v[i]))
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// matches checks if a value fulfills the filter
+func (f *filter) matches(value interface{}) bool {
+	matches := f.path.Evaluate(value)
+	if f.operator == "" {
+		return len(matches) > 0
+	}
+
+	for _, currMatch := range matches {
+		if compare(currMatch.Value, f.operator, f.literal) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare compares a value with a literal
+func compare(value interface{}, operator string, literal interface{}) bool {
+	switch operator {
+	case "==":
+		return reflect.DeepEqual(value, literal)
+	case "!=":
+		return !reflect.DeepEqual(value, literal)
+	}
+
+	if a, ok := value.(float64); ok {
+		if b, ok := literal.(float64); ok {
+			return compareOrder(a < b, a == b, operator)
+		}
+	}
+
+	if a, ok := value.(string); ok {
+		if b, ok := literal.(string); ok {
+			return compareOrder(a < b, a == b, operator)
+		}
+	}
+
+	return false
+}
+
+func compareOrder(less bool, equal bool, operator string) bool {
+	switch operator {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+
+	return false
+}
+
+// descendants returns a match and all values nested in it
+func descendants(m Match) []Match {
+	result := []Match{m}
+
+	switch v := m.Value.(type) {
+	case map[string]interface{}:
+		for _, currKey := range sortedKeys(v) {
+			result = append(result, descendants(child(m, currKey, v[currKey]))...)
+		}
+	case []interface{}:
+		for i := range v {
+			result = append(result, descendants(child(m, i, v[i]))...)
+		}
+	}
+
+	return result
+}
+
+func child(m Match, key interface{}, value interface{}) Match {
+	location := make([]interface{}, len(m.Location), len(m.Location)+1)
+	copy(location, m.Location)
+
+	return Match{Location: append(location, key), Value: value}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: p.pos, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+
+	return false
+}
+
+// parseSegments parses segments until the input doesn't continue a path
+func (p *parser) parseSegments() (Path, error) {
+	var path Path
+
+	for p.pos < len(p.input) {
+		var currSegment segment
+
+		switch {
+		case p.consume(".."):
+			currSegment.recursive = true
+			if p.consume("[") {
+				selectors, err := p.parseBracket()
+				if err != nil {
+					return Path{}, err
+				}
+
+				currSegment.selectors = selectors
+			} else {
+				s, err := p.parseDotSelector()
+				if err != nil {
+					return Path{}, err
+				}
+
+				currSegment.selectors = []selector{s}
+			}
+		case p.consume("."):
+			s, err := p.parseDotSelector()
+			if err != nil {
+				return Path{}, err
+			}
+
+			currSegment.selectors = []selector{s}
+		case p.consume("["):
+			selectors, err := p.parseBracket()
+			if err != nil {
+				return Path{}, err
+			}
+
+			currSegment.selectors = selectors
+		default:
+			return path, nil
+		}
+
+		path.segments = append(path.segments, currSegment)
+	}
+
+	return path, nil
+}
+
+// parseDotSelector parses the member name or wildcard following a dot
+func (p *parser) parseDotSelector() (selector, error) {
+	if p.consume("*") {
+		return selector{kind: selectWildcard}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && isNameChar(p.input[p.pos]) {
+		p.pos++
+	}
+
+	if start == p.pos {
+		return selector{}, p.errorf("member name expected")
+	}
+
+	return selector{kind: selectName, name: p.input[start:p.pos]}, nil
+}
+
+// parseBracket parses comma separated selectors up to the closing bracket
+func (p *parser) parseBracket() ([]selector, error) {
+	var selectors []selector
+
+	for {
+		p.skipSpace()
+
+		var s selector
+		switch {
+		case p.consume("*"):
+			s = selector{kind: selectWildcard}
+		case p.consume("?"):
+			f, err := p.parseFilter()
+			if err != nil {
+				return nil, err
+			}
+
+			s = selector{kind: selectFilter, filter: f}
+		case p.pos < len(p.input) && (p.input[p.pos] == '\'' || p.input[p.pos] == '"'):
+			name, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+
+			s = selector{kind: selectName, name: name}
+		default:
+			start := p.pos
+			p.consume("-")
+			for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+				p.pos++
+			}
+
+			index, err := strconv.Atoi(p.input[start:p.pos])
+			if err != nil {
+				p.pos = start
+				return nil, p.errorf("selector expected")
+			}
+
+			s = selector{kind: selectIndex, index: index}
+		}
+
+		selectors = append(selectors, s)
+
+		p.skipSpace()
+		if p.consume("]") {
+			return selectors, nil
+		}
+
+		if !p.consume(",") {
+			return nil, p.errorf("] expected")
+		}
+	}
+}
+
+// parseFilter parses a filter like "(@.type == 'boolean')", the parentheses are optional
+func (p *parser) parseFilter() (*filter, error) {
+	p.skipSpace()
+	parenthesized := p.consume("(")
+	p.skipSpace()
+
+	if !p.consume("@") {
+		return nil, p.errorf("filter has to start with @")
+	}
+
+	path, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &filter{path: path}
+
+	p.skipSpace()
+	for _, currOperator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(currOperator) {
+			f.operator = currOperator
+			break
+		}
+	}
+
+	if f.operator != "" {
+		p.skipSpace()
+		if f.literal, err = p.parseLiteral(); err != nil {
+			return nil, err
+		}
+	}
+
+	p.skipSpace()
+	if parenthesized && !p.consume(")") {
+		return nil, p.errorf(") expected")
+	}
+
+	return f, nil
+}
+
+// parseLiteral parses a string, number, boolean or null
+func (p *parser) parseLiteral() (interface{}, error) {
+	if p.pos < len(p.input) && (p.input[p.pos] == '\'' || p.input[p.pos] == '"') {
+		return p.parseString()
+	}
+
+	for literal, value := range map[string]interface{}{"true": true, "false": false, "null": nil} {
+		if p.consume(literal) {
+			return value, nil
+		}
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && strings.IndexByte("+-.0123456789eE", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+
+	f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("literal expected")
+	}
+
+	return f, nil
+}
+
+// parseString parses a single or double quoted string
+func (p *parser) parseString() (string, error) {
+	quote := p.input[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.input):
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '@' || c == ':' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
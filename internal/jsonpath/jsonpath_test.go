@@ -0,0 +1,83 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testDocument = `{
+	"id": "urn:dev:ops:lamp",
+	"title": "Lamp",
+	"properties": {
+		"on": {"type": "boolean", "forms": [{"href": "on"}]},
+		"brightness": {"type": "integer", "maximum": 100, "forms": [{"href": "brightness"}, {"href": "brightness/observe"}]}
+	},
+	"actions": {
+		"toggle": {"forms": [{"href": "toggle"}]}
+	}
+}`
+
+func TestEvaluate(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(testDocument), &document); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	testCases := []struct {
+		expression string
+		expected   []interface{}
+	}{
+		{"$.title", []interface{}{"Lamp"}},
+		{"$['id']", []interface{}{"urn:dev:ops:lamp"}},
+		{"$.properties.*.type", []interface{}{"integer", "boolean"}},
+		{"$..href", []interface{}{"toggle", "brightness", "brightness/observe", "on"}},
+		{"$.properties.brightness.forms[-1].href", []interface{}{"brightness/observe"}},
+		{"$.properties.brightness.forms[0, 1].href", []interface{}{"brightness", "brightness/observe"}},
+		{"$.properties[?(@.type == 'boolean')].type", []interface{}{"boolean"}},
+		{"$.properties[?(@.maximum >= 100)].maximum", []interface{}{float64(100)}},
+		{"$.properties[?@.maximum].type", []interface{}{"integer"}},
+		{"$..forms[?(@.href != 'on')].href", []interface{}{"toggle", "brightness", "brightness/observe"}},
+		{"$.missing", nil},
+	}
+
+	for _, currTestCase := range testCases {
+		path, err := Parse(currTestCase.expression)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", currTestCase.expression, err)
+		}
+
+		var values []interface{}
+		for _, currMatch := range path.Evaluate(document) {
+			values = append(values, currMatch.Value)
+		}
+
+		if !reflect.DeepEqual(values, currTestCase.expected) {
+			t.Fatalf("Expected %v for %s, got %v", currTestCase.expected, currTestCase.expression, values)
+		}
+	}
+
+	matches := mustEvaluate(t, "$.properties.on.forms[0]", document)
+	if !reflect.DeepEqual(matches[0].Location, []interface{}{"properties", "on", "forms", 0}) {
+		t.Fatalf("Unexpected location %v", matches[0].Location)
+	}
+}
+
+func mustEvaluate(t *testing.T, expression string, document interface{}) []Match {
+	path, err := Parse(expression)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", expression, err)
+	}
+
+	return path.Evaluate(document)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, currExpression := range []string{"", "title", "$.", "$[", "$['title'", "$[?(@.a == )]", "$.a b"} {
+		var syntaxErr *SyntaxError
+		if _, err := Parse(currExpression); !errors.As(err, &syntaxErr) {
+			t.Fatalf("Expected syntax error for %q, got %v", currExpression, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package mqtt
+
+import (
+	"bufio"
+	"net"
+	"sync"
+)
+
+// Broker is a minimal in-process broker. It supports QoS 0 and 1 and
+// retained messages, but neither sessions nor wills
+type Broker struct {
+	mutex    sync.Mutex
+	sessions map[*brokerSession]struct{}
+	retained map[string]Message
+	listener net.Listener
+}
+
+// NewBroker creates a new broker
+func NewBroker() *Broker {
+	return &Broker{
+		sessions: map[*brokerSession]struct{}{},
+		retained: map[string]Message{},
+	}
+}
+
+// Serve accepts connections until the listener is closed
+func (b *Broker) Serve(l net.Listener) error {
+	b.mutex.Lock()
+	b.listener = l
+	b.mutex.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go b.handle(conn)
+	}
+}
+
+// Close stops the broker and disconnects all clients
+func (b *Broker) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for s := range b.sessions {
+		s.conn.Close()
+	}
+
+	if b.listener != nil {
+		return b.listener.Close()
+	}
+
+	return nil
+}
+
+// Retained returns the retained message of a topic
+func (b *Broker) Retained(topic string) (Message, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	msg, ok := b.retained[topic]
+
+	return msg, ok
+}
+
+type brokerSession struct {
+	conn       net.Conn
+	writeMutex sync.Mutex
+	filters    map[string]byte
+}
+
+func (s *brokerSession) write(p packet) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	_, err := s.conn.Write(p.encode())
+
+	return err
+}
+
+func (b *Broker) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	connect, err := readPacket(reader)
+	if err != nil || connect.Type != packetConnect {
+		return
+	}
+
+	s := &brokerSession{conn: conn, filters: map[string]byte{}}
+	if err := s.write(packet{Type: packetConnack, Body: []byte{0, 0}}); err != nil {
+		return
+	}
+
+	b.mutex.Lock()
+	b.sessions[s] = struct{}{}
+	b.mutex.Unlock()
+
+	defer func() {
+		b.mutex.Lock()
+		delete(b.sessions, s)
+		b.mutex.Unlock()
+	}()
+
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch p.Type {
+		case packetPublish:
+			msg, id, err := decodePublish(p)
+			if err != nil {
+				return
+			}
+
+			if msg.QoS > 0 {
+				s.write(packet{Type: packetPuback, Body: appendUint16(nil, id)})
+			}
+
+			b.publish(msg)
+		case packetSubscribe:
+			b.subscribe(s, p)
+		case packetUnsubscribe:
+			d := decoder{body: p.Body}
+			id := d.uint16()
+
+			b.mutex.Lock()
+			for len(d.body) > 0 && d.err == nil {
+				delete(s.filters, d.string())
+			}
+			b.mutex.Unlock()
+
+			s.write(packet{Type: packetUnsuback, Body: appendUint16(nil, id)})
+		case packetPingreq:
+			s.write(packet{Type: packetPingresp})
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func (b *Broker) subscribe(s *brokerSession, p packet) {
+	d := decoder{body: p.Body}
+	id := d.uint16()
+	ack := appendUint16(nil, id)
+
+	var retained []Message
+
+	b.mutex.Lock()
+	for len(d.body) > 0 && d.err == nil {
+		filter := d.string()
+		qos := d.byte() & 0x03
+		if qos > 1 {
+			qos = 1
+		}
+
+		s.filters[filter] = qos
+		ack = append(ack, qos)
+
+		for topic, msg := range b.retained {
+			if Match(filter, topic) {
+				retained = append(retained, msg)
+			}
+		}
+	}
+	b.mutex.Unlock()
+
+	s.write(packet{Type: packetSuback, Body: ack})
+
+	for _, msg := range retained {
+		s.write(publishPacket(msg.Topic, msg.Payload, 0, true, 0))
+	}
+}
+
+func (b *Broker) publish(msg Message) {
+	b.mutex.Lock()
+	if msg.Retain {
+		if len(msg.Payload) == 0 {
+			delete(b.retained, msg.Topic)
+		} else {
+			b.retained[msg.Topic] = msg
+		}
+	}
+
+	var targets []*brokerSession
+	for s := range b.sessions {
+		for filter := range s.filters {
+			if Match(filter, msg.Topic) {
+				targets = append(targets, s)
+				break
+			}
+		}
+	}
+	b.mutex.Unlock()
+
+	for _, s := range targets {
+		// messages are forwarded with QoS 0, so no acknowledgements are needed
+		s.write(publishPacket(msg.Topic, msg.Payload, 0, false, 0))
+	}
+}
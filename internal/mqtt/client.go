@@ -0,0 +1,404 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// well known errors
+var (
+	ErrClosed          = errors.New("mqtt: connection closed")
+	ErrConnectRejected = errors.New("mqtt: connection rejected")
+	ErrSubscribeFailed = errors.New("mqtt: subscription rejected")
+)
+
+// ClientOptions configure a client
+type ClientOptions struct {
+	ClientID string
+	Username string
+	Password string
+	TLS      *tls.Config
+}
+
+// Client is a mqtt client connected to a broker
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+
+	mutex         sync.Mutex
+	nextPacketID  uint16
+	pending       map[uint16]chan packet
+	subscriptions map[string]*subscription
+	err           error
+	done          chan struct{}
+}
+
+// Dial connects to the broker at the given address (host:port)
+func Dial(ctx context.Context, addr string, opts ClientOptions) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TLS != nil {
+		tlsConn := tls.Client(conn, opts.TLS)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		conn = tlsConn
+	}
+
+	c := &Client{
+		conn:          conn,
+		reader:        bufio.NewReader(conn),
+		pending:       map[uint16]chan packet{},
+		subscriptions: map[string]*subscription{},
+		done:          make(chan struct{}),
+	}
+
+	connectDone := make(chan struct{})
+	defer close(connectDone)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-connectDone:
+		}
+	}()
+
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, err
+	}
+
+	go c.readLoop()
+
+	return c, nil
+}
+
+func (c *Client) connect(opts ClientOptions) error {
+	var flags byte = 0x02 // clean session
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+
+	if opts.Username != "" {
+		flags |= 0x80
+	}
+
+	if opts.Password != "" {
+		flags |= 0x40
+	}
+
+	body = append(body, flags)
+	body = appendUint16(body, 0) // no keep alive
+	body = appendString(body, opts.ClientID)
+
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+	}
+
+	if opts.Password != "" {
+		body = appendString(body, opts.Password)
+	}
+
+	if err := c.write(packet{Type: packetConnect, Body: body}); err != nil {
+		return err
+	}
+
+	ack, err := readPacket(c.reader)
+	if err != nil {
+		return err
+	}
+
+	if ack.Type != packetConnack || len(ack.Body) != 2 {
+		return errMalformedPacket
+	}
+
+	if ack.Body[1] != 0 {
+		return fmt.Errorf("%w: return code %d", ErrConnectRejected, ack.Body[1])
+	}
+
+	return nil
+}
+
+// Publish publishes a message. With QoS 1 it blocks until the broker
+// acknowledged the message. QoS 2 is not supported
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+	if qos > 1 {
+		qos = 1
+	}
+
+	if qos == 0 {
+		return c.write(publishPacket(topic, payload, 0, retain, 0))
+	}
+
+	id, ack := c.register()
+	defer c.unregister(id)
+
+	if err := c.write(publishPacket(topic, payload, qos, retain, id)); err != nil {
+		return err
+	}
+
+	_, err := c.await(ctx, ack)
+
+	return err
+}
+
+// Subscribe subscribes to a topic filter. Messages matching the filter are
+// delivered on the returned channel which is closed once the subscription
+// ends or the connection is lost
+func (c *Client) Subscribe(ctx context.Context, filter string, qos byte) (<-chan Message, error) {
+	if qos > 1 {
+		qos = 1
+	}
+
+	sub := newSubscription()
+
+	c.mutex.Lock()
+	if c.err != nil {
+		c.mutex.Unlock()
+		return nil, c.err
+	}
+
+	if existing, ok := c.subscriptions[filter]; ok {
+		existing.close()
+	}
+
+	c.subscriptions[filter] = sub
+	c.mutex.Unlock()
+
+	id, ack := c.register()
+	defer c.unregister(id)
+
+	body := appendUint16(nil, id)
+	body = appendString(body, filter)
+	body = append(body, qos)
+
+	err := c.write(packet{Type: packetSubscribe, Flags: 0x02, Body: body})
+	if err == nil {
+		var resp packet
+		resp, err = c.await(ctx, ack)
+		if err == nil && (len(resp.Body) != 3 || resp.Body[2] == 0x80) {
+			err = ErrSubscribeFailed
+		}
+	}
+
+	if err != nil {
+		c.removeSubscription(filter)
+		return nil, err
+	}
+
+	return sub.messages, nil
+}
+
+// Unsubscribe ends a subscription
+func (c *Client) Unsubscribe(ctx context.Context, filter string) error {
+	id, ack := c.register()
+	defer c.unregister(id)
+
+	body := appendUint16(nil, id)
+	body = appendString(body, filter)
+
+	err := c.write(packet{Type: packetUnsubscribe, Flags: 0x02, Body: body})
+	if err == nil {
+		_, err = c.await(ctx, ack)
+	}
+
+	c.removeSubscription(filter)
+
+	return err
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	c.write(packet{Type: packetDisconnect})
+	return c.conn.Close()
+}
+
+// Done is closed once the connection is lost
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason why the connection was lost
+func (c *Client) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
+
+func (c *Client) readLoop() {
+	var err error
+	for {
+		var p packet
+		p, err = readPacket(c.reader)
+		if err != nil {
+			break
+		}
+
+		switch p.Type {
+		case packetPublish:
+			var msg Message
+			var id uint16
+			msg, id, err = decodePublish(p)
+			if err != nil {
+				break
+			}
+
+			if msg.QoS > 0 {
+				c.write(packet{Type: packetPuback, Body: appendUint16(nil, id)})
+			}
+
+			c.dispatch(msg)
+		case packetPuback, packetSuback, packetUnsuback:
+			d := decoder{body: p.Body}
+			id := d.uint16()
+
+			c.mutex.Lock()
+			if ack, ok := c.pending[id]; ok {
+				ack <- p
+			}
+			c.mutex.Unlock()
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	c.conn.Close()
+
+	c.mutex.Lock()
+	c.err = ErrClosed
+	for filter, sub := range c.subscriptions {
+		sub.close()
+		delete(c.subscriptions, filter)
+	}
+	c.mutex.Unlock()
+
+	close(c.done)
+}
+
+func (c *Client) dispatch(msg Message) {
+	c.mutex.Lock()
+	var targets []*subscription
+	for filter, sub := range c.subscriptions {
+		if Match(filter, msg.Topic) {
+			targets = append(targets, sub)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, sub := range targets {
+		sub.deliver(msg)
+	}
+}
+
+func (c *Client) removeSubscription(filter string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if sub, ok := c.subscriptions[filter]; ok {
+		sub.close()
+		delete(c.subscriptions, filter)
+	}
+}
+
+// subscription delivers messages to a channel until it is closed
+type subscription struct {
+	messages  chan Message
+	done      chan struct{}
+	mutex     sync.Mutex
+	closeOnce sync.Once
+}
+
+func newSubscription() *subscription {
+	return &subscription{
+		messages: make(chan Message, 16),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *subscription) deliver(msg Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	select {
+	case s.messages <- msg:
+	case <-s.done:
+	}
+}
+
+func (s *subscription) close() {
+	s.closeOnce.Do(func() {
+		// unblock pending deliveries before closing the channel
+		close(s.done)
+
+		s.mutex.Lock()
+		close(s.messages)
+		s.mutex.Unlock()
+	})
+}
+
+func (c *Client) register() (uint16, chan packet) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.nextPacketID++
+	if c.nextPacketID == 0 {
+		c.nextPacketID = 1
+	}
+
+	ack := make(chan packet, 1)
+	c.pending[c.nextPacketID] = ack
+
+	return c.nextPacketID, ack
+}
+
+func (c *Client) unregister(id uint16) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.pending, id)
+}
+
+func (c *Client) await(ctx context.Context, ack chan packet) (packet, error) {
+	select {
+	case p := <-ack:
+		return p, nil
+	case <-c.done:
+		return packet{}, ErrClosed
+	case <-ctx.Done():
+		return packet{}, ctx.Err()
+	}
+}
+
+func (c *Client) write(p packet) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	_, err := c.conn.Write(p.encode())
+
+	return err
+}
@@ -0,0 +1,88 @@
+package mqtt
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func startBroker(t *testing.T) (*Broker, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	broker := NewBroker()
+	go broker.Serve(l)
+
+	return broker, l.Addr().String()
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	broker, addr := startBroker(t)
+	defer broker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := Dial(ctx, addr, ClientOptions{ClientID: "test"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Publish(ctx, "lamp/brightness", []byte("50"), 1, true); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	messages, err := client.Subscribe(ctx, "lamp/+", 1)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	// retained message is delivered first
+	if msg := <-messages; string(msg.Payload) != "50" || !msg.Retain {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+
+	if err := client.Publish(ctx, "lamp/color", []byte("red"), 0, false); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	if msg := <-messages; string(msg.Payload) != "red" || msg.Topic != "lamp/color" {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+
+	if err := client.Unsubscribe(ctx, "lamp/+"); err != nil {
+		t.Fatalf("Failed to unsubscribe: %v", err)
+	}
+
+	if _, ok := <-messages; ok {
+		t.Fatalf("Expected channel to be closed")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var matchTests = []struct {
+		Filter string
+		Topic  string
+		Match  bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a", true},
+		{"#", "a/b", true},
+		{"+/b", "a/b", true},
+		{"a/b/c", "a/b", false},
+	}
+
+	for _, currTest := range matchTests {
+		if Match(currTest.Filter, currTest.Topic) != currTest.Match {
+			t.Errorf("Match(%s, %s) should be %t", currTest.Filter, currTest.Topic, currTest.Match)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package mqtt implements the parts of MQTT 3.1.1 needed by the protocol
+// bindings of this module: a client and a small in-process broker
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// control packet types
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+const maxRemainingLength = 268435455
+
+var errMalformedPacket = errors.New("mqtt: malformed packet")
+
+// packet is a raw control packet
+type packet struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	var length, multiplier int = 0, 1
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+
+		multiplier *= 128
+		if i == 3 {
+			return packet{}, errMalformedPacket
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+
+	return packet{Type: header >> 4, Flags: header & 0x0f, Body: body}, nil
+}
+
+func (p packet) encode() []byte {
+	result := []byte{p.Type<<4 | p.Flags}
+
+	length := len(p.Body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+
+		result = append(result, b)
+		if length == 0 {
+			break
+		}
+	}
+
+	return append(result, p.Body...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// decoder reads fields from the body of a packet
+type decoder struct {
+	body []byte
+	err  error
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.body) < 2 {
+		d.err = errMalformedPacket
+		return 0
+	}
+
+	v := binary.BigEndian.Uint16(d.body)
+	d.body = d.body[2:]
+
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.body) < 1 {
+		d.err = errMalformedPacket
+		return 0
+	}
+
+	v := d.body[0]
+	d.body = d.body[1:]
+
+	return v
+}
+
+func (d *decoder) string() string {
+	length := int(d.uint16())
+	if d.err != nil || len(d.body) < length {
+		d.err = errMalformedPacket
+		return ""
+	}
+
+	v := string(d.body[:length])
+	d.body = d.body[length:]
+
+	return v
+}
+
+func (d *decoder) rest() []byte {
+	v := d.body
+	d.body = nil
+
+	return v
+}
+
+// publishPacket builds a publish packet
+func publishPacket(topic string, payload []byte, qos byte, retain bool, packetID uint16) packet {
+	flags := qos << 1
+	if retain {
+		flags |= 1
+	}
+
+	body := appendString(nil, topic)
+	if qos > 0 {
+		body = appendUint16(body, packetID)
+	}
+
+	return packet{Type: packetPublish, Flags: flags, Body: append(body, payload...)}
+}
+
+// Message is an application message
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+func decodePublish(p packet) (Message, uint16, error) {
+	d := decoder{body: p.Body}
+	msg := Message{
+		Topic:  d.string(),
+		QoS:    (p.Flags >> 1) & 0x03,
+		Retain: p.Flags&1 == 1,
+	}
+
+	var packetID uint16
+	if msg.QoS > 0 {
+		packetID = d.uint16()
+	}
+
+	msg.Payload = d.rest()
+
+	return msg, packetID, d.err
+}
+
+// Match checks if a topic matches a topic filter which may contain wildcards
+func Match(filter, topic string) bool {
+	for {
+		filterLevel, filterRest, filterMore := cut(filter)
+		topicLevel, topicRest, topicMore := cut(topic)
+
+		switch {
+		case filterLevel == "#":
+			return true
+		case filterLevel != "+" && filterLevel != topicLevel:
+			return false
+		case !filterMore || !topicMore:
+			// '#' also matches the parent level
+			return filterMore == topicMore || (filterMore && filterRest == "#")
+		}
+
+		filter, topic = filterRest, topicRest
+	}
+}
+
+func cut(s string) (string, string, bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' {
+			return s[:i], s[i+1:], true
+		}
+	}
+
+	return s, "", false
+}
@@ -0,0 +1,78 @@
+// Package payload handles the methods and payloads of http requests for forms
+package payload
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/codec"
+)
+
+// default http methods of operation types as defined by the http protocol
+// binding, keyed by the terms of the operation types
+var defaultMethods = map[string]string{
+	"readproperty":    http.MethodGet,
+	"writeproperty":   http.MethodPut,
+	"observeproperty": http.MethodGet,
+	"invokeaction":    http.MethodPost,
+	"subscribeevent":  http.MethodGet,
+}
+
+// DefaultMethod returns the default http method of an operation type given
+// by its term like "readproperty" or its iri
+func DefaultMethod(op string) (string, bool) {
+	method, ok := defaultMethods[strings.ToLower(strings.TrimPrefix(op, wotlib.SchemaWoT.IRI))]
+	return method, ok
+}
+
+// Read decodes and validates the body of a request. Bodies without content
+// type header are decoded with the content type of the form. On failure an
+// error response is written and false is returned
+func Read(w http.ResponseWriter, r *http.Request, contentType string, schema wotlib.ExpandedDataSchema) (interface{}, bool) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	var value interface{}
+	if len(body) > 0 {
+		if header := r.Header.Get("Content-Type"); header != "" {
+			contentType = header
+		}
+
+		value, err = codec.Unmarshal(contentType, body)
+		if errors.Is(err, codec.ErrUnsupportedContentType) {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return nil, false
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	if err := schema.Validate(value); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Write encodes a value with the content type and writes it as response
+func Write(w http.ResponseWriter, contentType string, status int, value interface{}) {
+	body, err := codec.Marshal(contentType, value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,55 @@
+// Package retry helps clients retrying requests and reconnecting streams
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MaxDelay limits the delay growing with each failed attempt
+const MaxDelay = 30 * time.Second
+
+// minDelay is the delay following failed attempts without delay
+const minDelay = 10 * time.Millisecond
+
+// Backoff returns the delay after another failed attempt. The delay is
+// doubled up to MaxDelay
+func Backoff(delay time.Duration) time.Duration {
+	switch delay *= 2; {
+	case delay < minDelay:
+		return minDelay
+	case delay > MaxDelay:
+		return MaxDelay
+	}
+
+	return delay
+}
+
+// Sleep waits for the delay and returns false if the context is done before
+func Sleep(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Temporary checks if an error is worth retrying. Errors of the transport
+// are retried, as well as server errors, but not canceled contexts
+func Temporary(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTemporary(t *testing.T) {
+	tests := []struct {
+		err       error
+		temporary bool
+	}{
+		{errors.New("connection refused"), true},
+		{fmt.Errorf("request failed: %w", context.Canceled), false},
+		{context.DeadlineExceeded, false},
+		{&net.DNSError{Err: "no such host", IsTemporary: false}, false},
+		{&net.DNSError{Err: "timeout", IsTemporary: true}, true},
+	}
+
+	for i, currTest := range tests {
+		if Temporary(currTest.err) != currTest.temporary {
+			t.Fatalf("Test %d: expected temporary to be %v for %v", i, currTest.temporary, currTest.err)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		delay    time.Duration
+		expected time.Duration
+	}{
+		{0, minDelay},
+		{time.Millisecond, minDelay},
+		{time.Second, 2 * time.Second},
+		{20 * time.Second, MaxDelay},
+	}
+
+	for i, currTest := range tests {
+		if delay := Backoff(currTest.delay); delay != currTest.expected {
+			t.Fatalf("Test %d: expected %s, got %s", i, currTest.expected, delay)
+		}
+	}
+}
+
+func TestSleep(t *testing.T) {
+	if !Sleep(context.Background(), time.Millisecond) {
+		t.Fatalf("Expected sleep to complete")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if Sleep(ctx, time.Hour) {
+		t.Fatalf("Expected sleep to stop with the context")
+	}
+}
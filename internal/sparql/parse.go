@@ -0,0 +1,486 @@
+package sparql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenKeyword
+	tokenIRI
+	tokenPrefixedName
+	tokenVariable
+	tokenString
+	tokenNumber
+	tokenPunctuation
+)
+
+type token struct {
+	kind   tokenKind
+	value  string
+	offset int
+}
+
+// Parse parses a select query
+func Parse(query string) (*Query, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, prefixes: map[string]string{}}
+
+	return p.parseQuery()
+}
+
+type parser struct {
+	tokens   []token
+	pos      int
+	prefixes map[string]string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Offset: t.offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+// keyword consumes the next token if it is the given case insensitive keyword
+func (p *parser) keyword(keyword string) bool {
+	t := p.peek()
+	if t.kind == tokenKeyword && strings.EqualFold(t.value, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// punctuation consumes the next token if it is the given punctuation
+func (p *parser) punctuation(punctuation string) bool {
+	t := p.peek()
+	if t.kind == tokenPunctuation && t.value == punctuation {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	for p.keyword("PREFIX") {
+		name := p.next()
+		if name.kind != tokenPrefixedName || !strings.HasSuffix(name.value, ":") {
+			return nil, p.errorf(name, "prefix name expected")
+		}
+
+		iri := p.next()
+		if iri.kind != tokenIRI {
+			return nil, p.errorf(iri, "iri expected")
+		}
+
+		p.prefixes[strings.TrimSuffix(name.value, ":")] = iri.value
+	}
+
+	if !p.keyword("SELECT") {
+		return nil, p.errorf(p.peek(), "SELECT expected")
+	}
+
+	q := &Query{Limit: -1}
+	q.Distinct = p.keyword("DISTINCT")
+
+	all := p.punctuation("*")
+	for !all && p.peek().kind == tokenVariable {
+		q.Variables = append(q.Variables, p.next().value)
+	}
+
+	if !all && len(q.Variables) == 0 {
+		return nil, p.errorf(p.peek(), "variables or * expected")
+	}
+
+	p.keyword("WHERE")
+	if !p.punctuation("{") {
+		return nil, p.errorf(p.peek(), "{ expected")
+	}
+
+	seen := map[string]bool{}
+	var appearance []string
+	for !p.punctuation("}") {
+		if p.keyword("FILTER") {
+			f, err := p.parseFilter()
+			if err != nil {
+				return nil, err
+			}
+
+			q.filters = append(q.filters, f)
+			p.punctuation(".")
+			continue
+		}
+
+		patterns, err := p.parseTriples()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, currPattern := range patterns {
+			for _, currNode := range []node{currPattern.subject, currPattern.predicate, currPattern.object} {
+				// blank nodes of patterns are variables which can't be selected
+				if currNode.variable != "" && !strings.HasPrefix(currNode.variable, "_:") && !seen[currNode.variable] {
+					seen[currNode.variable] = true
+					appearance = append(appearance, currNode.variable)
+				}
+			}
+		}
+
+		q.patterns = append(q.patterns, patterns...)
+	}
+
+	if all {
+		q.Variables = appearance
+	}
+
+	for {
+		switch {
+		case p.keyword("LIMIT"):
+			limit, err := p.parseInteger()
+			if err != nil {
+				return nil, err
+			}
+
+			q.Limit = limit
+			continue
+		case p.keyword("OFFSET"):
+			offset, err := p.parseInteger()
+			if err != nil {
+				return nil, err
+			}
+
+			q.Offset = offset
+			continue
+		}
+
+		break
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.value)
+	}
+
+	return q, nil
+}
+
+// parseTriples parses a subject with its predicate object list up to the next "." or "}"
+func (p *parser) parseTriples() ([]pattern, error) {
+	subject, err := p.parseNode(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []pattern
+	for {
+		predicate, err := p.parseNode(true)
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			object, err := p.parseNode(false)
+			if err != nil {
+				return nil, err
+			}
+
+			patterns = append(patterns, pattern{subject: subject, predicate: predicate, object: object})
+
+			if !p.punctuation(",") {
+				break
+			}
+		}
+
+		if !p.punctuation(";") {
+			break
+		}
+
+		// a trailing ";" may end the predicate object list
+		if t := p.peek(); t.kind == tokenPunctuation && (t.value == "." || t.value == "}") {
+			break
+		}
+	}
+
+	if !p.punctuation(".") {
+		if t := p.peek(); t.kind != tokenPunctuation || t.value != "}" {
+			return nil, p.errorf(t, ". or } expected")
+		}
+	}
+
+	return patterns, nil
+}
+
+// parseFilter parses a comparison like "(?type = <iri>)"
+func (p *parser) parseFilter() (filter, error) {
+	if !p.punctuation("(") {
+		return filter{}, p.errorf(p.peek(), "( expected")
+	}
+
+	left, err := p.parseNode(false)
+	if err != nil {
+		return filter{}, err
+	}
+
+	f := filter{left: left}
+	switch {
+	case p.punctuation("="):
+		f.operator = "="
+	case p.punctuation("!="):
+		f.operator = "!="
+	default:
+		return filter{}, p.errorf(p.peek(), "= or != expected")
+	}
+
+	if f.right, err = p.parseNode(false); err != nil {
+		return filter{}, err
+	}
+
+	if !p.punctuation(")") {
+		return filter{}, p.errorf(p.peek(), ") expected")
+	}
+
+	return f, nil
+}
+
+// parseNode parses a variable or term. The keyword "a" is accepted as predicate
+func (p *parser) parseNode(predicate bool) (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenVariable:
+		return node{variable: t.value}, nil
+	case tokenIRI:
+		return node{term: Term{Kind: IRI, Value: t.value}}, nil
+	case tokenPrefixedName:
+		iri, err := p.expand(t)
+		if err != nil {
+			return node{}, err
+		}
+
+		return node{term: Term{Kind: IRI, Value: iri}}, nil
+	case tokenKeyword:
+		switch {
+		case predicate && t.value == "a":
+			return node{term: Term{Kind: IRI, Value: RDFType}}, nil
+		case !predicate && (t.value == "true" || t.value == "false"):
+			return node{term: Term{Kind: Literal, Value: t.value, Datatype: XSDBoolean}}, nil
+		}
+	case tokenString:
+		if predicate {
+			break
+		}
+
+		return p.parseLiteral(t)
+	case tokenNumber:
+		if predicate {
+			break
+		}
+
+		datatype := XSDInteger
+		if strings.Contains(t.value, ".") {
+			datatype = XSDDecimal
+		}
+
+		return node{term: Term{Kind: Literal, Value: t.value, Datatype: datatype}}, nil
+	case tokenPunctuation:
+		if strings.HasPrefix(t.value, "_:") && !predicate {
+			return node{variable: t.value}, nil
+		}
+	}
+
+	return node{}, p.errorf(t, "unexpected %q", t.value)
+}
+
+// parseLiteral parses the optional language tag or datatype of a string
+func (p *parser) parseLiteral(t token) (node, error) {
+	term := Term{Kind: Literal, Value: t.value, Datatype: XSDString}
+
+	switch {
+	case p.punctuation("@"):
+		language := p.next()
+		if language.kind != tokenKeyword {
+			return node{}, p.errorf(language, "language tag expected")
+		}
+
+		term.Language = language.value
+		term.Datatype = RDFLangString
+	case p.punctuation("^^"):
+		datatype, err := p.parseNode(false)
+		if err != nil || datatype.variable != "" || datatype.term.Kind != IRI {
+			return node{}, p.errorf(t, "datatype iri expected")
+		}
+
+		term.Datatype = datatype.term.Value
+	}
+
+	return node{term: term}, nil
+}
+
+func (p *parser) parseInteger() (int, error) {
+	t := p.next()
+
+	i, err := strconv.Atoi(t.value)
+	if t.kind != tokenNumber || err != nil || i < 0 {
+		return 0, p.errorf(t, "non negative integer expected")
+	}
+
+	return i, nil
+}
+
+// expand expands a prefixed name using the declared prefixes
+func (p *parser) expand(t token) (string, error) {
+	i := strings.Index(t.value, ":")
+
+	iri, ok := p.prefixes[t.value[:i]]
+	if !ok {
+		return "", p.errorf(t, "undeclared prefix %q", t.value[:i])
+	}
+
+	return iri + t.value[i+1:], nil
+}
+
+// tokenize splits a query into tokens
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+			continue
+		case c == '<':
+			end := indexRune(runes, i+1, '>')
+			if end < 0 || strings.ContainsAny(string(runes[i+1:end]), " \t\n") {
+				return nil, &SyntaxError{Offset: i, Reason: "unterminated iri"}
+			}
+
+			tokens = append(tokens, token{kind: tokenIRI, value: string(runes[i+1 : end]), offset: start})
+			i = end + 1
+		case c == '?' || c == '$':
+			i++
+			for i < len(runes) && isNameRune(runes[i]) {
+				i++
+			}
+
+			if i == start+1 {
+				return nil, &SyntaxError{Offset: start, Reason: "variable name expected"}
+			}
+
+			tokens = append(tokens, token{kind: tokenVariable, value: string(runes[start+1 : i]), offset: start})
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			i++
+			for i < len(runes) && runes[i] != c {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					b.WriteRune(unescape(runes[i]))
+				} else {
+					b.WriteRune(runes[i])
+				}
+
+				i++
+			}
+
+			if i >= len(runes) {
+				return nil, &SyntaxError{Offset: start, Reason: "unterminated string"}
+			}
+
+			i++
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), offset: start})
+		case c >= '0' && c <= '9' || (c == '-' || c == '+') && i+1 < len(runes) && runes[i+1] >= '0' && runes[i+1] <= '9':
+			i++
+			for i < len(runes) && (runes[i] >= '0' && runes[i] <= '9' || runes[i] == '.' && i+1 < len(runes) && runes[i+1] >= '0' && runes[i+1] <= '9') {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[start:i]), offset: start})
+		case c == '_' && i+1 < len(runes) && runes[i+1] == ':':
+			i += 2
+			for i < len(runes) && isNameRune(runes[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenPunctuation, value: string(runes[start:i]), offset: start})
+		case isNameRune(c) || c == ':':
+			for i < len(runes) && (isNameRune(runes[i]) || runes[i] == ':' || runes[i] == '.' && i+1 < len(runes) && isNameRune(runes[i+1])) {
+				i++
+			}
+
+			value := string(runes[start:i])
+			kind := tokenKeyword
+			if strings.Contains(value, ":") {
+				kind = tokenPrefixedName
+			}
+
+			tokens = append(tokens, token{kind: kind, value: value, offset: start})
+		case c == '!' && i+1 < len(runes) && runes[i+1] == '=', c == '^' && i+1 < len(runes) && runes[i+1] == '^':
+			tokens = append(tokens, token{kind: tokenPunctuation, value: string(runes[i : i+2]), offset: start})
+			i += 2
+		case strings.ContainsRune("{}().;,*=@", c):
+			tokens = append(tokens, token{kind: tokenPunctuation, value: string(c), offset: start})
+			i++
+		default:
+			return nil, &SyntaxError{Offset: i, Reason: "unexpected " + strconv.QuoteRune(c)}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, offset: len(runes)}), nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func unescape(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	}
+
+	return r
+}
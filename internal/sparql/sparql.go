@@ -0,0 +1,243 @@
+// Package sparql implements a subset of SPARQL 1.1 select queries over a
+// list of rdf triples. Supported are PREFIX declarations, SELECT with
+// DISTINCT, basic graph patterns including the ";" and "," shorthands and
+// the "a" keyword, FILTER comparisons with = and != as well as LIMIT
+// and OFFSET
+package sparql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// datatypes of literals without explicit datatype
+const (
+	XSDString     = "http://www.w3.org/2001/XMLSchema#string"
+	XSDInteger    = "http://www.w3.org/2001/XMLSchema#integer"
+	XSDDecimal    = "http://www.w3.org/2001/XMLSchema#decimal"
+	XSDBoolean    = "http://www.w3.org/2001/XMLSchema#boolean"
+	RDFLangString = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
+	RDFType       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+)
+
+// TermKind is the kind of an rdf term
+type TermKind int
+
+// kinds of rdf terms
+const (
+	IRI TermKind = iota
+	BlankNode
+	Literal
+)
+
+// Term is an rdf term
+type Term struct {
+	Kind     TermKind
+	Value    string
+	Datatype string
+	Language string
+}
+
+// String formats the term like in n-triples
+func (t Term) String() string {
+	switch t.Kind {
+	case BlankNode:
+		return "_:" + strings.TrimPrefix(t.Value, "_:")
+	case Literal:
+		s := strconv.Quote(t.Value)
+		if t.Language != "" {
+			return s + "@" + t.Language
+		}
+
+		if t.Datatype != "" && t.Datatype != XSDString {
+			return s + "^^<" + t.Datatype + ">"
+		}
+
+		return s
+	}
+
+	return "<" + t.Value + ">"
+}
+
+// Triple is an rdf triple
+type Triple struct {
+	Subject   Term
+	Predicate Term
+	Object    Term
+}
+
+// Solution binds the selected variables of a query to terms
+type Solution map[string]Term
+
+// SyntaxError is returned if a query can't be parsed
+type SyntaxError struct {
+	Offset int
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid sparql query at offset %d: %s", e.Offset, e.Reason)
+}
+
+// Query is a parsed select query
+type Query struct {
+	// Variables are the selected variables without "?". All variables
+	// of the pattern in order of appearance for "SELECT *"
+	Variables []string
+	Distinct  bool
+	// Limit is the maximum number of solutions, -1 if unlimited
+	Limit  int
+	Offset int
+
+	patterns []pattern
+	filters  []filter
+}
+
+// node is a term or a variable of a pattern
+type node struct {
+	variable string
+	term     Term
+}
+
+type pattern struct {
+	subject   node
+	predicate node
+	object    node
+}
+
+type filter struct {
+	left     node
+	operator string
+	right    node
+}
+
+// Evaluate returns all solutions of the query over the triples
+func (q *Query) Evaluate(triples []Triple) []Solution {
+	var solutions []Solution
+	seen := map[string]bool{}
+	skipped := 0
+
+	var match func(i int, bindings map[string]Term) bool
+	match = func(i int, bindings map[string]Term) bool {
+		if i == len(q.patterns) {
+			for _, currFilter := range q.filters {
+				if !currFilter.holds(bindings) {
+					return true
+				}
+			}
+
+			solution := Solution{}
+			var key strings.Builder
+			for _, currVariable := range q.Variables {
+				if term, ok := bindings[currVariable]; ok {
+					solution[currVariable] = term
+					key.WriteString(term.String())
+				}
+
+				key.WriteByte(' ')
+			}
+
+			if q.Distinct {
+				if seen[key.String()] {
+					return true
+				}
+
+				seen[key.String()] = true
+			}
+
+			if skipped < q.Offset {
+				skipped++
+				return true
+			}
+
+			solutions = append(solutions, solution)
+
+			return q.Limit < 0 || len(solutions) < q.Limit
+		}
+
+		p := q.patterns[i]
+		for _, currTriple := range triples {
+			next, ok := p.match(currTriple, bindings)
+			if ok && !match(i+1, next) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if q.Limit != 0 {
+		match(0, map[string]Term{})
+	}
+
+	return solutions
+}
+
+// match binds the variables of the pattern to the terms of the triple
+func (p pattern) match(t Triple, bindings map[string]Term) (map[string]Term, bool) {
+	result := bindings
+	copied := false
+
+	for i, currNode := range []node{p.subject, p.predicate, p.object} {
+		term := []Term{t.Subject, t.Predicate, t.Object}[i]
+
+		if currNode.variable == "" {
+			if currNode.term != term {
+				return nil, false
+			}
+
+			continue
+		}
+
+		if bound, ok := result[currNode.variable]; ok {
+			if bound != term {
+				return nil, false
+			}
+
+			continue
+		}
+
+		if !copied {
+			result = make(map[string]Term, len(bindings)+3)
+			for key, value := range bindings {
+				result[key] = value
+			}
+
+			copied = true
+		}
+
+		result[currNode.variable] = term
+	}
+
+	return result, true
+}
+
+// holds evaluates the filter. Comparisons with unbound variables are false
+func (f filter) holds(bindings map[string]Term) bool {
+	left, ok := f.left.resolve(bindings)
+	if !ok {
+		return false
+	}
+
+	right, ok := f.right.resolve(bindings)
+	if !ok {
+		return false
+	}
+
+	if f.operator == "!=" {
+		return left != right
+	}
+
+	return left == right
+}
+
+func (n node) resolve(bindings map[string]Term) (Term, bool) {
+	if n.variable == "" {
+		return n.term, true
+	}
+
+	term, ok := bindings[n.variable]
+
+	return term, ok
+}
@@ -0,0 +1,100 @@
+package sparql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const td = "https://www.w3.org/2019/wot/td#"
+
+func iri(value string) Term {
+	return Term{Kind: IRI, Value: value}
+}
+
+func literal(value string) Term {
+	return Term{Kind: Literal, Value: value, Datatype: XSDString}
+}
+
+var testTriples = []Triple{
+	{iri("urn:lamp"), iri(RDFType), iri("http://iotschema.org/Light")},
+	{iri("urn:lamp"), iri(td + "name"), literal("Lamp")},
+	{iri("urn:lamp"), iri(td + "hasPropertyAffordance"), Term{Kind: BlankNode, Value: "_:b0"}},
+	{iri("urn:lamp"), iri(td + "hasPropertyAffordance"), Term{Kind: BlankNode, Value: "_:b1"}},
+	{Term{Kind: BlankNode, Value: "_:b0"}, iri(td + "name"), literal("on")},
+	{Term{Kind: BlankNode, Value: "_:b1"}, iri(td + "name"), literal("brightness")},
+	{Term{Kind: BlankNode, Value: "_:b1"}, iri(RDFType), iri("http://iotschema.org/Brightness")},
+	{iri("urn:switch"), iri(RDFType), iri("http://iotschema.org/Switch")},
+	{iri("urn:switch"), iri(td + "name"), literal("Switch")},
+}
+
+func TestEvaluate(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected []Solution
+	}{
+		{
+			`PREFIX iot: <http://iotschema.org/> SELECT ?thing WHERE { ?thing a iot:Light }`,
+			[]Solution{{"thing": iri("urn:lamp")}},
+		},
+		{
+			`PREFIX td: <https://www.w3.org/2019/wot/td#>
+			PREFIX iot: <http://iotschema.org/>
+			SELECT ?name WHERE {
+				?thing td:hasPropertyAffordance ?p .
+				?p td:name ?name ;
+				   a iot:Brightness .
+			}`,
+			[]Solution{{"name": literal("brightness")}},
+		},
+		{
+			`PREFIX td: <https://www.w3.org/2019/wot/td#>
+			SELECT * WHERE { ?thing td:name ?name . FILTER(?name != "Lamp") } LIMIT 1`,
+			[]Solution{{"thing": Term{Kind: BlankNode, Value: "_:b0"}, "name": literal("on")}},
+		},
+		{
+			`SELECT ?name WHERE { <urn:lamp> <https://www.w3.org/2019/wot/td#hasPropertyAffordance> _:p . _:p <https://www.w3.org/2019/wot/td#name> ?name } OFFSET 1`,
+			[]Solution{{"name": literal("brightness")}},
+		},
+		{
+			`SELECT DISTINCT ?thing WHERE { ?thing <https://www.w3.org/2019/wot/td#hasPropertyAffordance> ?p }`,
+			[]Solution{{"thing": iri("urn:lamp")}},
+		},
+		{
+			`SELECT ?thing WHERE { ?thing <https://www.w3.org/2019/wot/td#name> "Lamp"@en }`,
+			nil,
+		},
+	}
+
+	for _, currTestCase := range testCases {
+		q, err := Parse(currTestCase.query)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", currTestCase.query, err)
+		}
+
+		if solutions := q.Evaluate(testTriples); !reflect.DeepEqual(solutions, currTestCase.expected) {
+			t.Fatalf("Expected %v for %s, got %v", currTestCase.expected, currTestCase.query, solutions)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	queries := []string{
+		``,
+		`SELECT WHERE { ?a ?b ?c }`,
+		`SELECT ?a { ?a ?b }`,
+		`SELECT ?a { ?a td:name ?c }`,
+		`SELECT ?a { ?a ?b ?c } LIMIT x`,
+		`SELECT ?a { ?a "b" ?c }`,
+		`SELECT ?a { ?a ?b "c }`,
+		`SELECT ?a { ?a ?b ?c . FILTER(?a < 1) }`,
+		`CONSTRUCT { ?a ?b ?c } WHERE { ?a ?b ?c }`,
+	}
+
+	for _, currQuery := range queries {
+		var syntaxErr *SyntaxError
+		if _, err := Parse(currQuery); !errors.As(err, &syntaxErr) {
+			t.Fatalf("Expected syntax error for %q, got %v", currQuery, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package sse implements reading and writing of server-sent events
+// as defined by the html living standard
+package sse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType of event streams
+const ContentType = "text/event-stream"
+
+// Event is a single server-sent event
+type Event struct {
+	ID    string
+	Type  string
+	Data  string
+	Retry time.Duration
+}
+
+// Reader reads events from a stream
+type Reader struct {
+	reader *bufio.Reader
+	lastID string
+}
+
+// NewReader creates a new reader
+func NewReader(r io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(r)}
+}
+
+// NewReaderWithLastEventID creates a new reader which continues
+// a stream interrupted after the event with the given id
+func NewReaderWithLastEventID(r io.Reader, lastID string) *Reader {
+	return &Reader{reader: bufio.NewReader(r), lastID: lastID}
+}
+
+// Next blocks until the next event was received. The id of an event is
+// inherited from previous events if the event does not set it
+func (r *Reader) Next() (Event, error) {
+	var event Event
+	var data []string
+
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil {
+			return Event{}, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if data == nil {
+				// nothing to dispatch
+				event = Event{}
+				continue
+			}
+
+			event.ID = r.lastID
+			event.Data = strings.Join(data, "\n")
+
+			return event, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			// comment
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field = line[:i]
+			value = strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.lastID = value
+			}
+		case "retry":
+			if millis, err := strconv.Atoi(value); err == nil {
+				event.Retry = time.Duration(millis) * time.Millisecond
+			}
+		}
+	}
+}
+
+// LastEventID returns the id of the last event received
+func (r *Reader) LastEventID() string {
+	return r.lastID
+}
+
+// Write writes an event to a stream
+func Write(w io.Writer, event Event) error {
+	var b strings.Builder
+
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", event.ID)
+	}
+
+	if event.Type != "" {
+		fmt.Fprintf(&b, "event: %s\n", event.Type)
+	}
+
+	if event.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", event.Retry/time.Millisecond)
+	}
+
+	for _, line := range strings.Split(event.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// WriteID sets the id of the last event of a stream without dispatching an
+// event, so readers are able to resume from this position
+func WriteID(w io.Writer, id string) error {
+	_, err := fmt.Fprintf(w, "id: %s\n\n", id)
+
+	return err
+}
@@ -0,0 +1,75 @@
+package sse
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	stream := ": comment\n" +
+		"id: 1\n" +
+		"event: update\n" +
+		"data: {\"a\":\n" +
+		"data: 1}\n" +
+		"\n" +
+		"retry: 500\n" +
+		"data:second\r\n" +
+		"\r\n"
+
+	reader := NewReader(strings.NewReader(stream))
+
+	first, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+
+	if first.ID != "1" || first.Type != "update" || first.Data != "{\"a\":\n1}" {
+		t.Fatalf("Unexpected event %+v", first)
+	}
+
+	second, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+
+	if second.ID != "1" || second.Type != "" || second.Data != "second" || second.Retry != 500*time.Millisecond {
+		t.Fatalf("Unexpected event %+v", second)
+	}
+
+	if _, err := reader.Next(); err != io.EOF {
+		t.Fatalf("Expected end of stream, got %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	event := Event{ID: "42", Type: "thing_created", Data: "line one\nline two"}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, event); err != nil {
+		t.Fatalf("Failed to write event: %v", err)
+	}
+
+	read, err := NewReader(&buf).Next()
+	if err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+
+	if read != event {
+		t.Fatalf("Unexpected event. Expected: %+v, Got: %+v", event, read)
+	}
+}
+
+func TestWriteID(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteID(&buf, "7"); err != nil {
+		t.Fatalf("Failed to write id: %v", err)
+	}
+
+	reader := NewReader(&buf)
+	if _, err := reader.Next(); err != io.EOF || reader.LastEventID() != "7" {
+		t.Fatalf("Expected only the id to be read, got %q (%v)", reader.LastEventID(), err)
+	}
+}
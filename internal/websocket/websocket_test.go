@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEcho(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, []string{"echo"})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), []string{"other", "echo"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol != "echo" {
+		t.Fatalf("Unexpected subprotocol %q", conn.Subprotocol)
+	}
+
+	for _, size := range []int{0, 10, 200, 70000} {
+		message := bytes.Repeat([]byte("x"), size)
+		if err := conn.WriteMessage(BinaryMessage, message); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+
+		if messageType != BinaryMessage || !bytes.Equal(data, message) {
+			t.Fatalf("Unexpected echo of %d bytes", size)
+		}
+	}
+}
+
+func TestDialRejected(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected handshake to fail")
+	}
+}
@@ -0,0 +1,28 @@
+// Package wottest contains helpers shared by the tests of this module
+package wottest
+
+import (
+	"path/filepath"
+	"runtime"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// ContextURL is the url of the thing description context
+const ContextURL = "https://www.w3.org/2019/wot/td/v1"
+
+// DocumentLoader returns a document loader which serves the thing description
+// context from a local copy, so tests do not depend on network access
+func DocumentLoader() ld.DocumentLoader {
+	_, file, _, _ := runtime.Caller(0)
+
+	loader := ld.NewCachingDocumentLoader(ld.NewDefaultDocumentLoader(nil))
+	err := loader.PreloadWithMapping(map[string]string{
+		ContextURL: filepath.Join(filepath.Dir(file), "td-context-v1.jsonld"),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return loader
+}
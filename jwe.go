@@ -0,0 +1,359 @@
+package wotlib
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// EncryptedMember is the only member of objects replacing encrypted fields.
+// Its value is a compact jwe
+const EncryptedMember = "jwe"
+
+// SecurityDefinitionsField selects the security definitions of a td
+const SecurityDefinitionsField = "securityDefinitions"
+
+// FormHrefFields selects the hrefs of all forms of a td
+var FormHrefFields = []string{
+	"forms/*/href",
+	"properties/*/forms/*/href",
+	"actions/*/forms/*/href",
+	"events/*/forms/*/href",
+}
+
+// ErrDecryption is returned if encrypted content can't be decrypted
+var ErrDecryption = errors.New("failed to decrypt")
+
+// WithDecryptionKeys decrypts encrypted tds and encrypted fields of tds with
+// the key set. Without decryption keys tds are processed as they are
+func WithDecryptionKeys(keys *jose.JSONWebKeySet) FromBytesOption {
+	return func(o *fromBytesOptions) error {
+		o.decryptionKeys = keys
+		return nil
+	}
+}
+
+// Encrypt encrypts a whole td and returns the compact jwe. The algorithm of
+// the key is used or derived from the type of the key
+func Encrypt(td []byte, key jose.JSONWebKey) ([]byte, error) {
+	if !json.Valid(td) {
+		return nil, errors.New("thing description is not valid json")
+	}
+
+	jwe, err := encrypt(td, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(jwe), nil
+}
+
+// EncryptFields encrypts the selected fields of a td. Each field is replaced by
+// an object with the compact jwe of its value as only member. Fields are
+// selected by paths of member names or array indexes separated by '/', where
+// '*' matches every member or element (e.g. "properties/*/forms/*/href")
+func EncryptFields(td []byte, key jose.JSONWebKey, paths ...string) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return nil, err
+	}
+
+	for _, currPath := range paths {
+		var err error
+		document, err = encryptPath(document, strings.Split(currPath, "/"), key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(document)
+}
+
+// Decrypt decrypts an encrypted td and all encrypted fields of a td. The key
+// is selected by the key id of the jwe or, without key id, any key of the set
+// has to match
+func Decrypt(td []byte, keys *jose.JSONWebKeySet) ([]byte, error) {
+	if trimmed := bytes.TrimSpace(td); isCompactJWE(string(trimmed)) {
+		plaintext, err := decrypt(string(trimmed), keys)
+		if err != nil {
+			return nil, err
+		}
+
+		td = plaintext
+	}
+
+	if !bytes.Contains(td, []byte(`"`+EncryptedMember+`"`)) {
+		return td, nil
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return nil, err
+	}
+
+	document, err := decryptFields(document, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document)
+}
+
+// IsEncrypted returns true if the td is a compact jwe or any of its fields
+// is replaced by an object with a compact jwe as only member
+func IsEncrypted(td []byte) bool {
+	if isCompactJWE(string(bytes.TrimSpace(td))) {
+		return true
+	}
+
+	if !bytes.Contains(td, []byte(`"`+EncryptedMember+`"`)) {
+		return false
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return false
+	}
+
+	return hasEncryptedFields(document)
+}
+
+// decrypt decrypts a td as configured by the options
+func (o fromBytesOptions) decrypt(td []byte) ([]byte, error) {
+	if o.decryptionKeys == nil {
+		return td, nil
+	}
+
+	return Decrypt(td, o.decryptionKeys)
+}
+
+func encryptPath(value interface{}, path []string, key jose.JSONWebKey) (interface{}, error) {
+	if len(path) == 0 {
+		if m, ok := value.(map[string]interface{}); ok {
+			if _, ok := encryptedValue(m); ok {
+				return value, nil
+			}
+		}
+
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		jwe, err := encrypt(plaintext, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{EncryptedMember: jwe}, nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for currName, currValue := range v {
+			if path[0] != "*" && path[0] != currName {
+				continue
+			}
+
+			encrypted, err := encryptPath(currValue, path[1:], key)
+			if err != nil {
+				return nil, err
+			}
+
+			v[currName] = encrypted
+		}
+	case []interface{}:
+		for i, currValue := range v {
+			if path[0] != "*" && path[0] != strconv.Itoa(i) {
+				continue
+			}
+
+			encrypted, err := encryptPath(currValue, path[1:], key)
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = encrypted
+		}
+	}
+
+	return value, nil
+}
+
+func decryptFields(value interface{}, keys *jose.JSONWebKeySet) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if jwe, ok := encryptedValue(v); ok {
+			plaintext, err := decrypt(jwe, keys)
+			if err != nil {
+				return nil, err
+			}
+
+			var decrypted interface{}
+			if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+			}
+
+			return decrypted, nil
+		}
+
+		for currName, currValue := range v {
+			decrypted, err := decryptFields(currValue, keys)
+			if err != nil {
+				return nil, err
+			}
+
+			v[currName] = decrypted
+		}
+	case []interface{}:
+		for i, currValue := range v {
+			decrypted, err := decryptFields(currValue, keys)
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = decrypted
+		}
+	}
+
+	return value, nil
+}
+
+func hasEncryptedFields(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := encryptedValue(v); ok {
+			return true
+		}
+
+		for _, currValue := range v {
+			if hasEncryptedFields(currValue) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, currValue := range v {
+			if hasEncryptedFields(currValue) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// encryptedValue returns the jwe of an object replacing an encrypted field
+func encryptedValue(m map[string]interface{}) (string, bool) {
+	if len(m) != 1 {
+		return "", false
+	}
+
+	jwe, ok := m[EncryptedMember].(string)
+	return jwe, ok && isCompactJWE(jwe)
+}
+
+// isCompactJWE checks if s has the five parts of a compact jwe and a
+// protected header naming the algorithms
+func isCompactJWE(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != 5 {
+		return false
+	}
+
+	for _, currPart := range parts {
+		if _, err := base64.RawURLEncoding.DecodeString(currPart); err != nil {
+			return false
+		}
+	}
+
+	b, _ := base64.RawURLEncoding.DecodeString(parts[0])
+
+	var header struct {
+		Alg string `json:"alg"`
+		Enc string `json:"enc"`
+	}
+
+	return json.Unmarshal(b, &header) == nil && header.Alg != "" && header.Enc != ""
+}
+
+func encrypt(plaintext []byte, key jose.JSONWebKey) (string, error) {
+	alg, err := keyAlgorithm(key)
+	if err != nil {
+		return "", err
+	}
+
+	// symmetric keys have no public part
+	recipient := key.Public()
+	if !recipient.Valid() {
+		recipient = key
+	}
+
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: alg, Key: &recipient}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	object, err := encrypter.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	return object.CompactSerialize()
+}
+
+func decrypt(jwe string, keys *jose.JSONWebKeySet) ([]byte, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("%w: no decryption keys", ErrDecryption)
+	}
+
+	object, err := jose.ParseEncrypted(jwe)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+	}
+
+	candidates := keys.Keys
+	if kid := object.Header.KeyID; kid != "" {
+		candidates = keys.Key(kid)
+	}
+
+	for _, currKey := range candidates {
+		if plaintext, err := object.Decrypt(currKey); err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no matching key", ErrDecryption)
+}
+
+// keyAlgorithm returns the algorithm of the key or derives it from its type
+func keyAlgorithm(key jose.JSONWebKey) (jose.KeyAlgorithm, error) {
+	if key.Algorithm != "" {
+		return jose.KeyAlgorithm(key.Algorithm), nil
+	}
+
+	switch k := key.Key.(type) {
+	case *ecdsa.PrivateKey, *ecdsa.PublicKey:
+		return jose.ECDH_ES_A256KW, nil
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jose.RSA_OAEP_256, nil
+	case []byte:
+		switch len(k) {
+		case 16:
+			return jose.A128KW, nil
+		case 24:
+			return jose.A192KW, nil
+		case 32:
+			return jose.A256KW, nil
+		}
+	}
+
+	return "", fmt.Errorf("can't derive key algorithm for %T", key.Key)
+}
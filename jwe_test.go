@@ -0,0 +1,191 @@
+package wotlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+var testConfidentialTD = []byte(`{
+	"@context": "https://www.w3.org/2019/wot/td/v1",
+	"id": "urn:dev:ops:lamp",
+	"title": "Lamp",
+	"securityDefinitions": {"basic_sc": {"scheme": "basic", "in": "header"}},
+	"security": "basic_sc",
+	"properties": {
+		"on": {"type": "boolean", "forms": [{"href": "http://lamp.internal.corp/on"}]}
+	},
+	"actions": {
+		"toggle": {"forms": [{"href": "http://lamp.internal.corp/toggle"}]}
+	}
+}`)
+
+func TestEncrypt(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	key := jose.JSONWebKey{Key: private, KeyID: "lamp"}
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key}}
+	other := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: []byte("0123456789abcdef0123456789abcdef"), KeyID: "lamp"}}}
+
+	encrypted, err := Encrypt(testConfidentialTD, key)
+	if err != nil {
+		t.Fatalf("Failed to encrypt td: %v", err)
+	}
+
+	if strings.Count(string(encrypted), ".") != 4 || !IsEncrypted(encrypted) || IsEncrypted(testConfidentialTD) {
+		t.Fatalf("Expected compact jwe, got %s", encrypted)
+	}
+
+	tests := []struct {
+		opts     []FromBytesOption
+		expected error
+	}{
+		{[]FromBytesOption{WithDecryptionKeys(keys)}, nil},
+		{[]FromBytesOption{WithDecryptionKeys(other)}, ErrDecryption},
+	}
+
+	for i, currTest := range tests {
+		td, err := FromBytes(encrypted, currTest.opts...)
+		if !errors.Is(err, currTest.expected) {
+			t.Fatalf("Test %d: expected error %v, got %v", i, currTest.expected, err)
+		}
+
+		if err == nil && (td.ID != "urn:dev:ops:lamp" || len(td.Properties) != 1) {
+			t.Fatalf("Test %d: unexpected td %+v", i, td)
+		}
+	}
+
+	// without keys the jwe is processed as it is and isn't a td
+	if _, err := FromBytes(encrypted); err == nil || errors.Is(err, ErrDecryption) {
+		t.Fatalf("Expected jwe to fail as td without keys, got %v", err)
+	}
+
+	if _, err := Decrypt(encrypted, nil); !errors.Is(err, ErrDecryption) {
+		t.Fatalf("Expected decryption without keys to fail, got %v", err)
+	}
+
+	if _, err := Encrypt([]byte("no td"), key); err == nil {
+		t.Fatalf("Expected invalid td to fail")
+	}
+}
+
+func TestEncryptFields(t *testing.T) {
+	key := jose.JSONWebKey{Key: []byte("0123456789abcdef0123456789abcdef")}
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key}}
+
+	encrypted, err := EncryptFields(testConfidentialTD, key, append(FormHrefFields, SecurityDefinitionsField)...)
+	if err != nil {
+		t.Fatalf("Failed to encrypt fields: %v", err)
+	}
+
+	for _, currSecret := range []string{"internal.corp", `"scheme"`} {
+		if strings.Contains(string(encrypted), currSecret) {
+			t.Fatalf("Expected %s to be encrypted:\n%s", currSecret, encrypted)
+		}
+	}
+
+	if !strings.Contains(string(encrypted), `"title":"Lamp"`) || !IsEncrypted(encrypted) {
+		t.Fatalf("Expected only selected fields to be encrypted:\n%s", encrypted)
+	}
+
+	// encrypted fields aren't encrypted twice
+	again, err := EncryptFields(encrypted, key, SecurityDefinitionsField)
+	if err != nil {
+		t.Fatalf("Failed to encrypt fields: %v", err)
+	}
+
+	decrypted, err := Decrypt(again, keys)
+	if err != nil || IsEncrypted(decrypted) || !strings.Contains(string(decrypted), "http://lamp.internal.corp/toggle") {
+		t.Fatalf("Failed to decrypt fields: %s (%v)", decrypted, err)
+	}
+
+	// without keys encrypted fields are kept as they are
+	if td, err := FromBytes(encrypted); err == nil && len(td.Properties) == 1 && td.Properties[0].Form.Value().Href.Value() == "http://lamp.internal.corp/on" {
+		t.Fatalf("Expected fields not to be decrypted without keys")
+	}
+
+	td, err := FromBytes(encrypted, WithDecryptionKeys(keys))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	if len(td.Properties) != 1 || td.Properties[0].Form.Value().Href.Value() != "http://lamp.internal.corp/on" {
+		t.Fatalf("Unexpected properties %+v", td.Properties)
+	}
+
+	security := td.Properties[0].Form.Value().EffectiveSecurity
+	if len(security) != 1 || security[0].Scheme.Value() != SecurityBasic {
+		t.Fatalf("Expected decrypted security definitions to be resolved, got %+v", security)
+	}
+}
+
+func TestEncryptSigned(t *testing.T) {
+	signingKey, _ := testSigningKeys(t)
+	key := jose.JSONWebKey{Key: []byte("0123456789abcdef")}
+
+	signed, err := Sign(testConfidentialTD, signingKey)
+	if err != nil {
+		t.Fatalf("Failed to sign td: %v", err)
+	}
+
+	encrypted, err := EncryptFields(signed, key, FormHrefFields...)
+	if err != nil {
+		t.Fatalf("Failed to encrypt fields: %v", err)
+	}
+
+	// the signature covers the plaintext td
+	_, err = FromBytes(encrypted,
+		WithDecryptionKeys(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key}}),
+		WithVerificationKeys(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{signingKey.Public()}}),
+		RequireSignature(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to verify decrypted td: %v", err)
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	key := jose.JSONWebKey{Key: []byte("0123456789abcdef")}
+
+	encrypted, err := Encrypt(testConfidentialTD, key)
+	if err != nil {
+		t.Fatalf("Failed to encrypt td: %v", err)
+	}
+
+	fields, err := EncryptFields(testConfidentialTD, key, SecurityDefinitionsField)
+	if err != nil {
+		t.Fatalf("Failed to encrypt fields: %v", err)
+	}
+
+	tests := []struct {
+		td        []byte
+		encrypted bool
+	}{
+		{encrypted, true},
+		{fields, true},
+		{testConfidentialTD, false},
+		{[]byte(`[{"@id": "urn:dev:ops:lamp"}]`), false},
+		{[]byte("no td"), false},
+		{[]byte("a.b.c.d.e"), false},
+		{[]byte(`{"title": "Lamp", "links": [{"jwe": "not encrypted"}]}`), false},
+	}
+
+	for i, currTest := range tests {
+		if IsEncrypted(currTest.td) != currTest.encrypted {
+			t.Fatalf("Test %d: expected encrypted to be %v for %s", i, currTest.encrypted, currTest.td)
+		}
+	}
+
+	// content which isn't encrypted fails with the error of the json parser
+	if _, err := FromBytes([]byte("no td"), WithDecryptionKeys(&jose.JSONWebKeySet{})); err == nil || errors.Is(err, ErrDecryption) {
+		t.Fatalf("Expected parse error, got %v", err)
+	}
+}
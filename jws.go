@@ -0,0 +1,302 @@
+package wotlib
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// ProofMember is the member of a td carrying an embedded signature
+const ProofMember = "proof"
+
+// ProofType is the type of proofs embedded by Sign
+const ProofType = "JsonWebSignature2020"
+
+// well known signature errors
+var (
+	ErrUnsigned         = errors.New("thing description is not signed")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Proof is a signature embedded into a td
+type Proof struct {
+	Type    string `json:"type"`
+	Created string `json:"created,omitempty"`
+	// VerificationMethod is the id of the key which created the signature
+	VerificationMethod string `json:"verificationMethod,omitempty"`
+	ProofPurpose       string `json:"proofPurpose,omitempty"`
+	// JWS is the detached jws over the canonical form of the td
+	JWS string `json:"jws"`
+}
+
+// FromBytesOption configures how FromBytes processes a td
+type FromBytesOption func(o *fromBytesOptions) error
+
+type fromBytesOptions struct {
+	keys             *jose.JSONWebKeySet
+	signature        string
+	requireSignature bool
+	decryptionKeys   *jose.JSONWebKeySet
+}
+
+// WithVerificationKeys verifies the signature of the td, either embedded as
+// proof or given by WithDetachedSignature, against the key set
+func WithVerificationKeys(keys *jose.JSONWebKeySet) FromBytesOption {
+	return func(o *fromBytesOptions) error {
+		o.keys = keys
+		return nil
+	}
+}
+
+// WithDetachedSignature verifies the td against a detached jws carried
+// alongside the td
+func WithDetachedSignature(signature string) FromBytesOption {
+	return func(o *fromBytesOptions) error {
+		o.signature = signature
+		return nil
+	}
+}
+
+// RequireSignature rejects tds without a valid signature
+func RequireSignature() FromBytesOption {
+	return func(o *fromBytesOptions) error {
+		o.requireSignature = true
+		return nil
+	}
+}
+
+// SignDetached signs a td and returns the detached jws. The signed content
+// is the canonical form of the td followed by the td as json with sorted
+// members, so members which aren't mapped by the context are signed as well.
+// An embedded proof isn't part of the signed content. The algorithm of the
+// key is used or derived from the type of the key
+func SignDetached(td []byte, key jose.JSONWebKey) (string, error) {
+	payload, err := signingInput(td)
+	if err != nil {
+		return "", err
+	}
+
+	alg, err := signatureAlgorithm(key)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	object, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return object.DetachedCompactSerialize()
+}
+
+// Sign signs a td and embeds the signature as proof
+func Sign(td []byte, key jose.JSONWebKey) ([]byte, error) {
+	signature, err := SignDetached(td, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return nil, err
+	}
+
+	document[ProofMember] = Proof{
+		Type:               ProofType,
+		Created:            time.Now().UTC().Format(time.RFC3339),
+		VerificationMethod: key.KeyID,
+		ProofPurpose:       "assertionMethod",
+		JWS:                signature,
+	}
+
+	return json.Marshal(document)
+}
+
+// VerifyDetached verifies a detached jws created by SignDetached. Changes of
+// the formatting or the order of members keep the signature valid, any other
+// change of the td invalidates it. The key is selected by the key id of the signature or, without key id,
+// any key of the set has to match
+func VerifyDetached(td []byte, signature string, keys *jose.JSONWebKeySet) error {
+	if keys == nil {
+		return fmt.Errorf("%w: no verification keys", ErrInvalidSignature)
+	}
+
+	payload, err := signingInput(td)
+	if err != nil {
+		return err
+	}
+
+	object, err := jose.ParseDetached(signature, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	if len(object.Signatures) != 1 {
+		return fmt.Errorf("%w: expected one signature, got %d", ErrInvalidSignature, len(object.Signatures))
+	}
+
+	candidates := keys.Keys
+	if kid := object.Signatures[0].Header.KeyID; kid != "" {
+		candidates = keys.Key(kid)
+	}
+
+	for _, currKey := range candidates {
+		// symmetric keys have no public part
+		key := currKey.Public()
+		if !key.Valid() {
+			key = currKey
+		}
+
+		if err := object.DetachedVerify(payload, key); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: no matching key", ErrInvalidSignature)
+}
+
+// Verify verifies the proof embedded into a td
+func Verify(td []byte, keys *jose.JSONWebKeySet) error {
+	proof, err := findProof(td)
+	if err != nil {
+		return err
+	}
+
+	if proof == nil {
+		return ErrUnsigned
+	}
+
+	return VerifyDetached(td, proof.JWS, keys)
+}
+
+// verify checks the signature of a td as configured by the options
+func (o fromBytesOptions) verify(td []byte) error {
+	if o.keys == nil && !o.requireSignature {
+		return nil
+	}
+
+	signature := o.signature
+	if signature == "" {
+		proof, err := findProof(td)
+		if err != nil {
+			return err
+		}
+
+		if proof != nil {
+			signature = proof.JWS
+		}
+	}
+
+	if signature == "" {
+		if o.requireSignature {
+			return ErrUnsigned
+		}
+
+		return nil
+	}
+
+	if o.keys == nil {
+		return fmt.Errorf("%w: no verification keys", ErrInvalidSignature)
+	}
+
+	return VerifyDetached(td, signature, o.keys)
+}
+
+// findProof returns the embedded proof of a td or nil if it has none
+func findProof(td []byte) (*Proof, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(td, &document); err != nil {
+		return nil, err
+	}
+
+	raw, ok := document[ProofMember]
+	if !ok {
+		return nil, nil
+	}
+
+	var proof Proof
+	if err := json.Unmarshal(raw, &proof); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	if proof.Type != ProofType || proof.JWS == "" {
+		return nil, fmt.Errorf("%w: unsupported proof %q", ErrInvalidSignature, proof.Type)
+	}
+
+	return &proof, nil
+}
+
+// signingInput returns the content covered by signatures: the canonical form
+// of a td without its proof followed by its json with sorted members. The
+// canonical form lacks members which aren't mapped by the context
+func signingInput(td []byte) ([]byte, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(td, &document); err != nil {
+		return nil, err
+	}
+
+	delete(document, ProofMember)
+
+	canonical, err := canonicalize(document)
+	if err != nil {
+		return nil, err
+	}
+
+	// numbers are kept as written
+	decoder := json.NewDecoder(bytes.NewReader(td))
+	decoder.UseNumber()
+
+	var members map[string]interface{}
+	if err := decoder.Decode(&members); err != nil {
+		return nil, err
+	}
+
+	delete(members, ProofMember)
+
+	sorted, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(canonical, '\n'), sorted...), nil
+}
+
+// signatureAlgorithm returns the algorithm of the key or derives it from its type
+func signatureAlgorithm(key jose.JSONWebKey) (jose.SignatureAlgorithm, error) {
+	if key.Algorithm != "" {
+		return jose.SignatureAlgorithm(key.Algorithm), nil
+	}
+
+	switch k := key.Key.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		case elliptic.P521():
+			return jose.ES512, nil
+		}
+	case *rsa.PrivateKey:
+		return jose.RS256, nil
+	case ed25519.PrivateKey:
+		return jose.EdDSA, nil
+	case []byte:
+		return jose.HS256, nil
+	}
+
+	return "", fmt.Errorf("can't derive signature algorithm for %T", key.Key)
+}
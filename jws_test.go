@@ -0,0 +1,145 @@
+package wotlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+var testSignedTD = []byte(`{
+	"@context": "https://www.w3.org/2019/wot/td/v1",
+	"id": "urn:dev:ops:lamp",
+	"title": "Lamp",
+	"properties": {
+		"on": {"type": "boolean", "forms": [{"href": "http://lamp.local/on"}]}
+	}
+}`)
+
+func testSigningKeys(t *testing.T) (jose.JSONWebKey, jose.JSONWebKey) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	return jose.JSONWebKey{Key: private, KeyID: "lamp"}, jose.JSONWebKey{Key: other, KeyID: "other"}
+}
+
+func TestSign(t *testing.T) {
+	key, other := testSigningKeys(t)
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.Public(), other.Public()}}
+	hmac := jose.JSONWebKey{Key: []byte("0123456789abcdef0123456789abcdef")}
+
+	signed, err := Sign(testSignedTD, key)
+	if err != nil {
+		t.Fatalf("Failed to sign td: %v", err)
+	}
+
+	if err := Verify(signed, keys); err != nil {
+		t.Fatalf("Failed to verify signed td: %v", err)
+	}
+
+	// formatting and member order aren't part of the signature
+	var document map[string]interface{}
+	json.Unmarshal(signed, &document)
+	reformatted, _ := json.MarshalIndent(document, "", "  ")
+	if err := Verify(reformatted, keys); err != nil {
+		t.Fatalf("Failed to verify reformatted td: %v", err)
+	}
+
+	tampered := []byte(strings.Replace(string(signed), "http://lamp.local/on", "http://evil.local/on", 1))
+	if err := Verify(tampered, keys); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected tampered td to fail, got %v", err)
+	}
+
+	// members dropped by the expansion are signed as well
+	document["@unmapped"] = "added"
+	added, _ := json.Marshal(document)
+	if err := Verify(added, keys); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected added member to fail, got %v", err)
+	}
+
+	if err := Verify(signed, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{other.Public()}}); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected unknown key to fail, got %v", err)
+	}
+
+	if err := Verify(signed, nil); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected missing keys to fail, got %v", err)
+	}
+
+	if err := Verify(testSignedTD, keys); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("Expected unsigned td to fail, got %v", err)
+	}
+
+	// signatures without key id are checked against all keys
+	signature, err := SignDetached(testSignedTD, hmac)
+	if err != nil {
+		t.Fatalf("Failed to sign td: %v", err)
+	}
+
+	if strings.Count(signature, ".") != 2 || !strings.Contains(signature, "..") {
+		t.Fatalf("Expected detached compact jws, got %s", signature)
+	}
+
+	if err := VerifyDetached(testSignedTD, signature, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{other.Public(), hmac}}); err != nil {
+		t.Fatalf("Failed to verify detached signature: %v", err)
+	}
+}
+
+func TestFromBytesSignature(t *testing.T) {
+	key, other := testSigningKeys(t)
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.Public()}}
+
+	signed, err := Sign(testSignedTD, key)
+	if err != nil {
+		t.Fatalf("Failed to sign td: %v", err)
+	}
+
+	signature, err := SignDetached(testSignedTD, key)
+	if err != nil {
+		t.Fatalf("Failed to sign td: %v", err)
+	}
+
+	foreign, err := SignDetached(testSignedTD, other)
+	if err != nil {
+		t.Fatalf("Failed to sign td: %v", err)
+	}
+
+	tests := []struct {
+		td       []byte
+		opts     []FromBytesOption
+		expected error
+	}{
+		{testSignedTD, nil, nil},
+		{signed, nil, nil},
+		{signed, []FromBytesOption{WithVerificationKeys(keys)}, nil},
+		{signed, []FromBytesOption{WithVerificationKeys(keys), RequireSignature()}, nil},
+		{testSignedTD, []FromBytesOption{WithVerificationKeys(keys)}, nil},
+		{testSignedTD, []FromBytesOption{WithVerificationKeys(keys), RequireSignature()}, ErrUnsigned},
+		{testSignedTD, []FromBytesOption{WithVerificationKeys(keys), WithDetachedSignature(signature)}, nil},
+		{testSignedTD, []FromBytesOption{WithVerificationKeys(keys), WithDetachedSignature(foreign)}, ErrInvalidSignature},
+		{testSignedTD, []FromBytesOption{RequireSignature(), WithDetachedSignature(signature)}, ErrInvalidSignature},
+		{[]byte(strings.Replace(string(signed), `"Lamp"`, `"Lamp 2"`, 1)), []FromBytesOption{WithVerificationKeys(keys)}, ErrInvalidSignature},
+	}
+
+	for i, currTest := range tests {
+		td, err := FromBytes(currTest.td, currTest.opts...)
+		if !errors.Is(err, currTest.expected) {
+			t.Fatalf("Test %d: expected error %v, got %v", i, currTest.expected, err)
+		}
+
+		if err == nil && td.ID != "urn:dev:ops:lamp" {
+			t.Fatalf("Test %d: unexpected td %s", i, td.ID)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package mockthing
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/codec"
+	"github.com/connctd/wotlib/internal/payload"
+	"github.com/connctd/wotlib/internal/sse"
+	"github.com/connctd/wotlib/internal/websocket"
+)
+
+// pollTimeout is the time a long polling request waits for a value
+// before an empty response is sent
+const pollTimeout = 30 * time.Second
+
+// ServeHTTP dispatches requests for the forms of the thing
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrade := websocket.IsUpgrade(r)
+
+	allowed := map[string]bool{}
+	for _, currRoute := range s.routes[r.URL.Path] {
+		if (currRoute.transport == transportWebSocket) != upgrade {
+			continue
+		}
+
+		if currRoute.method == r.Method {
+			s.serveRoute(w, r, currRoute)
+			return
+		}
+
+		allowed[currRoute.method] = true
+	}
+
+	if len(allowed) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	methods := make([]string, 0, len(allowed))
+	for currMethod := range allowed {
+		methods = append(methods, currMethod)
+	}
+
+	sort.Strings(methods)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+func (s *Server) serveRoute(w http.ResponseWriter, r *http.Request, rt route) {
+	switch rt.op {
+	case wotlib.OpReadProperty:
+		value, _ := s.Property(rt.name)
+		payload.Write(w, rt.contentType, http.StatusOK, value)
+	case wotlib.OpWriteProperty:
+		property, _ := s.property(rt.name)
+
+		value, ok := payload.Read(w, r, rt.contentType, property.ExpandedDataSchema)
+		if !ok {
+			return
+		}
+
+		s.setProperty(rt.name, value)
+		w.WriteHeader(http.StatusNoContent)
+	case wotlib.OpInvokeAction:
+		action, _ := s.action(rt.name)
+
+		input, ok := payload.Read(w, r, rt.contentType, action.Input.Value())
+		if !ok {
+			return
+		}
+
+		output := s.invoke(rt.name, input)
+		if output == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		payload.Write(w, rt.contentType, http.StatusOK, output)
+	case wotlib.OpObserveProperty:
+		s.stream(w, r, rt, subscriptionKey{kind: kindProperty, name: rt.name})
+	case wotlib.OpSubscribeEvent:
+		s.stream(w, r, rt, subscriptionKey{kind: kindEvent, name: rt.name})
+	}
+}
+
+// stream delivers the values published for the key with the transport of the route
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, rt route, key subscriptionKey) {
+	values, cancel := s.subscribe(key)
+	defer cancel()
+
+	switch rt.transport {
+	case transportLongPoll:
+		s.longPoll(w, r, rt, values)
+	case transportSSE:
+		s.streamSSE(w, r, rt, values)
+	case transportWebSocket:
+		s.streamWebSocket(w, r, rt, values)
+	}
+}
+
+// longPoll responds with the next value or an empty response after the poll timeout
+func (s *Server) longPoll(w http.ResponseWriter, r *http.Request, rt route, values <-chan interface{}) {
+	timer := time.NewTimer(pollTimeout)
+	defer timer.Stop()
+
+	select {
+	case value := <-values:
+		payload.Write(w, rt.contentType, http.StatusOK, value)
+	case <-timer.C:
+		w.WriteHeader(http.StatusNoContent)
+	case <-s.done:
+		w.WriteHeader(http.StatusNoContent)
+	case <-r.Context().Done():
+	}
+}
+
+func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request, rt route, values <-chan interface{}) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// the content type of the form describes the stream, values are json
+	contentType := rt.contentType
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == sse.ContentType {
+		contentType = codec.DefaultContentType
+	}
+
+	w.Header().Set("Content-Type", sse.ContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for id := 1; ; id++ {
+		select {
+		case value := <-values:
+			data, err := codec.Marshal(contentType, value)
+			if err != nil {
+				return
+			}
+
+			if err := sse.Write(w, sse.Event{ID: strconv.Itoa(id), Data: string(data)}); err != nil {
+				return
+			}
+
+			flusher.Flush()
+		case <-s.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) streamWebSocket(w http.ResponseWriter, r *http.Request, rt route, values <-chan interface{}) {
+	var subprotocols []string
+	if rt.subprotocol != "" {
+		subprotocols = append(subprotocols, rt.subprotocol)
+	}
+
+	conn, err := websocket.Upgrade(w, r, subprotocols)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// messages of the client are ignored, reading detects closed connections
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case value := <-values:
+			data, err := codec.Marshal(rt.contentType, value)
+			if err != nil {
+				return
+			}
+
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-s.done:
+			return
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Package mockthing implements fake devices for integration tests. A mock
+// server implements the http and websocket forms of a thing description,
+// keeps the values of properties in memory, records action invocations and
+// emits events triggered by the test
+package mockthing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/connctd/wotlib"
+)
+
+// well known errors
+var (
+	ErrUnknownAffordance = errors.New("unknown affordance")
+)
+
+// Invocation is a recorded invocation of an action
+type Invocation struct {
+	Input interface{}
+	Time  time.Time
+}
+
+// Server is a mock device serving the forms of a thing description
+type Server struct {
+	// URL of the server, e.g. "http://127.0.0.1:1234"
+	URL string
+
+	server *httptest.Server
+	td     wotlib.ExpandedThingDescription
+	routes map[string][]route
+	done   chan struct{}
+
+	mutex       sync.Mutex
+	properties  map[string]interface{}
+	outputs     map[string]interface{}
+	invocations map[string][]Invocation
+	subscribers map[subscriptionKey]map[chan interface{}]struct{}
+	closeOnce   sync.Once
+}
+
+// NewServer starts a mock server implementing the forms of a thing description.
+// Properties are initialized with values matching their schemas and actions
+// respond with outputs matching their output schemas. Forms of other protocols
+// than http and websockets are not served and are missing in the td of the server
+func NewServer(td wotlib.ExpandedThingDescription) (*Server, error) {
+	s := &Server{
+		td:          td,
+		routes:      map[string][]route{},
+		done:        make(chan struct{}),
+		properties:  map[string]interface{}{},
+		outputs:     map[string]interface{}{},
+		invocations: map[string][]Invocation{},
+		subscribers: map[subscriptionKey]map[chan interface{}]struct{}{},
+	}
+
+	for _, currProperty := range td.Properties {
+		s.properties[currProperty.Name.Value()] = sampleValue(currProperty.ExpandedDataSchema)
+	}
+
+	for _, currAction := range td.Actions {
+		if len(currAction.Output) > 0 {
+			s.outputs[currAction.Name.Value()] = sampleValue(currAction.Output.Value())
+		}
+	}
+
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+
+	var err error
+	s.td, err = s.buildRoutes(td)
+	if err != nil {
+		s.server.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close shuts the server down and ends all subscriptions
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.server.Close()
+	})
+}
+
+// TD returns the thing description with all served forms pointing to the server
+func (s *Server) TD() wotlib.ExpandedThingDescription {
+	return s.td
+}
+
+// Property returns the current value of a property
+func (s *Server) Property(name string) (interface{}, error) {
+	if _, ok := s.property(name); !ok {
+		return nil, fmt.Errorf("%w: property %s", ErrUnknownAffordance, name)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.properties[name], nil
+}
+
+// SetProperty sets the value of a property after validating it against the
+// schema of the property. Observers of the property are notified
+func (s *Server) SetProperty(name string, value interface{}) error {
+	property, ok := s.property(name)
+	if !ok {
+		return fmt.Errorf("%w: property %s", ErrUnknownAffordance, name)
+	}
+
+	value, err := normalize(value)
+	if err != nil {
+		return err
+	}
+
+	if err := property.Validate(value); err != nil {
+		return err
+	}
+
+	s.setProperty(name, value)
+
+	return nil
+}
+
+// SetActionOutput sets the output returned by invocations of an action after
+// validating it against the output schema of the action
+func (s *Server) SetActionOutput(name string, output interface{}) error {
+	action, ok := s.action(name)
+	if !ok {
+		return fmt.Errorf("%w: action %s", ErrUnknownAffordance, name)
+	}
+
+	output, err := normalize(output)
+	if err != nil {
+		return err
+	}
+
+	if err := action.Output.Validate(output); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.outputs[name] = output
+
+	return nil
+}
+
+// Invocations returns the recorded invocations of an action in order
+func (s *Server) Invocations(name string) []Invocation {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Invocation{}, s.invocations[name]...)
+}
+
+// EmitEvent validates the data against the schema of the event and sends it
+// to all current subscribers. Subscribers connecting later miss the event
+func (s *Server) EmitEvent(name string, data interface{}) error {
+	event, ok := s.event(name)
+	if !ok {
+		return fmt.Errorf("%w: event %s", ErrUnknownAffordance, name)
+	}
+
+	data, err := normalize(data)
+	if err != nil {
+		return err
+	}
+
+	if err := event.Data.Validate(data); err != nil {
+		return err
+	}
+
+	s.publish(subscriptionKey{kind: kindEvent, name: name}, data)
+
+	return nil
+}
+
+// EventSubscribers returns the number of clients currently subscribed to an event
+func (s *Server) EventSubscribers(name string) int {
+	return s.countSubscribers(subscriptionKey{kind: kindEvent, name: name})
+}
+
+// PropertyObservers returns the number of clients currently observing a property
+func (s *Server) PropertyObservers(name string) int {
+	return s.countSubscribers(subscriptionKey{kind: kindProperty, name: name})
+}
+
+func (s *Server) setProperty(name string, value interface{}) {
+	s.mutex.Lock()
+	s.properties[name] = value
+	s.mutex.Unlock()
+
+	s.publish(subscriptionKey{kind: kindProperty, name: name}, value)
+}
+
+func (s *Server) invoke(name string, input interface{}) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.invocations[name] = append(s.invocations[name], Invocation{Input: input, Time: time.Now()})
+
+	return s.outputs[name]
+}
+
+func (s *Server) property(name string) (wotlib.ExpandedPropertyAffordance, bool) {
+	for _, currProperty := range s.td.Properties {
+		if currProperty.Name.Value() == name {
+			return currProperty, true
+		}
+	}
+
+	return wotlib.ExpandedPropertyAffordance{}, false
+}
+
+func (s *Server) action(name string) (wotlib.ExpandedActionAffordance, bool) {
+	for _, currAction := range s.td.Actions {
+		if currAction.Name.Value() == name {
+			return currAction, true
+		}
+	}
+
+	return wotlib.ExpandedActionAffordance{}, false
+}
+
+func (s *Server) event(name string) (wotlib.ExpandedEventAffordance, bool) {
+	for _, currEvent := range s.td.Events {
+		if currEvent.Name.Value() == name {
+			return currEvent, true
+		}
+	}
+
+	return wotlib.ExpandedEventAffordance{}, false
+}
+
+// kinds of affordances clients can subscribe to
+const (
+	kindProperty = "property"
+	kindEvent    = "event"
+)
+
+type subscriptionKey struct {
+	kind string
+	name string
+}
+
+// subscribe registers a subscriber. The returned function cancels the subscription
+func (s *Server) subscribe(key subscriptionKey) (<-chan interface{}, func()) {
+	values := make(chan interface{}, 16)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.subscribers[key] == nil {
+		s.subscribers[key] = map[chan interface{}]struct{}{}
+	}
+
+	s.subscribers[key][values] = struct{}{}
+
+	return values, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		delete(s.subscribers[key], values)
+	}
+}
+
+// publish sends a value to all subscribers. Values are dropped for
+// subscribers which do not keep up
+func (s *Server) publish(key subscriptionKey, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for currSubscriber := range s.subscribers[key] {
+		select {
+		case currSubscriber <- value:
+		default:
+		}
+	}
+}
+
+func (s *Server) countSubscribers(key subscriptionKey) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.subscribers[key])
+}
+
+// normalize converts a value into its generic json representation
+func normalize(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	err = json.Unmarshal(b, &result)
+
+	return result, err
+}
+
+// serverURL returns the url of the server with the scheme matching the given one
+func (s *Server) serverURL(scheme string) *url.URL {
+	u, _ := url.Parse(s.URL)
+	if scheme == "ws" || scheme == "wss" {
+		u.Scheme = "ws"
+	}
+
+	return u
+}
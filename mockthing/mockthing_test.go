@@ -0,0 +1,383 @@
+package mockthing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/consumer"
+	"github.com/connctd/wotlib/internal/wottest"
+)
+
+func init() {
+	wotlib.DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
+
+func newTestServer(t *testing.T) *Server {
+	td, err := wotlib.FromBytes([]byte(testTD))
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	s, err := NewServer(td)
+	if err != nil {
+		t.Fatalf("Failed to start mock server: %v", err)
+	}
+
+	return s
+}
+
+func findProperty(t *testing.T, td wotlib.ExpandedThingDescription, name string) wotlib.ExpandedPropertyAffordance {
+	for _, currProperty := range td.Properties {
+		if currProperty.Name.Value() == name {
+			return currProperty
+		}
+	}
+
+	t.Fatalf("Property %s not found", name)
+	return wotlib.ExpandedPropertyAffordance{}
+}
+
+func findAction(t *testing.T, td wotlib.ExpandedThingDescription, name string) wotlib.ExpandedActionAffordance {
+	for _, currAction := range td.Actions {
+		if currAction.Name.Value() == name {
+			return currAction
+		}
+	}
+
+	t.Fatalf("Action %s not found", name)
+	return wotlib.ExpandedActionAffordance{}
+}
+
+func findEvent(t *testing.T, td wotlib.ExpandedThingDescription, name string) wotlib.ExpandedEventAffordance {
+	for _, currEvent := range td.Events {
+		if currEvent.Name.Value() == name {
+			return currEvent
+		}
+	}
+
+	t.Fatalf("Event %s not found", name)
+	return wotlib.ExpandedEventAffordance{}
+}
+
+// waitFor waits until the condition is met
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Condition not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerTD(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	brightness := findProperty(t, s.TD(), "brightness")
+	if len(brightness.Form) != 2 {
+		t.Fatalf("Expected mqtt form to be removed, got %d forms", len(brightness.Form))
+	}
+
+	for _, currForm := range brightness.Form {
+		if !strings.HasPrefix(currForm.Href.Value(), s.URL+"/properties/brightness") {
+			t.Fatalf("Unexpected href %s", currForm.Href.Value())
+		}
+	}
+
+	overheated := findEvent(t, s.TD(), "overheated")
+	if href := overheated.Form.Value().Href.Value(); href != "ws"+strings.TrimPrefix(s.URL, "http")+"/events/overheated" {
+		t.Fatalf("Unexpected href %s", href)
+	}
+}
+
+func TestServerProperties(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	ctx := context.Background()
+	c := consumer.NewConsumer()
+	brightness := findProperty(t, s.TD(), "brightness")
+
+	value, err := c.ReadProperty(ctx, brightness)
+	if err != nil || value != float64(10) {
+		t.Fatalf("Expected minimum as initial value, got %v (%v)", value, err)
+	}
+
+	if err := c.WriteProperty(ctx, brightness, 70); err != nil {
+		t.Fatalf("Failed to write property: %v", err)
+	}
+
+	if value, _ := s.Property("brightness"); value != float64(70) {
+		t.Fatalf("Expected written value, got %v", value)
+	}
+
+	// the consumer validates as well, bypass it with an empty schema
+	invalid := brightness
+	invalid.ExpandedDataSchema = wotlib.ExpandedDataSchema{}
+
+	var httpErr *consumer.HTTPError
+	if err := c.WriteProperty(ctx, invalid, 150); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected bad request, got %v", err)
+	}
+
+	if err := s.SetProperty("brightness", 20); err != nil {
+		t.Fatalf("Failed to set property: %v", err)
+	}
+
+	if value, err := c.ReadProperty(ctx, brightness); err != nil || value != float64(20) {
+		t.Fatalf("Expected value set by test, got %v (%v)", value, err)
+	}
+
+	var validationErr *wotlib.ValidationError
+	if err := s.SetProperty("brightness", "bright"); !errors.As(err, &validationErr) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+
+	if err := s.SetProperty("unknown", 1); !errors.Is(err, ErrUnknownAffordance) {
+		t.Fatalf("Expected ErrUnknownAffordance, got %v", err)
+	}
+
+	status := findProperty(t, s.TD(), "status")
+	if value, err := c.ReadProperty(ctx, status); err != nil || value != "" {
+		t.Fatalf("Expected empty string, got %v (%v)", value, err)
+	}
+
+	resp, err := http.Post(status.Form.Value().Href.Value(), "application/json", strings.NewReader(`"on"`))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET" {
+		t.Fatalf("Expected read only property, got %d allowing %s", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+}
+
+func TestServerActions(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	ctx := context.Background()
+	c := consumer.NewConsumer()
+	setColor := findAction(t, s.TD(), "setColor")
+
+	input := map[string]interface{}{"red": 255, "green": 0, "blue": 10}
+
+	output, err := c.InvokeAction(ctx, setColor, input)
+	if err != nil {
+		t.Fatalf("Failed to invoke action: %v", err)
+	}
+
+	if output.(map[string]interface{})["status"] != "accepted" {
+		t.Fatalf("Expected first enum value as output, got %v", output)
+	}
+
+	if err := s.SetActionOutput("setColor", map[string]interface{}{"status": "done"}); err != nil {
+		t.Fatalf("Failed to set action output: %v", err)
+	}
+
+	output, err = c.InvokeAction(ctx, setColor, input)
+	if err != nil || output.(map[string]interface{})["status"] != "done" {
+		t.Fatalf("Expected output set by test, got %v (%v)", output, err)
+	}
+
+	if err := s.SetActionOutput("setColor", map[string]interface{}{"status": "failed"}); err == nil {
+		t.Fatalf("Expected invalid output to be rejected")
+	}
+
+	invocations := s.Invocations("setColor")
+	if len(invocations) != 2 || invocations[0].Input.(map[string]interface{})["red"] != float64(255) {
+		t.Fatalf("Unexpected invocations %+v", invocations)
+	}
+
+	if _, err := c.InvokeAction(ctx, findAction(t, s.TD(), "toggle"), nil); err != nil {
+		t.Fatalf("Failed to invoke action without output: %v", err)
+	}
+
+	if len(s.Invocations("toggle")) != 1 {
+		t.Fatalf("Expected toggle to be recorded")
+	}
+}
+
+func TestServerNotifications(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := consumer.NewConsumer()
+
+	testCases := []struct {
+		name      string
+		subscribe func() (<-chan consumer.Notification, error)
+		count     func() int
+		emit      func() error
+		expected  interface{}
+	}{
+		{
+			name: "long polling",
+			subscribe: func() (<-chan consumer.Notification, error) {
+				return c.ObserveProperty(ctx, findProperty(t, s.TD(), "brightness"))
+			},
+			count:    func() int { return s.PropertyObservers("brightness") },
+			emit:     func() error { return s.SetProperty("brightness", 42) },
+			expected: float64(42),
+		},
+		{
+			name: "server-sent events",
+			subscribe: func() (<-chan consumer.Notification, error) {
+				return c.ObserveProperty(ctx, findProperty(t, s.TD(), "temperature"))
+			},
+			count:    func() int { return s.PropertyObservers("temperature") },
+			emit:     func() error { return s.SetProperty("temperature", 21.5) },
+			expected: 21.5,
+		},
+		{
+			name: "websocket",
+			subscribe: func() (<-chan consumer.Notification, error) {
+				return c.SubscribeEvent(ctx, findEvent(t, s.TD(), "overheated"))
+			},
+			count:    func() int { return s.EventSubscribers("overheated") },
+			emit:     func() error { return s.EmitEvent("overheated", 85.5) },
+			expected: 85.5,
+		},
+	}
+
+	for _, currTestCase := range testCases {
+		notifications, err := currTestCase.subscribe()
+		if err != nil {
+			t.Fatalf("%s: failed to subscribe: %v", currTestCase.name, err)
+		}
+
+		waitFor(t, func() bool { return currTestCase.count() == 1 })
+
+		if err := currTestCase.emit(); err != nil {
+			t.Fatalf("%s: failed to emit value: %v", currTestCase.name, err)
+		}
+
+		if notification := <-notifications; notification.Err != nil || notification.Value != currTestCase.expected {
+			t.Fatalf("%s: unexpected notification %+v", currTestCase.name, notification)
+		}
+	}
+
+	if err := s.EmitEvent("overheated", "hot"); err == nil {
+		t.Fatalf("Expected invalid event data to be rejected")
+	}
+}
+
+var testTD = `{
+    "@context": [
+        "https://www.w3.org/2019/wot/td/v1",
+        {
+            "iot": "http://iotschema.org/"
+        }
+    ],
+    "id": "urn:dev:ops:32473-LampOne",
+    "title": "LampOne",
+    "securityDefinitions": {
+        "nosec_sc": {"scheme": "nosec"}
+    },
+    "security": ["nosec_sc"],
+    "properties": {
+        "brightness": {
+            "type": "integer",
+            "minimum": 10,
+            "maximum": 100,
+            "forms": [
+                {
+                    "op": ["readproperty", "writeproperty"],
+                    "href": "mqtt://broker.local/lamp/brightness"
+                },
+                {
+                    "op": ["readproperty", "writeproperty"],
+                    "href": "http://lamp.local/properties/brightness",
+                    "contentType": "application/json"
+                },
+                {
+                    "op": "observeproperty",
+                    "href": "http://lamp.local/properties/brightness/longpoll",
+                    "subprotocol": "longpoll"
+                }
+            ]
+        },
+        "temperature": {
+            "type": "number",
+            "observable": true,
+            "forms": [
+                {
+                    "op": "readproperty",
+                    "href": "http://lamp.local/properties/temperature"
+                },
+                {
+                    "op": "observeproperty",
+                    "href": "http://lamp.local/properties/temperature/sse",
+                    "subprotocol": "sse",
+                    "contentType": "text/event-stream"
+                }
+            ]
+        },
+        "status": {
+            "type": "string",
+            "readOnly": true,
+            "forms": [
+                {
+                    "href": "http://lamp.local/properties/status"
+                }
+            ]
+        }
+    },
+    "events": {
+        "overheated": {
+            "data": {"type": "number"},
+            "forms": [
+                {
+                    "op": "subscribeevent",
+                    "href": "ws://lamp.local/events/overheated"
+                }
+            ]
+        }
+    },
+    "actions": {
+        "setColor": {
+            "input": {
+                "type": "object",
+                "required": ["red", "green", "blue"],
+                "properties": {
+                    "red": {"type": "integer", "minimum": 0, "maximum": 255},
+                    "green": {"type": "integer", "minimum": 0, "maximum": 255},
+                    "blue": {"type": "integer", "minimum": 0, "maximum": 255}
+                }
+            },
+            "output": {
+                "type": "object",
+                "required": ["status"],
+                "properties": {
+                    "status": {"type": "string", "enum": ["accepted", "done"]}
+                }
+            },
+            "forms": [
+                {
+                    "op": "invokeaction",
+                    "href": "http://lamp.local/actions/setColor"
+                }
+            ]
+        },
+        "toggle": {
+            "forms": [
+                {
+                    "op": "invokeaction",
+                    "href": "http://lamp.local/actions/toggle",
+                    "htv:methodName": "PUT"
+                }
+            ]
+        }
+    }
+}`
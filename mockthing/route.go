@@ -0,0 +1,164 @@
+package mockthing
+
+import (
+	"net/url"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/codec"
+	"github.com/connctd/wotlib/internal/payload"
+)
+
+// transports of forms
+const (
+	transportRequest = iota
+	transportLongPoll
+	transportSSE
+	transportWebSocket
+)
+
+// route is an operation served for a form of the td and the transport used
+// to deliver its values
+type route struct {
+	method      string
+	op          string
+	name        string
+	transport   int
+	contentType string
+	subprotocol string
+}
+
+// buildRoutes creates the routes of all http and websocket forms and returns
+// a copy of the td containing only those forms, pointing to the server
+func (s *Server) buildRoutes(td wotlib.ExpandedThingDescription) (wotlib.ExpandedThingDescription, error) {
+	var err error
+
+	td.Properties = append([]wotlib.ExpandedPropertyAffordance{}, td.Properties...)
+	for i := range td.Properties {
+		property := &td.Properties[i]
+
+		ops := []string{}
+		if !property.WriteOnly.Value() {
+			ops = append(ops, wotlib.OpReadProperty, wotlib.OpObserveProperty)
+		}
+
+		if !property.ReadOnly.Value() {
+			ops = append(ops, wotlib.OpWriteProperty)
+		}
+
+		property.Form, err = s.formRoutes(property.Form, property.Name.Value(), ops)
+		if err != nil {
+			return td, err
+		}
+	}
+
+	td.Actions = append([]wotlib.ExpandedActionAffordance{}, td.Actions...)
+	for i := range td.Actions {
+		action := &td.Actions[i]
+
+		action.Form, err = s.formRoutes(action.Form, action.Name.Value(), []string{wotlib.OpInvokeAction})
+		if err != nil {
+			return td, err
+		}
+	}
+
+	td.Events = append([]wotlib.ExpandedEventAffordance{}, td.Events...)
+	for i := range td.Events {
+		event := &td.Events[i]
+
+		event.Form, err = s.formRoutes(event.Form, event.Name.Value(), []string{wotlib.OpSubscribeEvent})
+		if err != nil {
+			return td, err
+		}
+	}
+
+	return td, nil
+}
+
+// formRoutes adds the routes of the served forms and returns them with
+// hrefs pointing to the server. Operations are limited to the supported ones
+func (s *Server) formRoutes(forms wotlib.ExpandedFormNode, name string, supportedOps []string) (wotlib.ExpandedFormNode, error) {
+	var served wotlib.ExpandedFormNode
+
+	for _, currForm := range forms {
+		u, err := url.Parse(currForm.Href.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		transport := transportRequest
+		switch {
+		case u.Scheme == "ws" || u.Scheme == "wss":
+			transport = transportWebSocket
+		case u.IsAbs() && u.Scheme != "http" && u.Scheme != "https":
+			continue
+		case currForm.Subprotocol.Value() == "sse":
+			transport = transportSSE
+		case currForm.Subprotocol.Value() == "longpoll":
+			transport = transportLongPoll
+		}
+
+		var routes []route
+		for _, currOp := range supportedOps {
+			if !currForm.HasOp(currOp) || !supportsTransport(currOp, transport, currForm) {
+				continue
+			}
+
+			opTransport := transport
+			if opTransport == transportRequest && (currOp == wotlib.OpObserveProperty || currOp == wotlib.OpSubscribeEvent) {
+				opTransport = transportLongPoll
+			}
+
+			method, _ := payload.DefaultMethod(currOp)
+			if formMethod := currForm.Method.Value(); formMethod != "" {
+				method = formMethod
+			}
+
+			contentType := currForm.ContentType.Value()
+			if contentType == "" {
+				contentType = codec.DefaultContentType
+			}
+
+			routes = append(routes, route{
+				method:      method,
+				op:          currOp,
+				name:        name,
+				transport:   opTransport,
+				contentType: contentType,
+				subprotocol: currForm.Subprotocol.Value(),
+			})
+		}
+
+		if len(routes) == 0 {
+			continue
+		}
+
+		target := s.serverURL(u.Scheme)
+		target.Path = (&url.URL{Path: "/"}).ResolveReference(u).Path
+		target.RawQuery = u.RawQuery
+
+		s.routes[target.Path] = append(s.routes[target.Path], routes...)
+
+		currForm.Href = wotlib.IDNode{{ID: target.String()}}
+		served = append(served, currForm)
+	}
+
+	return served, nil
+}
+
+// supportsTransport checks if an operation can be performed with the transport
+// of a form. Plain http forms without subprotocol are used for long polling,
+// properties only if the form is explicitly meant for observing
+func supportsTransport(op string, transport int, form wotlib.ExpandedForm) bool {
+	switch op {
+	case wotlib.OpSubscribeEvent:
+		return true
+	case wotlib.OpObserveProperty:
+		if transport == transportRequest {
+			return len(form.Op) > 0 && !form.HasOp(wotlib.OpReadProperty)
+		}
+
+		return true
+	}
+
+	return transport == transportRequest
+}
@@ -0,0 +1,65 @@
+package mockthing
+
+import (
+	"math"
+	"strings"
+
+	"github.com/connctd/wotlib"
+)
+
+// sampleValue creates a value matching the data schema. Constants and the
+// first enum value are preferred, numbers respect their bounds
+func sampleValue(schema wotlib.ExpandedDataSchema) interface{} {
+	if len(schema.Const) > 0 {
+		return schema.Const[0].Value
+	}
+
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0].Value
+	}
+
+	switch schema.DataType.Value() {
+	case wotlib.DataTypeBoolean:
+		return false
+	case wotlib.DataTypeInteger:
+		return sampleNumber(schema, math.Ceil, math.Floor)
+	case wotlib.DataTypeNumber:
+		identity := func(f float64) float64 { return f }
+		return sampleNumber(schema, identity, identity)
+	case wotlib.DataTypeString:
+		length, _ := schema.MinLength.Value()
+		return strings.Repeat("x", int(length))
+	case wotlib.DataTypeArray:
+		length, _ := schema.MinItems.Value()
+
+		items := make([]interface{}, int(length))
+		for i := range items {
+			items[i] = sampleValue(schema.Items.Value())
+		}
+
+		return items
+	case wotlib.DataTypeObject:
+		object := map[string]interface{}{}
+		for _, currProperty := range schema.Properties {
+			object[currProperty.Name.Value()] = sampleValue(currProperty.ExpandedDataSchema)
+		}
+
+		return object
+	}
+
+	return nil
+}
+
+// sampleNumber returns zero if it is within the bounds of the schema or the
+// bound closest to zero otherwise. Bounds are rounded towards the inside
+func sampleNumber(schema wotlib.ExpandedDataSchema, roundMin, roundMax func(float64) float64) float64 {
+	if min, ok := schema.Minimum.Value(); ok && min > 0 {
+		return roundMin(min)
+	}
+
+	if max, ok := schema.Maximum.Value(); ok && max < 0 {
+		return roundMax(max)
+	}
+
+	return 0
+}
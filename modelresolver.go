@@ -0,0 +1,346 @@
+package wotlib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// well known errors of the model resolver
+var (
+	ErrModelNotFound = errors.New("thing model not found")
+	ErrModelCycle    = errors.New("cyclic thing model reference")
+)
+
+// terms of thing model references
+const (
+	tmExtends = "tm:extends"
+	tmRef     = "tm:ref"
+)
+
+// ModelLoader loads the documents of thing models by their location. Locations
+// are urls or slash separated paths, relative references are resolved against
+// the location of the referencing model
+type ModelLoader interface {
+	Load(location string) ([]byte, error)
+}
+
+// ModelLoaderFunc is a function implementing ModelLoader
+type ModelLoaderFunc func(location string) ([]byte, error)
+
+// Load calls the function
+func (f ModelLoaderFunc) Load(location string) ([]byte, error) {
+	return f(location)
+}
+
+// MemoryModelLoader serves thing models from memory, keyed by location
+type MemoryModelLoader map[string][]byte
+
+// Load returns the model stored at the location
+func (l MemoryModelLoader) Load(location string) ([]byte, error) {
+	b, ok := l[location]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotFound, location)
+	}
+
+	return b, nil
+}
+
+// FileModelLoader loads thing models from a directory. Locations are
+// slash separated paths relative to the directory and can't leave it
+type FileModelLoader struct {
+	Dir string
+}
+
+// Load reads the model file at the location
+func (l FileModelLoader) Load(location string) ([]byte, error) {
+	if u, err := url.Parse(location); err != nil || u.Scheme != "" {
+		return nil, fmt.Errorf("%w: %s is not a file path", ErrModelNotFound, location)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(l.Dir, filepath.FromSlash(path.Clean("/"+location))))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotFound, location)
+	}
+
+	return b, err
+}
+
+// ModelResolver flattens thing models by following their tm:extends links and
+// tm:ref imports. Members of an extending model override the ones of the
+// extended model and members next to a tm:ref override the imported ones.
+// Objects are merged recursively, all other values are replaced
+type ModelResolver struct {
+	loader   ModelLoader
+	resolved map[string]map[string]interface{}
+}
+
+// NewModelResolver creates a resolver loading models with the given loader
+func NewModelResolver(loader ModelLoader) *ModelResolver {
+	return &ModelResolver{loader: loader, resolved: map[string]map[string]interface{}{}}
+}
+
+// Resolve loads the model at the location and returns it with all
+// references resolved, ready for instantiation
+func (r *ModelResolver) Resolve(location string) (*ThingModel, error) {
+	document, err := r.resolveModel(location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewThingModel(document)
+}
+
+// resolveModel returns the flattened document of a model. The stack contains
+// the models and references currently being resolved
+func (r *ModelResolver) resolveModel(location string, stack []string) (map[string]interface{}, error) {
+	if err := checkCycle(stack, location); err != nil {
+		return nil, err
+	}
+
+	if document, ok := r.resolved[location]; ok {
+		return copyValue(document).(map[string]interface{}), nil
+	}
+
+	b, err := r.loader.Load(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(b, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+
+	stack = append(stack, location)
+
+	// links to extended models are replaced by their content
+	var links []interface{}
+	var extended []map[string]interface{}
+	currLinks, _ := document["links"].([]interface{})
+	for _, currLink := range currLinks {
+		link, _ := currLink.(map[string]interface{})
+		if link["rel"] != tmExtends {
+			links = append(links, currLink)
+			continue
+		}
+
+		href, _ := link["href"].(string)
+		parent, err := r.resolveModel(resolveLocation(location, href), stack)
+		if err != nil {
+			return nil, err
+		}
+
+		extended = append(extended, parent)
+	}
+
+	if links == nil {
+		delete(document, "links")
+	} else {
+		document["links"] = links
+	}
+
+	merged := map[string]interface{}{}
+	for _, currParent := range extended {
+		merged = mergeValues(merged, currParent).(map[string]interface{})
+	}
+
+	merged = mergeValues(merged, document).(map[string]interface{})
+
+	resolved, err := r.resolveRefs(merged, merged, location, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	r.resolved[location] = resolved.(map[string]interface{})
+
+	return copyValue(resolved).(map[string]interface{}), nil
+}
+
+// resolveRefs replaces all objects containing tm:ref by the referenced
+// definition merged with the other members of the object
+func (r *ModelResolver) resolveRefs(value interface{}, document map[string]interface{}, location string, stack []string) (interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			var err error
+			if result[i], err = r.resolveRefs(v[i], document, location, stack); err != nil {
+				return nil, err
+			}
+		}
+
+		return result, nil
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, currValue := range v {
+			if key == tmRef {
+				continue
+			}
+
+			resolved, err := r.resolveRefs(currValue, document, location, stack)
+			if err != nil {
+				return nil, err
+			}
+
+			result[key] = resolved
+		}
+
+		ref, ok := v[tmRef].(string)
+		if !ok {
+			return result, nil
+		}
+
+		imported, err := r.resolveRef(ref, document, location, stack)
+		if err != nil {
+			return nil, err
+		}
+
+		return mergeValues(imported, result), nil
+	}
+
+	return value, nil
+}
+
+// resolveRef returns the resolved definition a tm:ref points to
+func (r *ModelResolver) resolveRef(ref string, document map[string]interface{}, location string, stack []string) (interface{}, error) {
+	target := ref
+	pointer := ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		target, pointer = ref[:i], ref[i+1:]
+	}
+
+	if target != "" {
+		targetLocation := resolveLocation(location, target)
+		if targetLocation != location {
+			targetDocument, err := r.resolveModel(targetLocation, stack)
+			if err != nil {
+				return nil, err
+			}
+
+			value, ok := lookupPointer(targetDocument, pointer)
+			if !ok {
+				return nil, &ModelError{Path: "/" + tmRef, Reason: fmt.Sprintf("%s does not exist", ref)}
+			}
+
+			return value, nil
+		}
+	}
+
+	key := location + "#" + pointer
+	if err := checkCycle(stack, key); err != nil {
+		return nil, err
+	}
+
+	value, ok := lookupPointer(document, pointer)
+	if !ok {
+		return nil, &ModelError{Path: "/" + tmRef, Reason: fmt.Sprintf("%s does not exist", ref)}
+	}
+
+	return r.resolveRefs(value, document, location, append(stack, key))
+}
+
+// checkCycle fails if the location is part of the stack
+func checkCycle(stack []string, location string) error {
+	for i, currLocation := range stack {
+		if currLocation == location {
+			return fmt.Errorf("%w: %s", ErrModelCycle, strings.Join(append(stack[i:], location), " -> "))
+		}
+	}
+
+	return nil
+}
+
+// resolveLocation resolves a reference against the location of a model.
+// Relative locations stay relative
+func resolveLocation(location string, ref string) string {
+	base, err := url.Parse(location)
+	if err != nil {
+		return ref
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	relative := !base.IsAbs() && !strings.HasPrefix(base.Path, "/")
+	if relative {
+		base.Path = "/" + base.Path
+	}
+
+	resolved := base.ResolveReference(u)
+	if relative && !resolved.IsAbs() {
+		resolved.Path = strings.TrimPrefix(resolved.Path, "/")
+	}
+
+	return resolved.String()
+}
+
+// lookupPointer returns the value a json pointer like "/properties/on" points to
+func lookupPointer(document interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return document, true
+	}
+
+	current := document
+	for _, currToken := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		currToken = strings.NewReplacer("~1", "/", "~0", "~").Replace(currToken)
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = object[currToken]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// mergeValues merges override into base. Objects are merged recursively,
+// other values of override replace the ones of base
+func mergeValues(base, override interface{}) interface{} {
+	baseObject, baseIsObject := base.(map[string]interface{})
+	overrideObject, overrideIsObject := override.(map[string]interface{})
+	if !baseIsObject || !overrideIsObject {
+		return copyValue(override)
+	}
+
+	result := copyValue(baseObject).(map[string]interface{})
+	for key, currValue := range overrideObject {
+		result[key] = mergeValues(result[key], currValue)
+	}
+
+	return result
+}
+
+// copyValue returns a deep copy of a json value
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = copyValue(v[i])
+		}
+
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, currValue := range v {
+			result[key] = copyValue(currValue)
+		}
+
+		return result
+	}
+
+	return value
+}
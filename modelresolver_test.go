@@ -0,0 +1,154 @@
+package wotlib
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testModels = MemoryModelLoader{
+	"models/base.tm.json": []byte(`{
+		"@context": ["https://www.w3.org/2019/wot/td/v1", {"tm": "https://www.w3.org/2019/wot/tm#"}],
+		"@type": "tm:ThingModel",
+		"title": "Base",
+		"securityDefinitions": {"nosec_sc": {"scheme": "nosec"}},
+		"security": ["nosec_sc"],
+		"links": [{"rel": "manual", "href": "https://example.com/manual.pdf"}],
+		"properties": {
+			"on": {"type": "boolean", "description": "base", "forms": [{"href": "http://lamp.local/on"}]}
+		}
+	}`),
+	"models/components.tm.json": []byte(`{
+		"@type": "tm:ThingModel",
+		"properties": {
+			"level": {"type": "integer", "minimum": 0, "maximum": 100, "forms": [{"href": "http://lamp.local/level"}]}
+		}
+	}`),
+	"models/lamps/dimmable.tm.json": []byte(`{
+		"@context": ["https://www.w3.org/2019/wot/td/v1", {"tm": "https://www.w3.org/2019/wot/tm#"}],
+		"@type": "tm:ThingModel",
+		"title": "Dimmable {{SERIAL}}",
+		"links": [{"rel": "tm:extends", "href": "../base.tm.json", "type": "application/tm+json"}],
+		"properties": {
+			"on": {"description": "overridden"},
+			"brightness": {"tm:ref": "../components.tm.json#/properties/level", "maximum": 255},
+			"mirror": {"tm:ref": "#/properties/brightness", "readOnly": true}
+		}
+	}`),
+	"models/cycle/a.tm.json": []byte(`{
+		"@type": "tm:ThingModel",
+		"links": [{"rel": "tm:extends", "href": "b.tm.json"}]
+	}`),
+	"models/cycle/b.tm.json": []byte(`{
+		"@type": "tm:ThingModel",
+		"links": [{"rel": "tm:extends", "href": "a.tm.json"}]
+	}`),
+	"models/cycle/self.tm.json": []byte(`{
+		"@type": "tm:ThingModel",
+		"properties": {
+			"a": {"tm:ref": "#/properties/b"},
+			"b": {"tm:ref": "#/properties/a"}
+		}
+	}`),
+}
+
+func TestModelResolver(t *testing.T) {
+	m, err := NewModelResolver(testModels).Resolve("models/lamps/dimmable.tm.json")
+	if err != nil {
+		t.Fatalf("Failed to resolve model: %v", err)
+	}
+
+	if affordances := m.Affordances(); !reflect.DeepEqual(affordances, []string{"#/properties/brightness", "#/properties/mirror", "#/properties/on"}) {
+		t.Fatalf("Unexpected affordances %v", affordances)
+	}
+
+	document := m.document
+	if links := document["links"].([]interface{}); len(links) != 1 || links[0].(map[string]interface{})["rel"] != "manual" {
+		t.Fatalf("Expected extends link to be replaced by the extended model, got %v", links)
+	}
+
+	properties := document["properties"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"on":         map[string]interface{}{"type": "boolean", "description": "overridden", "forms": []interface{}{map[string]interface{}{"href": "http://lamp.local/on"}}},
+		"brightness": map[string]interface{}{"type": "integer", "minimum": float64(0), "maximum": float64(255), "forms": []interface{}{map[string]interface{}{"href": "http://lamp.local/level"}}},
+		"mirror":     map[string]interface{}{"type": "integer", "minimum": float64(0), "maximum": float64(255), "readOnly": true, "forms": []interface{}{map[string]interface{}{"href": "http://lamp.local/level"}}},
+	}
+
+	if !reflect.DeepEqual(properties, expected) {
+		t.Fatalf("Unexpected properties %v", properties)
+	}
+
+	td, err := m.Instantiate(map[string]interface{}{"SERIAL": "4711"})
+	if err != nil {
+		t.Fatalf("Failed to instantiate resolved model: %v", err)
+	}
+
+	if len(td.Properties) != 3 {
+		t.Fatalf("Expected 3 properties, got %d", len(td.Properties))
+	}
+}
+
+func TestModelResolverErrors(t *testing.T) {
+	testCases := []struct {
+		location string
+		expected error
+	}{
+		{"models/cycle/a.tm.json", ErrModelCycle},
+		{"models/cycle/self.tm.json", ErrModelCycle},
+		{"models/missing.tm.json", ErrModelNotFound},
+	}
+
+	for _, currTestCase := range testCases {
+		if _, err := NewModelResolver(testModels).Resolve(currTestCase.location); !errors.Is(err, currTestCase.expected) {
+			t.Fatalf("Expected %v for %s, got %v", currTestCase.expected, currTestCase.location, err)
+		}
+	}
+
+	loader := MemoryModelLoader{"m.json": []byte(`{"@type": "tm:ThingModel", "properties": {"a": {"tm:ref": "#/properties/missing"}}}`)}
+
+	var modelErr *ModelError
+	if _, err := NewModelResolver(loader).Resolve("m.json"); !errors.As(err, &modelErr) {
+		t.Fatalf("Expected model error for dangling reference, got %v", err)
+	}
+}
+
+func TestFileModelLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "models")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for location, currModel := range testModels {
+		file := filepath.Join(dir, filepath.FromSlash(location))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		if err := ioutil.WriteFile(file, currModel, 0644); err != nil {
+			t.Fatalf("Failed to write model: %v", err)
+		}
+	}
+
+	loader := FileModelLoader{Dir: filepath.Join(dir, "models")}
+
+	m, err := NewModelResolver(loader).Resolve("lamps/dimmable.tm.json")
+	if err != nil {
+		t.Fatalf("Failed to resolve model: %v", err)
+	}
+
+	if len(m.Affordances()) != 3 {
+		t.Fatalf("Unexpected affordances %v", m.Affordances())
+	}
+
+	if _, err := loader.Load("../../etc/passwd"); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("Expected locations outside the directory to be confined, got %v", err)
+	}
+
+	if _, err := loader.Load("https://example.com/model.json"); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("Expected urls to be rejected, got %v", err)
+	}
+}
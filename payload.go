@@ -0,0 +1,214 @@
+package wotlib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MappingError is returned if semantic values can't be mapped onto a data schema
+type MappingError struct {
+	Type   string
+	Reason string
+}
+
+func (e *MappingError) Error() string {
+	if e.Type == "" {
+		return "failed to map semantic values: " + e.Reason
+	}
+
+	return fmt.Sprintf("failed to map semantic type %s: %s", e.Type, e.Reason)
+}
+
+// annotatedProperty is a property somewhere inside a data schema which
+// carries semantic annotations
+type annotatedProperty struct {
+	Path  []string
+	Types []string
+}
+
+// BuildPayload builds an object payload for the data schema from values keyed
+// by semantic type iris. Each value is assigned to the (possibly nested) property
+// annotated with its type. It fails if a type is unknown to the schema, if more
+// than one property is annotated with a type or if a required property has no value.
+// The resulting payload is validated against the schema
+func (s ExpandedDataSchema) BuildPayload(values map[string]interface{}) (map[string]interface{}, error) {
+	if s.DataType.Value() != DataTypeObject {
+		return nil, &MappingError{Reason: "data schema is not an object schema"}
+	}
+
+	annotated := s.annotatedProperties(nil)
+	payload := map[string]interface{}{}
+
+	for _, semanticType := range sortedKeys(values) {
+		property, err := findAnnotatedProperty(annotated, semanticType)
+		if err != nil {
+			return nil, err
+		}
+
+		setPath(payload, property.Path, values[semanticType])
+	}
+
+	if err := s.checkRequired(nil, payload); err != nil {
+		return nil, err
+	}
+
+	if err := s.Validate(payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// SemanticValues is the inverse of BuildPayload. It extracts all values
+// of annotated properties from a payload and keys them by their semantic types.
+// It fails if the same semantic type annotates more than one property in the payload
+func (s ExpandedDataSchema) SemanticValues(payload interface{}) (map[string]interface{}, error) {
+	normalized, err := normalizeValue(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	origins := map[string]string{}
+
+	for _, currProperty := range s.annotatedProperties(nil) {
+		value, ok := getPath(normalized, currProperty.Path)
+		if !ok {
+			continue
+		}
+
+		path := "/" + strings.Join(currProperty.Path, "/")
+		for _, semanticType := range currProperty.Types {
+			if origin, exists := origins[semanticType]; exists {
+				return nil, &MappingError{
+					Type:   semanticType,
+					Reason: fmt.Sprintf("ambiguous annotation of %s and %s", origin, path),
+				}
+			}
+
+			origins[semanticType] = path
+			result[semanticType] = value
+		}
+	}
+
+	return result, nil
+}
+
+// BuildInput builds the input payload of the action from values keyed by semantic types
+func (t ExpandedActionAffordance) BuildInput(values map[string]interface{}) (map[string]interface{}, error) {
+	return t.Input.Value().BuildPayload(values)
+}
+
+func (s ExpandedDataSchema) annotatedProperties(parent []string) []annotatedProperty {
+	var result []annotatedProperty
+
+	for _, currProperty := range s.Properties {
+		path := append(append([]string{}, parent...), currProperty.Name.Value())
+
+		if len(currProperty.Type) > 0 {
+			result = append(result, annotatedProperty{Path: path, Types: currProperty.Type})
+		}
+
+		result = append(result, currProperty.annotatedProperties(path)...)
+	}
+
+	return result
+}
+
+// checkRequired checks recursively if all required properties of objects
+// which are part of the payload are present
+func (s ExpandedDataSchema) checkRequired(parent []string, payload map[string]interface{}) error {
+	for _, name := range s.Required.Values() {
+		if _, ok := payload[name]; !ok {
+			return &MappingError{
+				Reason: fmt.Sprintf("no value for required property /%s", strings.Join(append(append([]string{}, parent...), name), "/")),
+			}
+		}
+	}
+
+	for _, currProperty := range s.Properties {
+		nested, ok := payload[currProperty.Name.Value()].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		path := append(append([]string{}, parent...), currProperty.Name.Value())
+		if err := currProperty.checkRequired(path, nested); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findAnnotatedProperty(annotated []annotatedProperty, semanticType string) (annotatedProperty, error) {
+	var matches []annotatedProperty
+
+	for _, currProperty := range annotated {
+		for _, currType := range currProperty.Types {
+			if currType == semanticType {
+				matches = append(matches, currProperty)
+				break
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return annotatedProperty{}, &MappingError{Type: semanticType, Reason: "no property is annotated with this type"}
+	case 1:
+		return matches[0], nil
+	}
+
+	paths := make([]string, len(matches))
+	for i := range matches {
+		paths[i] = "/" + strings.Join(matches[i].Path, "/")
+	}
+
+	return annotatedProperty{}, &MappingError{
+		Type:   semanticType,
+		Reason: "ambiguous annotation of " + strings.Join(paths, " and "),
+	}
+}
+
+func setPath(payload map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := payload[name].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			payload[name] = nested
+		}
+
+		payload = nested
+	}
+
+	payload[path[len(path)-1]] = value
+}
+
+func getPath(payload interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		asMap, ok := payload.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		payload, ok = asMap[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return payload, true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
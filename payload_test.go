@@ -0,0 +1,125 @@
+package wotlib
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBuildInput(t *testing.T) {
+	expandedTD, err := FromBytes(testTDOne)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	setColor := expandedTD.GetActionAffordances(ActionConstraint{Name: asStringPointer("lamp-setColor")})[0]
+
+	payload, err := setColor.BuildInput(map[string]interface{}{
+		iotSchema.IRIPrefix("RColourData"): 255,
+		iotSchema.IRIPrefix("GColourData"): 0,
+		iotSchema.IRIPrefix("BColourData"): 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build payload: %v", err)
+	}
+
+	expected := map[string]interface{}{"red": 255, "green": 0, "blue": 10}
+	if !reflect.DeepEqual(payload, expected) {
+		t.Fatalf("Unexpected payload. Expected: %v, Got: %v", expected, payload)
+	}
+
+	_, err = setColor.BuildInput(map[string]interface{}{
+		iotSchema.IRIPrefix("StatusData"): true,
+	})
+
+	var mappingErr *MappingError
+	if !errors.As(err, &mappingErr) || mappingErr.Type != iotSchema.IRIPrefix("StatusData") {
+		t.Fatalf("Expected mapping error for unknown type, got %v", err)
+	}
+}
+
+func TestBuildPayloadFailures(t *testing.T) {
+	schema := ExpandedDataSchema{
+		DataType: IDNode{{ID: DataTypeObject}},
+		Required: StringNode{{Value: "on"}},
+		Properties: []ExpandedDataProperty{
+			{
+				Name:               StringNode{{Value: "on"}},
+				Type:               []string{iotSchema.IRIPrefix("StatusData")},
+				ExpandedDataSchema: ExpandedDataSchema{DataType: IDNode{{ID: DataTypeBoolean}}},
+			},
+			{
+				Name:               StringNode{{Value: "lower"}},
+				Type:               []string{iotSchema.IRIPrefix("Temperature")},
+				ExpandedDataSchema: ExpandedDataSchema{DataType: IDNode{{ID: DataTypeNumber}}},
+			},
+			{
+				Name:               StringNode{{Value: "upper"}},
+				Type:               []string{iotSchema.IRIPrefix("Temperature")},
+				ExpandedDataSchema: ExpandedDataSchema{DataType: IDNode{{ID: DataTypeNumber}}},
+			},
+		},
+	}
+
+	var failureTests = []struct {
+		Name   string
+		Values map[string]interface{}
+	}{
+		{
+			Name: "Ambiguous annotation",
+			Values: map[string]interface{}{
+				iotSchema.IRIPrefix("StatusData"):  true,
+				iotSchema.IRIPrefix("Temperature"): 21.5,
+			},
+		},
+		{
+			Name:   "Missing required value",
+			Values: map[string]interface{}{},
+		},
+	}
+
+	for _, currTest := range failureTests {
+		t.Run(currTest.Name, func(t *testing.T) {
+			_, err := schema.BuildPayload(currTest.Values)
+
+			var mappingErr *MappingError
+			if !errors.As(err, &mappingErr) {
+				t.Fatalf(currTest.Name+" failed. Expected mapping error, got %v", err)
+			}
+		})
+	}
+
+	_, err := schema.BuildPayload(map[string]interface{}{iotSchema.IRIPrefix("StatusData"): "yes"})
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+}
+
+func TestSemanticValues(t *testing.T) {
+	expandedTD, err := FromBytes(testTDOne)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	color := expandedTD.GetPropertyAffordances(PropertyConstraint{Name: asStringPointer("lamp-color")})[0]
+
+	values, err := color.SemanticValues(map[string]interface{}{
+		"red":   10,
+		"green": 20,
+		"time":  "2020-05-01T10:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("Failed to decode values: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		iotSchema.IRIPrefix("RColourData"): float64(10),
+		iotSchema.IRIPrefix("GColourData"): float64(20),
+		"http://schema.org/DateTime":       "2020-05-01T10:00:00Z",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("Unexpected values. Expected: %v, Got: %v", expected, values)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/piprate/json-gold/ld"
 )
@@ -15,22 +16,40 @@ var (
 
 // FromResponse tries to extract an expanded wot td from a
 // response object
-func FromResponse(resp *http.Response) (ExpandedThingDescription, error) {
+func FromResponse(resp *http.Response, opts ...FromBytesOption) (ExpandedThingDescription, error) {
 	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return ExpandedThingDescription{}, err
 	}
 
-	return FromBytes(bytes)
+	return FromBytes(bytes, opts...)
 }
 
-// FromBytes expands input bytes and converts it to ExpandedThingDescription
-func FromBytes(b []byte) (ExpandedThingDescription, error) {
+// FromBytes expands input bytes and converts it to ExpandedThingDescription.
+// Options can decrypt the td and require it to be signed by a trusted key,
+// without options the td is processed as it is
+func FromBytes(b []byte, opts ...FromBytesOption) (ExpandedThingDescription, error) {
+	var options fromBytesOptions
+	for _, currOpt := range opts {
+		if err := currOpt(&options); err != nil {
+			return ExpandedThingDescription{}, err
+		}
+	}
+
+	b, err := options.decrypt(b)
+	if err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	if err := options.verify(b); err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
 	proc := ld.NewJsonLdProcessor()
 
 	// lib is expecting a map
 	var asMap map[string]interface{}
-	err := json.Unmarshal(b, &asMap)
+	err = json.Unmarshal(b, &asMap)
 	if err != nil {
 		return ExpandedThingDescription{}, err
 	}
@@ -53,9 +72,45 @@ func FromBytes(b []byte) (ExpandedThingDescription, error) {
 		return ExpandedThingDescription{}, err
 	}
 
+	td[0].ResolveHrefs()
+	td[0].ResolveSecurity()
+	td[0].source = newSource(b, td[0])
+
 	return td[0], nil
 }
 
+// ResolveHrefs resolves relative hrefs of all forms against the base of the td.
+// FromBytes resolves the hrefs of the returned td
+func (e *ExpandedThingDescription) ResolveHrefs() {
+	base, err := url.Parse(e.Base.Value())
+	if err != nil || !base.IsAbs() {
+		return
+	}
+
+	resolve := func(forms ExpandedFormNode) {
+		for i := range forms {
+			for j := range forms[i].Href {
+				ref, err := url.Parse(forms[i].Href[j].ID)
+				if err == nil && !ref.IsAbs() {
+					forms[i].Href[j].ID = base.ResolveReference(ref).String()
+				}
+			}
+		}
+	}
+
+	for i := range e.Properties {
+		resolve(e.Properties[i].Form)
+	}
+
+	for i := range e.Actions {
+		resolve(e.Actions[i].Form)
+	}
+
+	for i := range e.Events {
+		resolve(e.Events[i].Form)
+	}
+}
+
 // Compact compacts the thing description
 func (e *ExpandedThingDescription) Compact() (json.RawMessage, error) {
 	compactedBytes, err := compact(e)
@@ -86,6 +141,16 @@ func (e *ExpandedActionAffordance) Compact() (json.RawMessage, error) {
 	return json.RawMessage(compactedBytes), nil
 }
 
+// Compact compacts an expanded event affordance
+func (e *ExpandedEventAffordance) Compact() (json.RawMessage, error) {
+	compactedBytes, err := compact(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(compactedBytes), nil
+}
+
 func compact(e interface{}) ([]byte, error) {
 	proc := ld.NewJsonLdProcessor()
 
@@ -0,0 +1,445 @@
+package wotlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// RDFFormat is a serialization of rdf
+type RDFFormat string
+
+// supported rdf formats
+const (
+	RDFNQuads   RDFFormat = "application/n-quads"
+	RDFNTriples RDFFormat = "application/n-triples"
+	RDFTurtle   RDFFormat = "text/turtle"
+)
+
+// RDFPrefixes returns the prefixes used to abbreviate iris in turtle. These
+// are the prefixes of the default context including those added by
+// AppendSchema, sorted by prefix
+func RDFPrefixes() []SchemaMapping {
+	prefixes := make([]SchemaMapping, 0, len(DefaultContext))
+	for currPrefix, currValue := range DefaultContext {
+		if iri, ok := currValue.(string); ok && iri != "" {
+			prefixes = append(prefixes, SchemaMapping{Prefix: SchemaPrefix(currPrefix), IRI: iri})
+		}
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+
+	return prefixes
+}
+
+// turtleLocalName matches the local names which can be written as prefixed names
+var turtleLocalName = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_-])?$`)
+
+// ToRDF serializes the td as rdf. N-Quads put the td into a named graph
+// identified by its id, N-Triples and Turtle use the default graph
+func (t ExpandedThingDescription) ToRDF(format RDFFormat) ([]byte, error) {
+	return toRDF([]ExpandedThingDescription{t}, format)
+}
+
+// ToRDF serializes all tds of the set as rdf. N-Quads put each td into a
+// named graph identified by its id, N-Triples and Turtle use the default graph.
+// Blank nodes are unique across all tds
+func (s *ExpandedThingDescriptionSet) ToRDF(format RDFFormat) ([]byte, error) {
+	ids := make([]string, 0, len(*s))
+	for id := range *s {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	tds := make([]ExpandedThingDescription, 0, len(ids))
+	for _, currID := range ids {
+		tds = append(tds, (*s)[currID])
+	}
+
+	return toRDF(tds, format)
+}
+
+// FromRDF builds a td from N-Quads or N-Triples. The triples of all graphs are
+// merged and have to describe exactly one thing. Information which isn't part
+// of the rdf form of a td, like the names of security definitions, is lost
+func FromRDF(b []byte) (ExpandedThingDescription, error) {
+	dataset, err := ld.ParseNQuads(string(b))
+	if err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	// n-quads of a td carry the id of the td as graph name
+	merged := ld.NewRDFDataset()
+	for _, currQuads := range dataset.Graphs {
+		merged.Graphs["@default"] = append(merged.Graphs["@default"], currQuads...)
+	}
+
+	opts := DefaultJSONDLDOptions.Copy()
+	opts.UseNativeTypes = true
+
+	nodes, err := ld.NewJsonLdApi().FromRDF(merged, opts)
+	if err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	for _, currNode := range nodes {
+		if node, ok := currNode.(map[string]interface{}); ok {
+			separateDataTypes(node)
+		}
+	}
+
+	root, byID, err := findThingNode(nodes)
+	if err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	id, _ := root["@id"].(string)
+	thing := embedBlankNodes(root, byID, map[string]bool{id: true}).(map[string]interface{})
+	if strings.HasPrefix(id, "_:") {
+		delete(thing, "@id")
+	}
+
+	expandedBytes, err := json.Marshal(thing)
+	if err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	var td ExpandedThingDescription
+	if err := json.Unmarshal(expandedBytes, &td); err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	td.ResolveSecurity()
+
+	return td, nil
+}
+
+// node returns the td as json-ld node object. Anonymous tds become blank nodes
+func (t ExpandedThingDescription) node() (map[string]interface{}, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var node map[string]interface{}
+	if err := json.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+
+	if t.IsAnonymous() {
+		delete(node, "@id")
+	}
+
+	return node, nil
+}
+
+func toRDF(tds []ExpandedThingDescription, format RDFFormat) ([]byte, error) {
+	switch format {
+	case RDFNQuads, RDFNTriples, RDFTurtle:
+	default:
+		return nil, fmt.Errorf("unsupported rdf format %q", format)
+	}
+
+	input := make([]interface{}, 0, len(tds))
+	for _, currTD := range tds {
+		node, err := currTD.node()
+		if err != nil {
+			return nil, err
+		}
+
+		if format == RDFNQuads && !currTD.IsAnonymous() {
+			input = append(input, map[string]interface{}{"@id": currTD.ID, "@graph": []interface{}{node}})
+			continue
+		}
+
+		input = append(input, node)
+	}
+
+	rdf, err := ld.NewJsonLdProcessor().ToRDF(input, DefaultJSONDLDOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset, ok := rdf.(*ld.RDFDataset)
+	if !ok {
+		return nil, fmt.Errorf("unexpected rdf result %T", rdf)
+	}
+
+	if format == RDFTurtle {
+		return writeTurtle(dataset), nil
+	}
+
+	return writeNQuads(dataset), nil
+}
+
+// writeNQuads writes the statements of all graphs in lexical order
+func writeNQuads(dataset *ld.RDFDataset) []byte {
+	var lines []string
+	for graphName, currQuads := range dataset.Graphs {
+		for _, currQuad := range currQuads {
+			line := formatTerm(currQuad.Subject, formatIRI) + " " + formatTerm(currQuad.Predicate, formatIRI) + " " + formatTerm(currQuad.Object, formatIRI)
+			if graphName != "@default" {
+				graph := graphName
+				if !strings.HasPrefix(graphName, "_:") {
+					graph = formatIRI(graphName)
+				}
+
+				line += " " + graph
+			}
+
+			lines = append(lines, line+" .\n")
+		}
+	}
+
+	sort.Strings(lines)
+
+	var buf bytes.Buffer
+	for _, currLine := range lines {
+		buf.WriteString(currLine)
+	}
+
+	return buf.Bytes()
+}
+
+// writeTurtle writes the default graph grouped by subjects and abbreviates
+// iris with the prefixes used
+func writeTurtle(dataset *ld.RDFDataset) []byte {
+	prefixes := RDFPrefixes()
+	used := map[SchemaPrefix]bool{}
+	abbreviate := func(iri string) string {
+		// the longest matching iri wins if prefixes overlap
+		var match *SchemaMapping
+		for i, currPrefix := range prefixes {
+			local := strings.TrimPrefix(iri, currPrefix.IRI)
+			if len(local) < len(iri) && turtleLocalName.MatchString(local) && (match == nil || len(currPrefix.IRI) > len(match.IRI)) {
+				match = &prefixes[i]
+			}
+		}
+
+		if match == nil {
+			return formatIRI(iri)
+		}
+
+		used[match.Prefix] = true
+		return match.Prefix.String() + ":" + strings.TrimPrefix(iri, match.IRI)
+	}
+
+	statements := map[string]map[string][]string{}
+	for _, currQuad := range dataset.Graphs["@default"] {
+		subject := formatTerm(currQuad.Subject, abbreviate)
+
+		predicate := "a"
+		if currQuad.Predicate.GetValue() != ld.RDFType {
+			predicate = formatTerm(currQuad.Predicate, abbreviate)
+		}
+
+		if statements[subject] == nil {
+			statements[subject] = map[string][]string{}
+		}
+
+		statements[subject][predicate] = append(statements[subject][predicate], formatTerm(currQuad.Object, abbreviate))
+	}
+
+	subjects := make([]string, 0, len(statements))
+	for currSubject := range statements {
+		subjects = append(subjects, currSubject)
+	}
+
+	sort.Strings(subjects)
+
+	var body bytes.Buffer
+	for _, currSubject := range subjects {
+		objects := statements[currSubject]
+
+		predicates := make([]string, 0, len(objects))
+		for currPredicate := range objects {
+			predicates = append(predicates, currPredicate)
+		}
+
+		// types are written first
+		sort.Slice(predicates, func(i, j int) bool {
+			if predicates[i] == "a" || predicates[j] == "a" {
+				return predicates[i] == "a"
+			}
+
+			return predicates[i] < predicates[j]
+		})
+
+		lines := make([]string, 0, len(predicates))
+		for _, currPredicate := range predicates {
+			sort.Strings(objects[currPredicate])
+			lines = append(lines, currPredicate+" "+strings.Join(objects[currPredicate], ", "))
+		}
+
+		body.WriteString("\n" + currSubject + " " + strings.Join(lines, " ;\n    ") + " .\n")
+	}
+
+	var buf bytes.Buffer
+	for _, currPrefix := range prefixes {
+		if used[currPrefix.Prefix] {
+			fmt.Fprintf(&buf, "@prefix %s: %s .\n", currPrefix.Prefix, formatIRI(currPrefix.IRI))
+		}
+	}
+
+	buf.Write(body.Bytes())
+
+	return buf.Bytes()
+}
+
+func formatIRI(iri string) string {
+	return "<" + iri + ">"
+}
+
+// formatTerm formats a term as in n-triples, iris are written by the given function
+func formatTerm(node ld.Node, iri func(string) string) string {
+	switch n := node.(type) {
+	case *ld.BlankNode:
+		return n.Attribute
+	case *ld.Literal:
+		literal := `"` + escapeLiteral(n.Value) + `"`
+		switch {
+		case n.Datatype == ld.RDFLangString:
+			literal += "@" + n.Language
+		case n.Datatype != "" && n.Datatype != ld.XSDString:
+			literal += "^^" + iri(n.Datatype)
+		}
+
+		return literal
+	}
+
+	return iri(node.GetValue())
+}
+
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func escapeLiteral(value string) string {
+	return literalEscaper.Replace(value)
+}
+
+// findThingNode finds the node of the thing among the nodes of a flattened
+// td. Without a node typed as thing the only node not referenced by others is used
+func findThingNode(nodes []interface{}) (map[string]interface{}, map[string]map[string]interface{}, error) {
+	byID := map[string]map[string]interface{}{}
+	referenced := map[string]bool{}
+
+	var things []map[string]interface{}
+	for _, currNode := range nodes {
+		node, ok := currNode.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		id, _ := node["@id"].(string)
+		byID[id] = node
+
+		types, _ := node["@type"].([]interface{})
+		for _, currType := range types {
+			if currType == SchemaWoT.IRIPrefix("Thing") {
+				things = append(things, node)
+				break
+			}
+		}
+
+		for key, currValue := range node {
+			if key != "@id" {
+				collectReferences(currValue, referenced)
+			}
+		}
+	}
+
+	if len(things) == 0 {
+		for id, currNode := range byID {
+			if !referenced[id] {
+				things = append(things, currNode)
+			}
+		}
+	}
+
+	if len(things) != 1 {
+		return nil, nil, fmt.Errorf("expected rdf of one thing, found %d", len(things))
+	}
+
+	return things[0], byID, nil
+}
+
+// separateDataTypes moves the types of data schemas from @type to rdf:type,
+// where the expanded form of a td keeps them
+func separateDataTypes(node map[string]interface{}) {
+	types, _ := node["@type"].([]interface{})
+
+	var remaining, dataTypes []interface{}
+	for _, currType := range types {
+		if iri, _ := currType.(string); strings.HasPrefix(iri, SchemaJSON.IRI) {
+			dataTypes = append(dataTypes, map[string]interface{}{"@id": iri})
+			continue
+		}
+
+		remaining = append(remaining, currType)
+	}
+
+	if len(dataTypes) == 0 {
+		return
+	}
+
+	node["@type"] = remaining
+	node[SchemaRdfType.IRIPrefix("type")] = dataTypes
+}
+
+func collectReferences(value interface{}, referenced map[string]bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, currValue := range v {
+			collectReferences(currValue, referenced)
+		}
+	case map[string]interface{}:
+		if id, ok := v["@id"].(string); ok {
+			referenced[id] = true
+		}
+
+		for key, currValue := range v {
+			if key != "@id" {
+				collectReferences(currValue, referenced)
+			}
+		}
+	}
+}
+
+// embedBlankNodes replaces references to blank nodes by the nodes themselves
+// to restore the nested form of a td
+func embedBlankNodes(value interface{}, byID map[string]map[string]interface{}, embedding map[string]bool) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, currValue := range v {
+			result[i] = embedBlankNodes(currValue, byID, embedding)
+		}
+
+		return result
+	case map[string]interface{}:
+		if id, ok := v["@id"].(string); ok && len(v) == 1 && strings.HasPrefix(id, "_:") && !embedding[id] {
+			if node, ok := byID[id]; ok {
+				embedding[id] = true
+				defer delete(embedding, id)
+
+				return embedBlankNodes(node, byID, embedding)
+			}
+		}
+
+		result := make(map[string]interface{}, len(v))
+		for key, currValue := range v {
+			result[key] = embedBlankNodes(currValue, byID, embedding)
+		}
+
+		return result
+	}
+
+	return value
+}
@@ -0,0 +1,193 @@
+package wotlib
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestToRDF(t *testing.T) {
+	AppendSchema(iotSchema)
+
+	td, err := FromBytes(testTDOne)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	tests := []struct {
+		format     RDFFormat
+		contains   []string
+		notContain []string
+	}{
+		{RDFNTriples,
+			[]string{`<` + td.ID + `> <https://www.w3.org/2019/wot/td#title> "LightOne" .`},
+			[]string{"@prefix", `"LightOne" <`},
+		},
+		{RDFNQuads,
+			[]string{`<` + td.ID + `> <https://www.w3.org/2019/wot/td#title> "LightOne" <` + td.ID + `> .`},
+			[]string{"@prefix"},
+		},
+		{RDFTurtle,
+			[]string{
+				"@prefix iot: <http://iotschema.org/> .",
+				"@prefix wot: <https://www.w3.org/2019/wot/td#> .",
+				"<" + td.ID + "> a iot:BinarySwitchControl, iot:ColourControl, iot:DimmerControl, wot:Thing ;",
+				`wot:isSafe "true"^^<http://www.w3.org/2001/XMLSchema#boolean>`,
+				"hypermedia:hasOperationType wot:readProperty",
+			},
+			[]string{"@prefix tm:", "<https://www.w3.org/2019/wot/td#title>"},
+		},
+	}
+
+	for _, currTest := range tests {
+		b, err := td.ToRDF(currTest.format)
+		if err != nil {
+			t.Fatalf("Failed to serialize %s: %v", currTest.format, err)
+		}
+
+		for _, currExpected := range currTest.contains {
+			if !strings.Contains(string(b), currExpected) {
+				t.Fatalf("Expected %s to contain %q:\n%s", currTest.format, currExpected, b)
+			}
+		}
+
+		for _, currUnexpected := range currTest.notContain {
+			if strings.Contains(string(b), currUnexpected) {
+				t.Fatalf("Expected %s not to contain %q:\n%s", currTest.format, currUnexpected, b)
+			}
+		}
+
+		again, _ := td.ToRDF(currTest.format)
+		if string(again) != string(b) {
+			t.Fatalf("Expected %s output to be stable", currTest.format)
+		}
+	}
+
+	if _, err := td.ToRDF("application/rdf+xml"); err == nil {
+		t.Fatalf("Expected unsupported format to fail")
+	}
+}
+
+func TestSetToRDF(t *testing.T) {
+	set := NewExpandedThingDescriptionSet()
+	for _, currID := range []string{"urn:dev:ops:a", "urn:dev:ops:b"} {
+		td, err := FromBytes([]byte(`{
+			"@context": "https://www.w3.org/2019/wot/td/v1",
+			"id": "` + currID + `",
+			"title": "Lamp",
+			"properties": {"on": {"type": "boolean", "forms": [{"href": "http://lamp.local/on"}]}}
+		}`))
+		if err != nil {
+			t.Fatalf("Failed to build expanded td: %v", err)
+		}
+
+		set.Append(td)
+	}
+
+	b, err := set.ToRDF(RDFNQuads)
+	if err != nil {
+		t.Fatalf("Failed to serialize set: %v", err)
+	}
+
+	// blank nodes of different tds must not be merged
+	graphs := map[string]string{}
+	for _, currLine := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		fields := strings.Fields(currLine)
+		graph := fields[len(fields)-2]
+
+		if !strings.HasPrefix(fields[0], "_:") {
+			continue
+		}
+
+		if other, ok := graphs[fields[0]]; ok && other != graph {
+			t.Fatalf("Blank node %s is used in %s and %s", fields[0], other, graph)
+		}
+
+		graphs[fields[0]] = graph
+	}
+
+	var names []string
+	for _, currGraph := range graphs {
+		names = append(names, currGraph)
+	}
+
+	sort.Strings(names)
+	if len(names) == 0 || names[0] != "<urn:dev:ops:a>" || names[len(names)-1] != "<urn:dev:ops:b>" {
+		t.Fatalf("Expected a graph per td, got %v", names)
+	}
+
+	// each td can be read back from its graph
+	var lines []string
+	for _, currLine := range strings.Split(string(b), "\n") {
+		if strings.HasSuffix(currLine, "<urn:dev:ops:b> .") {
+			lines = append(lines, currLine)
+		}
+	}
+
+	td, err := FromRDF([]byte(strings.Join(lines, "\n")))
+	if err != nil || td.ID != "urn:dev:ops:b" || len(td.Properties) != 1 {
+		t.Fatalf("Failed to read td from its graph: %+v (%v)", td, err)
+	}
+
+	// without a thing the whole set describes more than one thing
+	if _, err := FromRDF(b); err == nil {
+		t.Fatalf("Expected rdf of several things to fail")
+	}
+}
+
+func TestFromRDF(t *testing.T) {
+	expected, err := FromBytes(testTDOne)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	for _, currFormat := range []RDFFormat{RDFNQuads, RDFNTriples} {
+		b, err := expected.ToRDF(currFormat)
+		if err != nil {
+			t.Fatalf("Failed to serialize td: %v", err)
+		}
+
+		td, err := FromRDF(b)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", currFormat, err)
+		}
+
+		if td.ID != expected.ID || td.Title.Value() != expected.Title.Value() || len(td.Type) != len(expected.Type) {
+			t.Fatalf("Unexpected thing %s %q %v", td.ID, td.Title.Value(), td.Type)
+		}
+
+		if len(td.Properties) != len(expected.Properties) || len(td.Actions) != len(expected.Actions) {
+			t.Fatalf("Expected %d properties and %d actions, got %d and %d", len(expected.Properties), len(expected.Actions), len(td.Properties), len(td.Actions))
+		}
+
+		for _, currExpected := range expected.Properties {
+			found := false
+			for _, currProperty := range td.Properties {
+				if currProperty.Name.Value() != currExpected.Name.Value() {
+					continue
+				}
+
+				found = true
+				if currProperty.Form.Value().Href.Value() != currExpected.Form.Value().Href.Value() ||
+					currProperty.DataType.Value() != currExpected.DataType.Value() ||
+					len(currProperty.Properties) != len(currExpected.Properties) {
+					t.Fatalf("Unexpected property %+v", currProperty)
+				}
+			}
+
+			if !found {
+				t.Fatalf("Missing property %s", currExpected.Name.Value())
+			}
+		}
+
+		for _, currAction := range td.Actions {
+			if currAction.Name.Value() == "lamp-setOn" && (!currAction.IsIdempotent.Value() || currAction.IsSafe.Value()) {
+				t.Fatalf("Unexpected action flags %+v", currAction)
+			}
+		}
+	}
+
+	if _, err := FromRDF([]byte("not rdf")); err == nil {
+		t.Fatalf("Expected invalid rdf to fail")
+	}
+}
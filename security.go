@@ -0,0 +1,84 @@
+package wotlib
+
+// ExpandedSecurityScheme is a security definition of a td. Index is the name
+// under which the definition is referenced by security configurations
+type ExpandedSecurityScheme struct {
+	Index         string     `json:"@index,omitempty"`
+	Scheme        IDNode     `json:"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"`
+	Description   StringNode `json:"https://www.w3.org/2019/wot/td#description"`
+	Proxy         IDNode     `json:"https://www.w3.org/2019/wot/security#proxy"`
+	In            StringNode `json:"https://www.w3.org/2019/wot/security#in"`
+	Name          StringNode `json:"https://www.w3.org/2019/wot/security#name"`
+	QOP           StringNode `json:"https://www.w3.org/2019/wot/security#qop"`
+	Alg           StringNode `json:"https://www.w3.org/2019/wot/security#alg"`
+	Format        StringNode `json:"https://www.w3.org/2019/wot/security#format"`
+	Flow          StringNode `json:"https://www.w3.org/2019/wot/security#flow"`
+	Authorization IDNode     `json:"https://www.w3.org/2019/wot/security#authorization"`
+	Token         IDNode     `json:"https://www.w3.org/2019/wot/security#token"`
+	Refresh       IDNode     `json:"https://www.w3.org/2019/wot/security#refresh"`
+	Scopes        StringNode `json:"https://www.w3.org/2019/wot/security#scopes"`
+}
+
+// well known security schemes
+var (
+	SecurityNoSec  = SchemaSecurity.IRIPrefix("NoSecurityScheme")
+	SecurityBasic  = SchemaSecurity.IRIPrefix("BasicSecurityScheme")
+	SecurityDigest = SchemaSecurity.IRIPrefix("DigestSecurityScheme")
+	SecurityAPIKey = SchemaSecurity.IRIPrefix("APIKeySecurityScheme")
+	SecurityBearer = SchemaSecurity.IRIPrefix("BearerSecurityScheme")
+	SecurityPSK    = SchemaSecurity.IRIPrefix("PSKSecurityScheme")
+	SecurityOAuth2 = SchemaSecurity.IRIPrefix("OAuth2SecurityScheme")
+)
+
+// SecurityDefinition returns the security definition with the given name
+func (t ExpandedThingDescription) SecurityDefinition(name string) (ExpandedSecurityScheme, bool) {
+	for _, currScheme := range t.SecurityDefinitions {
+		if currScheme.Index == name {
+			return currScheme, true
+		}
+	}
+
+	return ExpandedSecurityScheme{}, false
+}
+
+// EffectiveSecurity returns the security schemes applying to a form. The security
+// configuration of the form overrides the one of the thing. References to unknown
+// definitions are skipped
+func (t ExpandedThingDescription) EffectiveSecurity(form ExpandedForm) []ExpandedSecurityScheme {
+	refs := form.Security
+	if len(refs) == 0 {
+		refs = t.Security
+	}
+
+	var result []ExpandedSecurityScheme
+	for _, currRef := range refs {
+		if scheme, ok := t.SecurityDefinition(currRef.ID); ok {
+			result = append(result, scheme)
+		}
+	}
+
+	return result
+}
+
+// ResolveSecurity sets the effective security of all forms of the td so that
+// affordances carry their security schemes on their own. FromBytes resolves
+// the security of the returned td
+func (t *ExpandedThingDescription) ResolveSecurity() {
+	resolve := func(forms ExpandedFormNode) {
+		for i := range forms {
+			forms[i].EffectiveSecurity = t.EffectiveSecurity(forms[i])
+		}
+	}
+
+	for i := range t.Properties {
+		resolve(t.Properties[i].Form)
+	}
+
+	for i := range t.Actions {
+		resolve(t.Actions[i].Form)
+	}
+
+	for i := range t.Events {
+		resolve(t.Events[i].Form)
+	}
+}
@@ -0,0 +1,58 @@
+package wotlib
+
+import "testing"
+
+func TestResolveSecurity(t *testing.T) {
+	td, err := FromBytes([]byte(`{
+		"@context": "https://www.w3.org/2019/wot/td/v1",
+		"id": "urn:dev:ops:32473-SecureLamp",
+		"title": "SecureLamp",
+		"securityDefinitions": {
+			"basic_sc": {"scheme": "basic", "in": "header"},
+			"oauth_sc": {"scheme": "oauth2", "flow": "client", "token": "https://example.com/token", "scopes": ["read", "write"]}
+		},
+		"security": ["basic_sc"],
+		"properties": {
+			"brightness": {
+				"type": "integer",
+				"forms": [
+					{"href": "https://example.com/brightness"},
+					{"href": "https://example.com/brightness/oauth", "security": "oauth_sc", "scopes": "read"}
+				]
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to expand td: %v", err)
+	}
+
+	forms := td.Properties[0].Form
+	if len(forms) != 2 {
+		t.Fatalf("Expected two forms, got %d", len(forms))
+	}
+
+	tests := []struct {
+		form   ExpandedForm
+		scheme string
+		index  string
+	}{
+		{form: forms[0], scheme: SecurityBasic, index: "basic_sc"},
+		{form: forms[1], scheme: SecurityOAuth2, index: "oauth_sc"},
+	}
+
+	for _, currTest := range tests {
+		if len(currTest.form.EffectiveSecurity) != 1 {
+			t.Fatalf("Expected one effective scheme for %s, got %d", currTest.form.Href.Value(), len(currTest.form.EffectiveSecurity))
+		}
+
+		scheme := currTest.form.EffectiveSecurity[0]
+		if scheme.Scheme.Value() != currTest.scheme || scheme.Index != currTest.index {
+			t.Fatalf("Unexpected scheme %s (%s) for %s", scheme.Scheme.Value(), scheme.Index, currTest.form.Href.Value())
+		}
+	}
+
+	oauth := forms[1].EffectiveSecurity[0]
+	if oauth.Token.Value() != "https://example.com/token" || len(oauth.Scopes) != 2 || forms[1].Scopes.Value() != "read" {
+		t.Fatalf("Unexpected oauth2 scheme %+v", oauth)
+	}
+}
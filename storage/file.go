@@ -0,0 +1,531 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/connctd/wotlib"
+)
+
+// well known errors of file storages
+var (
+	ErrClosed = errors.New("storage closed")
+	// ErrFailed is returned by changes after the log was left in an unknown state
+	ErrFailed = errors.New("storage failed")
+)
+
+// names of the files within the directory of a file storage
+const (
+	logFile         = "log"
+	snapshotFile    = "snapshot"
+	snapshotTmpFile = "snapshot.tmp"
+)
+
+// defaultSnapshotInterval is the number of changes logged before a snapshot is written
+const defaultSnapshotInterval = 1000
+
+// entries are framed by their length and crc32 checksum
+const (
+	headerSize   = 8
+	maxEntrySize = 64 << 20
+)
+
+// operations of log entries
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// entry is a change in the log or a record in a snapshot. The raw td is
+// encoded as base64 to keep it byte for byte
+type entry struct {
+	Op  string                           `json:"op"`
+	ID  string                           `json:"id"`
+	Raw []byte                           `json:"raw,omitempty"`
+	TD  *wotlib.ExpandedThingDescription `json:"td,omitempty"`
+}
+
+// File stores records in a directory. Changes are appended to a log which
+// is compacted into a snapshot after a number of changes. Snapshots are
+// replaced atomically and log entries carry a checksum, so changes which
+// were interrupted by a crash are discarded when the storage is opened again
+type File struct {
+	dir              string
+	snapshotInterval int
+
+	mutex      sync.Mutex
+	log        *os.File
+	logSize    int64
+	logEntries int
+	records    map[string]Record
+	// failure leaves the log in an unknown state until a snapshot succeeds
+	failure error
+}
+
+// FileOption configures a file storage
+type FileOption func(f *File) error
+
+// WithSnapshotInterval sets the number of changes after which the log is
+// compacted into a snapshot
+func WithSnapshotInterval(changes int) FileOption {
+	return func(f *File) error {
+		if changes <= 0 {
+			return fmt.Errorf("invalid snapshot interval %d", changes)
+		}
+
+		f.snapshotInterval = changes
+		return nil
+	}
+}
+
+// OpenFile opens or creates a file storage in the given directory and
+// restores all records from the snapshot and the log
+func OpenFile(dir string, opts ...FileOption) (*File, error) {
+	f := &File{dir: dir, snapshotInterval: defaultSnapshotInterval, records: map[string]Record{}}
+
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	// a snapshot which wasn't completed is discarded, the log still contains its changes
+	if err := os.Remove(filepath.Join(dir, snapshotTmpFile)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := f.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	if err := f.loadLog(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Load returns all stored records ordered by id
+func (f *File) Load() ([]Record, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.log == nil {
+		return nil, ErrClosed
+	}
+
+	return f.sortedRecords(), nil
+}
+
+// Put stores a record. The change is synced to disk before Put returns
+func (f *File) Put(record Record) error {
+	if err := validate(record); err != nil {
+		return err
+	}
+
+	record.Raw = append(json.RawMessage(nil), record.Raw...)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.append(entry{Op: opPut, ID: record.ID, Raw: record.Raw, TD: &record.TD}); err != nil {
+		return err
+	}
+
+	f.records[record.ID] = record
+	f.compact()
+
+	return nil
+}
+
+// Delete removes the record with the given id
+func (f *File) Delete(id string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.log == nil {
+		return ErrClosed
+	}
+
+	if _, exists := f.records[id]; !exists {
+		return nil
+	}
+
+	if err := f.append(entry{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+
+	delete(f.records, id)
+	f.compact()
+
+	return nil
+}
+
+// Snapshot writes all records into a new snapshot and truncates the log.
+// A successful snapshot recovers a failed storage
+func (f *File) Snapshot() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.log == nil {
+		return ErrClosed
+	}
+
+	return f.snapshot()
+}
+
+// Close closes the log. Further operations fail with ErrClosed
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.log == nil {
+		return nil
+	}
+
+	err := f.log.Close()
+	f.log = nil
+
+	return err
+}
+
+// append writes an entry to the log and syncs it. Partially written
+// entries are removed again. The mutex has to be locked
+func (f *File) append(e entry) error {
+	if f.log == nil {
+		return ErrClosed
+	}
+
+	if f.failure != nil {
+		return fmt.Errorf("%w: %v", ErrFailed, f.failure)
+	}
+
+	b, err := encodeEntry(e)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.log.Write(b); err != nil {
+		return f.rollback(err)
+	}
+
+	if err := f.log.Sync(); err != nil {
+		return f.rollback(err)
+	}
+
+	f.logSize += int64(len(b))
+	f.logEntries++
+
+	return nil
+}
+
+// rollback truncates the log to its last complete entry and returns the
+// error of the failed write. If the log can't be truncated the storage
+// fails, entries appended after a torn one would be lost when loading the log
+func (f *File) rollback(cause error) error {
+	err := f.log.Truncate(f.logSize)
+	if err == nil {
+		_, err = f.log.Seek(f.logSize, io.SeekStart)
+	}
+
+	if err != nil {
+		f.failure = err
+		return fmt.Errorf("%w: %v after %v", ErrFailed, err, cause)
+	}
+
+	return cause
+}
+
+// compact writes a snapshot once the log reached the snapshot interval.
+// The change is logged already, so it's kept even if the snapshot fails.
+// Snapshots failing before the log is truncated are retried with the next
+// change, failures truncating the log fail the storage
+func (f *File) compact() {
+	if f.logEntries < f.snapshotInterval {
+		return
+	}
+
+	_ = f.snapshot()
+}
+
+// snapshot replaces the snapshot and truncates the log. The mutex has to be locked
+func (f *File) snapshot() error {
+	tmpPath := filepath.Join(f.dir, snapshotTmpFile)
+
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	for _, currRecord := range f.sortedRecords() {
+		td := currRecord.TD
+		b, err := encodeEntry(entry{Op: opPut, ID: currRecord.ID, Raw: currRecord.Raw, TD: &td})
+		if err == nil {
+			_, err = tmp.Write(b)
+		}
+
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(f.dir, snapshotFile)); err != nil {
+		return err
+	}
+
+	if err := syncDir(f.dir); err != nil {
+		return err
+	}
+
+	// replaying the log over the new snapshot doesn't change it, so a crash
+	// before the log is truncated loses nothing
+	if err := f.truncateLog(); err != nil {
+		f.failure = err
+		return fmt.Errorf("%w: %v", ErrFailed, err)
+	}
+
+	f.failure = nil
+
+	return nil
+}
+
+// truncateLog removes all entries of the log
+func (f *File) truncateLog() error {
+	if err := f.log.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := f.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	f.logSize = 0
+	f.logEntries = 0
+
+	return f.log.Sync()
+}
+
+// loadSnapshot restores the records of the snapshot. Snapshots are never
+// written in place, so a damaged snapshot can't be repaired
+func (f *File) loadSnapshot() error {
+	file, err := os.Open(filepath.Join(f.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	entries, valid, err := readEntries(file)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if valid != info.Size() {
+		return fmt.Errorf("%w: snapshot is damaged at offset %d", ErrCorrupt, valid)
+	}
+
+	for _, currEntry := range entries {
+		if err := f.apply(currEntry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadLog replays the log and truncates it after the last complete entry
+func (f *File) loadLog() error {
+	file, err := os.OpenFile(filepath.Join(f.dir, logFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	entries, valid, err := readEntries(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	for _, currEntry := range entries {
+		if err := f.apply(currEntry); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if valid < info.Size() {
+		// the last change was interrupted
+		if err := file.Truncate(valid); err != nil {
+			file.Close()
+			return err
+		}
+
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+
+	f.log = file
+	f.logSize = valid
+	f.logEntries = len(entries)
+
+	return nil
+}
+
+// apply applies a change to the records
+func (f *File) apply(e entry) error {
+	switch e.Op {
+	case opPut:
+		if e.TD == nil {
+			return fmt.Errorf("%w: record %s has no td", ErrCorrupt, e.ID)
+		}
+
+		// the effective security of forms isn't serialized
+		e.TD.ResolveSecurity()
+		f.records[e.ID] = withDocument(Record{ID: e.ID, Raw: json.RawMessage(e.Raw), TD: *e.TD})
+	case opDelete:
+		delete(f.records, e.ID)
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrCorrupt, e.Op)
+	}
+
+	return nil
+}
+
+func (f *File) sortedRecords() []Record {
+	ids := make([]string, 0, len(f.records))
+	for id := range f.records {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	records := make([]Record, 0, len(ids))
+	for _, currID := range ids {
+		records = append(records, f.records[currID])
+	}
+
+	return records
+}
+
+// encodeEntry frames an entry by its length and checksum
+func encodeEntry(e entry) ([]byte, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) > maxEntrySize {
+		return nil, fmt.Errorf("record %s exceeds %d bytes", e.ID, maxEntrySize)
+	}
+
+	b := make([]byte, headerSize, headerSize+len(payload))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(b[4:8], crc32.ChecksumIEEE(payload))
+
+	return append(b, payload...), nil
+}
+
+// readEntries reads entries until the end of the file or the first
+// incomplete or damaged entry. It returns the offset after the last
+// valid entry
+func readEntries(r io.Reader) ([]entry, int64, error) {
+	var entries []entry
+	var offset int64
+
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return entries, offset, nil
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > maxEntrySize {
+			return entries, offset, nil
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return entries, offset, nil
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return entries, offset, nil
+		}
+
+		var e entry
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, 0, fmt.Errorf("%w: entry at offset %d: %v", ErrCorrupt, offset, err)
+		}
+
+		entries = append(entries, e)
+		offset += int64(headerSize + len(payload))
+	}
+}
+
+// syncDir syncs a directory to persist renames within it
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	// some platforms don't support syncing directories
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/connctd/wotlib"
+	"github.com/connctd/wotlib/internal/wottest"
+)
+
+func init() {
+	wotlib.DefaultJSONDLDOptions.DocumentLoader = wottest.DocumentLoader()
+}
+
+func testRecord(t *testing.T, id string, title string) Record {
+	raw := fmt.Sprintf(`{
+		"@context": "https://www.w3.org/2019/wot/td/v1",
+		"id": %q,
+		"title": %q,
+		"securityDefinitions": {"basic_sc": {"scheme": "basic", "in": "header"}},
+		"security": ["basic_sc"],
+		"properties": {"on": {"type": "boolean", "forms": [{"href": "http://lamp.local/on"}]}}
+	}`, id, title)
+
+	record, err := NewRecord([]byte(raw))
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	return record
+}
+
+func openTestFile(t *testing.T, dir string, opts ...FileOption) *File {
+	f, err := OpenFile(dir, opts...)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+
+	return f
+}
+
+func expectIDs(t *testing.T, s Storage, ids ...string) []Record {
+	records, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load records: %v", err)
+	}
+
+	if len(records) != len(ids) {
+		t.Fatalf("Expected records %v, got %d records", ids, len(records))
+	}
+
+	for i, currRecord := range records {
+		if currRecord.ID != ids[i] {
+			t.Fatalf("Expected record %s at %d, got %s", ids[i], i, currRecord.ID)
+		}
+	}
+
+	return records
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wotlib-storage")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := openTestFile(t, dir, WithSnapshotInterval(3))
+
+	for _, currID := range []string{"urn:dev:ops:c", "urn:dev:ops:a", "urn:dev:ops:b"} {
+		if err := f.Put(testRecord(t, currID, currID)); err != nil {
+			t.Fatalf("Failed to put record: %v", err)
+		}
+	}
+
+	if err := f.Put(testRecord(t, "urn:dev:ops:a", "changed")); err != nil {
+		t.Fatalf("Failed to put record: %v", err)
+	}
+
+	if err := f.Delete("urn:dev:ops:c"); err != nil {
+		t.Fatalf("Failed to delete record: %v", err)
+	}
+
+	if err := f.Delete("urn:dev:ops:missing"); err != nil {
+		t.Fatalf("Failed to delete missing record: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	if err := f.Put(testRecord(t, "urn:dev:ops:d", "d")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected closed storage, got %v", err)
+	}
+
+	// the first three changes were compacted into the snapshot
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Fatalf("Expected snapshot: %v", err)
+	}
+
+	f = openTestFile(t, dir)
+	defer f.Close()
+
+	records := expectIDs(t, f, "urn:dev:ops:a", "urn:dev:ops:b")
+	if string(records[0].Raw) != string(testRecord(t, "urn:dev:ops:a", "changed").Raw) {
+		t.Fatalf("Unexpected raw td %s", records[0].Raw)
+	}
+
+	set, err := Restore(f)
+	if err != nil {
+		t.Fatalf("Failed to restore set: %v", err)
+	}
+
+	td := set.Get("urn:dev:ops:b")
+	if len(td.Properties) != 1 || td.Properties[0].Name.Value() != "on" {
+		t.Fatalf("Unexpected restored td %+v", td)
+	}
+
+	if security := td.Properties[0].Form.Value().EffectiveSecurity; len(security) != 1 || security[0].Scheme.Value() != wotlib.SecurityBasic {
+		t.Fatalf("Expected effective security to be restored, got %+v", security)
+	}
+}
+
+func TestFileTruncatedLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(b []byte, last int) []byte
+	}{
+		{"truncated payload", func(b []byte, last int) []byte { return b[:len(b)-10] }},
+		{"truncated header", func(b []byte, last int) []byte { return b[:last+3] }},
+		{"only header", func(b []byte, last int) []byte { return b[:last+headerSize] }},
+		{"corrupt checksum", func(b []byte, last int) []byte {
+			b[last+4] ^= 0xff
+			return b
+		}},
+		{"garbage", func(b []byte, last int) []byte {
+			return append(b[:last], 0xff, 0xff, 0xff, 0xff, 0x00)
+		}},
+	}
+
+	for _, currTest := range tests {
+		t.Run(currTest.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "wotlib-storage")
+			if err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			f := openTestFile(t, dir)
+
+			if err := f.Put(testRecord(t, "urn:dev:ops:a", "a")); err != nil {
+				t.Fatalf("Failed to put record: %v", err)
+			}
+
+			last := int(f.logSize)
+
+			if err := f.Put(testRecord(t, "urn:dev:ops:b", "b")); err != nil {
+				t.Fatalf("Failed to put record: %v", err)
+			}
+
+			f.Close()
+
+			// simulate a crash while the last change was written
+			logPath := filepath.Join(dir, logFile)
+			b, err := ioutil.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("Failed to read log: %v", err)
+			}
+
+			if err := ioutil.WriteFile(logPath, currTest.damage(b, last), 0600); err != nil {
+				t.Fatalf("Failed to write log: %v", err)
+			}
+
+			f = openTestFile(t, dir)
+			expectIDs(t, f, "urn:dev:ops:a")
+
+			// the damaged entry is discarded, so new changes are readable again
+			if err := f.Put(testRecord(t, "urn:dev:ops:c", "c")); err != nil {
+				t.Fatalf("Failed to put record: %v", err)
+			}
+
+			f.Close()
+
+			f = openTestFile(t, dir)
+			defer f.Close()
+
+			expectIDs(t, f, "urn:dev:ops:a", "urn:dev:ops:c")
+		})
+	}
+}
+
+func TestFileSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wotlib-storage")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := openTestFile(t, dir)
+
+	for _, currID := range []string{"urn:dev:ops:a", "urn:dev:ops:b"} {
+		if err := f.Put(testRecord(t, currID, currID)); err != nil {
+			t.Fatalf("Failed to put record: %v", err)
+		}
+	}
+
+	if err := f.Snapshot(); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	f.Close()
+
+	// a crash while writing a snapshot leaves an incomplete temporary file
+	if err := ioutil.WriteFile(filepath.Join(dir, snapshotTmpFile), []byte{0, 0, 1}, 0600); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	f = openTestFile(t, dir)
+	expectIDs(t, f, "urn:dev:ops:a", "urn:dev:ops:b")
+	f.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotTmpFile)); !os.IsNotExist(err) {
+		t.Fatalf("Expected incomplete snapshot to be removed, got %v", err)
+	}
+
+	// snapshots are replaced atomically, a damaged one is reported
+	snapshotPath := filepath.Join(dir, snapshotFile)
+	b, err := ioutil.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+
+	if err := ioutil.WriteFile(snapshotPath, b[:len(b)-1], 0600); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	if _, err := OpenFile(dir); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Expected corrupt storage, got %v", err)
+	}
+}
+
+func TestFileFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wotlib-storage")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := openTestFile(t, dir)
+	defer f.Close()
+
+	if err := f.Put(testRecord(t, "urn:dev:ops:a", "a")); err != nil {
+		t.Fatalf("Failed to put record: %v", err)
+	}
+
+	// a read only log can be neither written nor rolled back
+	writable := f.log
+	readOnly, err := os.Open(filepath.Join(dir, logFile))
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer readOnly.Close()
+
+	f.log = readOnly
+	if err := f.Put(testRecord(t, "urn:dev:ops:b", "b")); !errors.Is(err, ErrFailed) {
+		t.Fatalf("Expected failed rollback to fail the storage, got %v", err)
+	}
+
+	// writes are rejected even if the log is writable again
+	f.log = writable
+	if err := f.Put(testRecord(t, "urn:dev:ops:c", "c")); !errors.Is(err, ErrFailed) {
+		t.Fatalf("Expected failed storage to reject changes, got %v", err)
+	}
+
+	if err := f.Snapshot(); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	if err := f.Put(testRecord(t, "urn:dev:ops:c", "c")); err != nil {
+		t.Fatalf("Expected snapshot to recover the storage, got %v", err)
+	}
+
+	f.Close()
+
+	f = openTestFile(t, dir)
+	defer f.Close()
+
+	expectIDs(t, f, "urn:dev:ops:a", "urn:dev:ops:c")
+}
+
+func TestRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wotlib-storage")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	record := testRecord(t, "urn:dev:ops:a", "Lamp")
+
+	f := openTestFile(t, dir)
+	if err := f.Put(record); err != nil {
+		t.Fatalf("Failed to put record: %v", err)
+	}
+
+	f.Close()
+
+	f = openTestFile(t, dir)
+	defer f.Close()
+
+	set, err := Restore(f)
+	if err != nil {
+		t.Fatalf("Failed to restore set: %v", err)
+	}
+
+	td, err := wotlib.FromBytes(record.Raw)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	// the restored td is equal to the td expanded again
+	if result := set.Append(td); result != wotlib.AppendUnchanged {
+		t.Fatalf("Expected unchanged td after restart, got %v", result)
+	}
+}
@@ -0,0 +1,90 @@
+// Package storage persists thing descriptions so that an expanded thing
+// description set survives restarts. Storages keep the raw td as received
+// and its expanded form, which restores without expanding the td again
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/connctd/wotlib"
+)
+
+// ErrCorrupt is returned if stored data can't be restored
+var ErrCorrupt = errors.New("storage corrupt")
+
+// Record is a stored thing description
+type Record struct {
+	// ID of the thing
+	ID string `json:"id"`
+	// Raw is the td as it was received
+	Raw json.RawMessage `json:"raw"`
+	// TD is the expanded td
+	TD wotlib.ExpandedThingDescription `json:"td"`
+}
+
+// NewRecord expands a raw td into a record
+func NewRecord(raw []byte) (Record, error) {
+	td, err := wotlib.FromBytes(raw)
+	if err != nil {
+		return Record{}, err
+	}
+
+	if td.ID == "" {
+		return Record{}, errors.New("thing description has no id")
+	}
+
+	return Record{ID: td.ID, Raw: append(json.RawMessage(nil), raw...), TD: td}, nil
+}
+
+// Storage persists records of thing descriptions
+type Storage interface {
+	// Load returns all stored records ordered by id
+	Load() ([]Record, error)
+	// Put stores a record, replacing the one with the same id
+	Put(record Record) error
+	// Delete removes the record with the given id if it exists
+	Delete(id string) error
+	// Close releases the storage
+	Close() error
+}
+
+// Restore creates a set from all stored records
+func Restore(s Storage) (wotlib.ExpandedThingDescriptionSet, error) {
+	records, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	set := wotlib.NewExpandedThingDescriptionSet()
+	for _, currRecord := range records {
+		set.Append(withDocument(currRecord).TD)
+	}
+
+	return set, nil
+}
+
+// withDocument attaches the raw td to the expanded td of a record, so
+// restored tds are equal to tds expanded again from the same raw td
+func withDocument(record Record) Record {
+	record.TD = record.TD.WithDocument(record.Raw)
+	return record
+}
+
+// validate checks a record before it is stored
+func validate(record Record) error {
+	if record.ID == "" {
+		return errors.New("record has no id")
+	}
+
+	if record.TD.ID != "" && record.TD.ID != record.ID {
+		return fmt.Errorf("record id %q does not match td id %q", record.ID, record.TD.ID)
+	}
+
+	if !json.Valid(record.Raw) {
+		return fmt.Errorf("raw td of %s is not valid json", record.ID)
+	}
+
+	return nil
+}
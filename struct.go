@@ -0,0 +1,507 @@
+package wotlib
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TagName is the name of struct tags annotating fields for thing descriptions
+const TagName = "wot"
+
+// ActionAnnotator can be implemented by structs passed to AddStruct to select
+// the methods which become actions. The keys are method names, the values
+// use the syntax of struct tags, e.g. "action,idempotent,type=iot:TurnOn".
+// Methods annotated with "-" are skipped
+type ActionAnnotator interface {
+	WoTActions() map[string]string
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// FromStruct derives a thing description from an annotated struct as done
+// by AddStruct. The thing uses no security and hrefs relative to its base
+func FromStruct(title string, v interface{}) (ExpandedThingDescription, error) {
+	return NewThing(title).
+		WithSecurityDefinition("nosec_sc", NewSecurityScheme("nosec")).
+		WithSecurity("nosec_sc").
+		AddStruct(v).
+		Build()
+}
+
+// AddStruct adds affordances derived from a struct. Fields tagged with
+// `wot:"property"` become properties and exported methods annotated as
+// "action" by WoTActions become actions.
+// Tags may contain the options observable, readonly, writeonly, idempotent
+// and safe as well as name=, type=, unit=, min= and max=. Data schemas are
+// inferred from the go types, fields of nested structs use their json names
+// and may be annotated with the same options. Affordances get forms with
+// hrefs like "properties/{name}" and "actions/{name}"
+func (b *ThingBuilder) AddStruct(v interface{}) *ThingBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	if v == nil {
+		b.err = &BuildError{Reason: "nil is not a struct"}
+		return b
+	}
+
+	value := reflect.ValueOf(v)
+	structType := value.Type()
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType.Kind() != reflect.Struct {
+		b.err = &BuildError{Reason: fmt.Sprintf("%s is not a struct", value.Type())}
+		return b
+	}
+
+	if err := b.addStructProperties(structType); err != nil {
+		b.err = err
+		return b
+	}
+
+	if err := b.addStructActions(v, value.Type()); err != nil {
+		b.err = err
+	}
+
+	return b
+}
+
+func (b *ThingBuilder) addStructProperties(structType reflect.Type) error {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		tag, ok := field.Tag.Lookup(TagName)
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+
+		opts := parseTag(tag)
+		if opts.kind == "-" {
+			continue
+		}
+
+		if opts.kind != "property" {
+			return &BuildError{Path: "/properties/" + field.Name, Reason: fmt.Sprintf("unsupported tag %q", tag)}
+		}
+
+		name := opts.name
+		if name == "" {
+			name = lowerCamelCase(field.Name)
+		}
+
+		schema, err := schemaOf(field.Type, map[reflect.Type]bool{})
+		if err != nil {
+			return &BuildError{Path: "/properties/" + name, Reason: err.Error()}
+		}
+
+		if err := opts.apply(schema); err != nil {
+			return &BuildError{Path: "/properties/" + name, Reason: err.Error()}
+		}
+
+		property := NewProperty(name, schema)
+		if opts.observable {
+			property.Observable()
+		}
+
+		var ops []string
+		if !opts.writeOnly {
+			ops = append(ops, "readproperty")
+		}
+
+		if !opts.readOnly {
+			ops = append(ops, "writeproperty")
+		}
+
+		b.AddProperty(property.AddForm(NewForm("properties/" + name).WithOp(ops...)))
+	}
+
+	return nil
+}
+
+// addStructActions adds the methods annotated by WoTActions as actions.
+// Other methods like String or Error are never exposed
+func (b *ThingBuilder) addStructActions(v interface{}, t reflect.Type) error {
+	annotator, ok := v.(ActionAnnotator)
+	if !ok {
+		return nil
+	}
+
+	annotations := annotator.WoTActions()
+	for currName := range annotations {
+		if _, ok := t.MethodByName(currName); !ok {
+			return &BuildError{Path: "/actions/" + lowerCamelCase(currName), Reason: fmt.Sprintf("%s has no method %s", t, currName)}
+		}
+	}
+
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+
+		annotation, ok := annotations[method.Name]
+		if !ok {
+			continue
+		}
+
+		opts := parseTag(annotation)
+		if opts.kind == "-" {
+			continue
+		}
+
+		name := opts.name
+		if name == "" {
+			name = lowerCamelCase(method.Name)
+		}
+
+		if opts.kind != "action" {
+			return &BuildError{Path: "/actions/" + name, Reason: fmt.Sprintf("unsupported annotation %q", annotation)}
+		}
+
+		// the first argument is the receiver
+		in, out, ok := actionSignature(method.Type)
+		if !ok {
+			return &BuildError{Path: "/actions/" + name, Reason: "unsupported method signature " + method.Type.String()}
+		}
+
+		action := NewAction(name).WithType(opts.types...)
+		if opts.idempotent {
+			action.Idempotent()
+		}
+
+		if opts.safe {
+			action.Safe()
+		}
+
+		if in != nil {
+			schema, err := schemaOf(in, map[reflect.Type]bool{})
+			if err != nil {
+				return &BuildError{Path: "/actions/" + name + "/input", Reason: err.Error()}
+			}
+
+			action.WithInput(schema)
+		}
+
+		if out != nil {
+			schema, err := schemaOf(out, map[reflect.Type]bool{})
+			if err != nil {
+				return &BuildError{Path: "/actions/" + name + "/output", Reason: err.Error()}
+			}
+
+			action.WithOutput(schema)
+		}
+
+		b.AddAction(action.AddForm(NewForm("actions/" + name).WithOp("invokeaction")))
+	}
+
+	return nil
+}
+
+// actionSignature checks if a method can be used as action and returns
+// the types of its input and output. Supported are methods with an optional
+// context, at most one further argument and an optional result followed
+// by an optional error
+func actionSignature(method reflect.Type) (in reflect.Type, out reflect.Type, ok bool) {
+	args := make([]reflect.Type, 0, method.NumIn())
+	for i := 1; i < method.NumIn(); i++ {
+		args = append(args, method.In(i))
+	}
+
+	if len(args) > 0 && args[0] == contextType {
+		args = args[1:]
+	}
+
+	if len(args) > 1 || method.IsVariadic() {
+		return nil, nil, false
+	}
+
+	if len(args) == 1 {
+		in = args[0]
+	}
+
+	results := make([]reflect.Type, 0, method.NumOut())
+	for i := 0; i < method.NumOut(); i++ {
+		results = append(results, method.Out(i))
+	}
+
+	if len(results) > 0 && results[len(results)-1] == errorType {
+		results = results[:len(results)-1]
+	}
+
+	if len(results) > 1 {
+		return nil, nil, false
+	}
+
+	if len(results) == 1 {
+		out = results[0]
+	}
+
+	return in, out, true
+}
+
+// schemaOf infers the data schema of a go type
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) (*DataSchemaBuilder, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return BooleanSchema(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntegerSchema(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return IntegerSchema().WithMinimum(0), nil
+	case reflect.Float32, reflect.Float64:
+		return NumberSchema(), nil
+	case reflect.String:
+		return StringSchema(), nil
+	case reflect.Interface:
+		return &DataSchemaBuilder{schema: map[string]interface{}{}}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+
+		return ObjectSchema(), nil
+	case reflect.Slice, reflect.Array:
+		// byte slices are encoded as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return StringSchema(), nil
+		}
+
+		items, err := schemaOf(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		schema := ArraySchema(items)
+		if t.Kind() == reflect.Array {
+			schema.WithMinItems(t.Len()).WithMaxItems(t.Len())
+		}
+
+		return schema, nil
+	case reflect.Struct:
+		if t == timeType {
+			return StringSchema(), nil
+		}
+
+		if visiting[t] {
+			return nil, fmt.Errorf("recursive type %s", t)
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		schema := ObjectSchema()
+		if err := addStructFields(schema, t, visiting); err != nil {
+			return nil, err
+		}
+
+		return schema, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// addStructFields adds the exported fields of a struct as properties using
+// their json names. Fields which are neither pointers nor omitted if empty
+// are required
+func addStructFields(schema *DataSchemaBuilder, t reflect.Type, visiting map[reflect.Type]bool) error {
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		jsonName, jsonOpts, named := field.Name, "", false
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			parts := strings.SplitN(tag, ",", 2)
+			if parts[0] == "-" {
+				continue
+			}
+
+			if parts[0] != "" {
+				jsonName, named = parts[0], true
+			}
+
+			if len(parts) > 1 {
+				jsonOpts = parts[1]
+			}
+		}
+
+		// fields of embedded structs are promoted unless the json tag names them
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !named {
+			if err := addStructFields(schema, field.Type, visiting); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		fieldSchema, err := schemaOf(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		if tag, ok := field.Tag.Lookup(TagName); ok {
+			if err := parseTag(tag).apply(fieldSchema); err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+
+		schema.AddProperty(jsonName, fieldSchema)
+
+		if field.Type.Kind() != reflect.Ptr && !strings.Contains(jsonOpts, "omitempty") {
+			required = append(required, jsonName)
+		}
+	}
+
+	if len(required) > 0 {
+		existing, _ := schema.schema["required"].([]string)
+		schema.WithRequired(append(existing, required...)...)
+	}
+
+	return nil
+}
+
+// tagOptions are the options of a wot struct tag
+type tagOptions struct {
+	kind       string
+	name       string
+	types      []string
+	unit       string
+	minimum    *float64
+	maximum    *float64
+	observable bool
+	readOnly   bool
+	writeOnly  bool
+	idempotent bool
+	safe       bool
+	err        error
+}
+
+func parseTag(tag string) tagOptions {
+	var opts tagOptions
+
+	for i, currPart := range strings.Split(tag, ",") {
+		currPart = strings.TrimSpace(currPart)
+		key, value := currPart, ""
+		if eq := strings.IndexByte(currPart, '='); eq >= 0 {
+			key, value = currPart[:eq], currPart[eq+1:]
+		}
+
+		switch key {
+		case "property", "action", "-":
+			if i == 0 {
+				opts.kind = key
+				continue
+			}
+		case "observable":
+			opts.observable = true
+			continue
+		case "readonly":
+			opts.readOnly = true
+			continue
+		case "writeonly":
+			opts.writeOnly = true
+			continue
+		case "idempotent":
+			opts.idempotent = true
+			continue
+		case "safe":
+			opts.safe = true
+			continue
+		case "name":
+			opts.name = value
+			continue
+		case "type":
+			opts.types = append(opts.types, value)
+			continue
+		case "unit":
+			opts.unit = value
+			continue
+		case "min", "max":
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				opts.err = fmt.Errorf("invalid %s %q", key, value)
+				continue
+			}
+
+			if key == "min" {
+				opts.minimum = &f
+			} else {
+				opts.maximum = &f
+			}
+
+			continue
+		case "":
+			continue
+		}
+
+		if opts.err == nil {
+			opts.err = fmt.Errorf("unknown tag option %q", currPart)
+		}
+	}
+
+	return opts
+}
+
+// apply applies the schema related options to a data schema
+func (o tagOptions) apply(schema *DataSchemaBuilder) error {
+	if o.err != nil {
+		return o.err
+	}
+
+	if len(o.types) > 0 {
+		schema.WithType(o.types...)
+	}
+
+	if o.unit != "" {
+		schema.WithUnit(o.unit)
+	}
+
+	if o.minimum != nil {
+		schema.WithMinimum(*o.minimum)
+	}
+
+	if o.maximum != nil {
+		schema.WithMaximum(*o.maximum)
+	}
+
+	if o.readOnly {
+		schema.ReadOnly()
+	}
+
+	if o.writeOnly {
+		schema.WriteOnly()
+	}
+
+	return nil
+}
+
+// lowerCamelCase converts names like "SwitchStatus" or "HTTPPort" to
+// "switchStatus" and "httpPort"
+func lowerCamelCase(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,248 @@
+package wotlib
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type testColor struct {
+	Red   uint8 `json:"red" wot:"type=iot:RColourData,max=255"`
+	Green uint8 `json:"green" wot:"type=iot:GColourData,max=255"`
+	Blue  uint8 `json:"blue" wot:"type=iot:BColourData,max=255"`
+}
+
+type testLamp struct {
+	Status     bool      `wot:"property,observable,readonly,type=iot:SwitchStatus"`
+	Brightness int       `wot:"property,type=iot:Brightness,min=0,max=100,unit=percent"`
+	Color      testColor `wot:"property,name=colour"`
+	Firmware   string
+	secret     string
+}
+
+func (l *testLamp) TurnOn(ctx context.Context) error {
+	l.Status = true
+	return nil
+}
+
+func (l *testLamp) SetColor(ctx context.Context, color testColor) (testColor, error) {
+	l.Color = color
+	return color, nil
+}
+
+func (l *testLamp) Close() {}
+
+func (l *testLamp) Merge(a, b int) int {
+	return a + b
+}
+
+func (l *testLamp) WoTActions() map[string]string {
+	return map[string]string{
+		"TurnOn":   "action,idempotent,type=iot:TurnOn",
+		"SetColor": "action,type=iot:SetColour",
+		"Close":    "-",
+	}
+}
+
+type testSensor struct {
+	Temperature float64 `wot:"property,readonly"`
+}
+
+func (s *testSensor) String() string {
+	return "sensor"
+}
+
+func (s *testSensor) Error() string {
+	return "failed"
+}
+
+type testAnnotated struct {
+	actions map[string]string
+}
+
+func (a *testAnnotated) Reset() {}
+
+func (a *testAnnotated) Merge(x, y int) int {
+	return x + y
+}
+
+func (a *testAnnotated) WoTActions() map[string]string {
+	return a.actions
+}
+
+func TestFromStruct(t *testing.T) {
+	AppendSchema(iotSchema)
+
+	td, err := FromStruct("Lamp", &testLamp{})
+	if err != nil {
+		t.Fatalf("Failed to derive td: %v", err)
+	}
+
+	props := td.GetPropertyAffordances(PropertyConstraint{
+		Type: &[]string{iotSchema.IRIPrefix("SwitchStatus")},
+	})
+	if len(props) != 1 || props[0].Name.Value() != "status" || !props[0].IsObservable.Value() || !props[0].ReadOnly.Value() {
+		t.Fatalf("Unexpected status properties %+v", props)
+	}
+
+	if _, ok := props[0].Form.Find(OpWriteProperty); ok {
+		t.Fatalf("Read only property must not be writable")
+	}
+
+	brightness := td.GetPropertyAffordances(PropertyConstraint{Name: stringPtr("brightness")})
+	if len(brightness) != 1 || brightness[0].DataType.Value() != DataTypeInteger {
+		t.Fatalf("Unexpected brightness properties %+v", brightness)
+	}
+
+	if err := brightness[0].Validate(101); err == nil {
+		t.Fatalf("Expected brightness above maximum to be rejected")
+	}
+
+	color := td.GetPropertyAffordances(PropertyConstraint{Name: stringPtr("colour")})
+	if len(color) != 1 {
+		t.Fatalf("Expected colour property")
+	}
+
+	if err := color[0].Validate(testColor{Red: 1, Green: 2, Blue: 3}); err != nil {
+		t.Fatalf("Failed to validate colour: %v", err)
+	}
+
+	if err := color[0].Validate(map[string]interface{}{"red": 1}); err == nil {
+		t.Fatalf("Expected missing colour components to be rejected")
+	}
+
+	if len(td.Properties) != 3 {
+		t.Fatalf("Expected only tagged fields to become properties, got %d", len(td.Properties))
+	}
+
+	actions := td.GetActionAffordances(ActionConstraint{
+		Type: &[]string{iotSchema.IRIPrefix("TurnOn")},
+	})
+	if len(actions) != 1 || actions[0].Name.Value() != "turnOn" || !actions[0].IsIdempotent.Value() || len(actions[0].Input) != 0 {
+		t.Fatalf("Unexpected turnOn actions %+v", actions)
+	}
+
+	setColor := td.GetActionAffordances(ActionConstraint{
+		InputConstraint: &InputConstraint{
+			DataType: &DataTypeObject,
+			DataPropertyConstraint: &DataPropertyConstraint{
+				Type: &[]string{iotSchema.IRIPrefix("RColourData")},
+			},
+		},
+	})
+	if len(setColor) != 1 || setColor[0].Name.Value() != "setColor" {
+		t.Fatalf("Unexpected setColor actions %+v", setColor)
+	}
+
+	input, err := setColor[0].BuildInput(map[string]interface{}{
+		iotSchema.IRIPrefix("RColourData"): 10,
+		iotSchema.IRIPrefix("GColourData"): 20,
+		iotSchema.IRIPrefix("BColourData"): 30,
+	})
+	if err != nil || input["red"] != 10 {
+		t.Fatalf("Unexpected input %v (%v)", input, err)
+	}
+
+	if len(td.Actions) != 2 {
+		t.Fatalf("Expected only annotated methods to become actions, got %d actions", len(td.Actions))
+	}
+
+	// methods without annotation aren't exposed
+	sensor, err := FromStruct("Sensor", &testSensor{})
+	if err != nil {
+		t.Fatalf("Failed to derive td: %v", err)
+	}
+
+	if len(sensor.Properties) != 1 || len(sensor.Actions) != 0 {
+		t.Fatalf("Expected no actions, got %+v", sensor.Actions)
+	}
+}
+
+func TestFromStructErrors(t *testing.T) {
+	type invalidTag struct {
+		Value int `wot:"property,min=low"`
+	}
+
+	type unsupportedType struct {
+		Value chan int `wot:"property"`
+	}
+
+	tests := []interface{}{
+		nil,
+		42,
+		&invalidTag{},
+		&unsupportedType{},
+		&testAnnotated{actions: map[string]string{"Missing": "action"}},
+		&testAnnotated{actions: map[string]string{"Reset": "property"}},
+		&testAnnotated{actions: map[string]string{"Merge": "action"}},
+	}
+
+	for _, currTest := range tests {
+		var buildErr *BuildError
+		if _, err := FromStruct("Invalid", currTest); !errors.As(err, &buildErr) {
+			t.Fatalf("Expected build error for %T, got %v", currTest, err)
+		}
+	}
+}
+
+func TestSchemaOfEmbedded(t *testing.T) {
+	type Base struct {
+		ID string `json:"id"`
+	}
+
+	type promoted struct {
+		Base `xjson:"base"`
+		Name string `json:"name"`
+	}
+
+	type named struct {
+		Base `json:"base"`
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		value      interface{}
+		properties []string
+	}{
+		{promoted{}, []string{"id", "name"}},
+		{named{}, []string{"base", "name"}},
+	}
+
+	for i, currTest := range tests {
+		schema, err := schemaOf(reflect.TypeOf(currTest.value), map[reflect.Type]bool{})
+		if err != nil {
+			t.Fatalf("Test %d: failed to infer schema: %v", i, err)
+		}
+
+		var properties []string
+		for currName := range schema.properties {
+			properties = append(properties, currName)
+		}
+
+		sort.Strings(properties)
+		if !reflect.DeepEqual(properties, currTest.properties) {
+			t.Fatalf("Test %d: expected properties %v, got %v", i, currTest.properties, properties)
+		}
+	}
+}
+
+func TestLowerCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"SwitchStatus": "switchStatus",
+		"HTTPPort":     "httpPort",
+		"ID":           "id",
+		"on":           "on",
+	}
+
+	for input, expected := range tests {
+		if result := lowerCamelCase(input); result != expected {
+			t.Fatalf("Expected %s for %s, got %s", expected, input, result)
+		}
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,373 @@
+package wotlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// well known terms of thing models
+var (
+	TypeThingModel = SchemaTM.IRIPrefix("ThingModel")
+)
+
+// members of thing models which are removed during instantiation
+const (
+	tmRequired = "tm:required"
+	tmOptional = "tm:optional"
+)
+
+// affordance types of thing descriptions
+var affordanceTypes = []string{"properties", "actions", "events"}
+
+// placeholderPattern matches placeholders like "{{DEVICE_ID}}"
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// ModelError is returned if a thing model is invalid or can't be instantiated
+type ModelError struct {
+	// Path points to the invalid element, e.g. "/properties/brightness"
+	Path   string
+	Reason string
+}
+
+func (e *ModelError) Error() string {
+	if e.Path == "" {
+		return "invalid thing model: " + e.Reason
+	}
+
+	return fmt.Sprintf("invalid thing model at %s: %s", e.Path, e.Reason)
+}
+
+// ThingModel is a template for thing descriptions as introduced by td 1.1.
+// String values may contain placeholders like "{{DEVICE_ID}}" which are
+// substituted during instantiation. Affordances referenced by tm:required
+// are part of every instance, all others are optional. Models without
+// tm:required treat all affordances as required except the ones listed
+// by tm:optional
+type ThingModel struct {
+	document map[string]interface{}
+	required map[string]bool
+}
+
+// ParseThingModel parses a thing model in its compact json form
+func ParseThingModel(b []byte) (*ThingModel, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(b, &document); err != nil {
+		return nil, err
+	}
+
+	return NewThingModel(document)
+}
+
+// NewThingModel creates a thing model from its compact document
+func NewThingModel(document map[string]interface{}) (*ThingModel, error) {
+	if !isThingModel(document) {
+		return nil, &ModelError{Path: "/@type", Reason: "type tm:ThingModel is missing"}
+	}
+
+	m := &ThingModel{document: document, required: map[string]bool{}}
+
+	required, hasRequired := document[tmRequired]
+	optional := map[string]bool{}
+	for _, currPointer := range stringValues(document[tmOptional]) {
+		name, err := m.affordance(tmOptional, currPointer)
+		if err != nil {
+			return nil, err
+		}
+
+		optional[name] = true
+	}
+
+	for _, currPointer := range stringValues(required) {
+		name, err := m.affordance(tmRequired, currPointer)
+		if err != nil {
+			return nil, err
+		}
+
+		if optional[name] {
+			return nil, &ModelError{Path: "/" + tmRequired, Reason: fmt.Sprintf("%s is required and optional", name)}
+		}
+
+		m.required[name] = true
+	}
+
+	if !hasRequired {
+		for _, currName := range m.Affordances() {
+			m.required[currName] = !optional[currName]
+		}
+	}
+
+	return m, nil
+}
+
+// Title returns the title of the model which may contain placeholders
+func (m *ThingModel) Title() string {
+	title, _ := m.document["title"].(string)
+	return title
+}
+
+// Placeholders returns the sorted names of all placeholders used by the model
+func (m *ThingModel) Placeholders() []string {
+	names := map[string]bool{}
+
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			for _, currMatch := range placeholderPattern.FindAllStringSubmatch(v, -1) {
+				names[currMatch[1]] = true
+			}
+		case []interface{}:
+			for _, currValue := range v {
+				collect(currValue)
+			}
+		case map[string]interface{}:
+			for key, currValue := range v {
+				collect(key)
+				collect(currValue)
+			}
+		}
+	}
+
+	collect(m.document)
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// Affordances returns all affordances of the model as sorted json pointers
+// like "#/properties/brightness"
+func (m *ThingModel) Affordances() []string {
+	var result []string
+	for _, currType := range affordanceTypes {
+		affordances, _ := m.document[currType].(map[string]interface{})
+		for name := range affordances {
+			result = append(result, "#/"+currType+"/"+name)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// RequiredAffordances returns the affordances which are part of every instance
+func (m *ThingModel) RequiredAffordances() []string {
+	var result []string
+	for _, currName := range m.Affordances() {
+		if m.required[currName] {
+			result = append(result, currName)
+		}
+	}
+
+	return result
+}
+
+// OptionalAffordances returns the affordances which are only part of
+// instances if they are chosen
+func (m *ThingModel) OptionalAffordances() []string {
+	var result []string
+	for _, currName := range m.Affordances() {
+		if !m.required[currName] {
+			result = append(result, currName)
+		}
+	}
+
+	return result
+}
+
+// Document instantiates the model as compact thing description. All placeholders
+// have to be given. Values of placeholders which make up a whole string are
+// inserted with their json type, others are formatted into the string.
+// Optional affordances are only included if they are listed by optional, either
+// as json pointer like "#/properties/brightness" or as path like "properties/brightness"
+func (m *ThingModel) Document(placeholders map[string]interface{}, optional ...string) ([]byte, error) {
+	for _, currName := range m.Placeholders() {
+		if _, ok := placeholders[currName]; !ok {
+			return nil, &ModelError{Reason: fmt.Sprintf("placeholder %s is missing", currName)}
+		}
+	}
+
+	chosen := map[string]bool{}
+	for _, currPointer := range optional {
+		name, err := m.affordance("", currPointer)
+		if err != nil {
+			return nil, err
+		}
+
+		if m.required[name] {
+			return nil, &ModelError{Path: strings.TrimPrefix(name, "#"), Reason: "affordance is required and can't be chosen"}
+		}
+
+		chosen[name] = true
+	}
+
+	document := substitute(m.document, placeholders).(map[string]interface{})
+
+	for _, currType := range affordanceTypes {
+		affordances, ok := document[currType].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for name := range affordances {
+			pointer := "#/" + currType + "/" + name
+			if !m.required[pointer] && !chosen[pointer] {
+				delete(affordances, name)
+			}
+		}
+
+		if len(affordances) == 0 {
+			delete(document, currType)
+		}
+	}
+
+	delete(document, tmRequired)
+	delete(document, tmOptional)
+	document["@type"] = withoutThingModelType(document["@type"])
+	if document["@type"] == nil {
+		delete(document, "@type")
+	}
+
+	if title, _ := document["title"].(string); title == "" {
+		return nil, &ModelError{Path: "/title", Reason: "title is required"}
+	}
+
+	if _, ok := document["securityDefinitions"].(map[string]interface{}); !ok {
+		return nil, &ModelError{Path: "/securityDefinitions", Reason: "security definitions are required"}
+	}
+
+	if _, ok := document["security"]; !ok {
+		return nil, &ModelError{Path: "/security", Reason: "security is required"}
+	}
+
+	return json.Marshal(document)
+}
+
+// Instantiate creates a thing description from the model. See Document for
+// details about placeholders and optional affordances
+func (m *ThingModel) Instantiate(placeholders map[string]interface{}, optional ...string) (ExpandedThingDescription, error) {
+	document, err := m.Document(placeholders, optional...)
+	if err != nil {
+		return ExpandedThingDescription{}, err
+	}
+
+	return FromBytes(document)
+}
+
+// affordance normalizes a reference to an affordance of the model into
+// a json pointer and checks that the affordance exists
+func (m *ThingModel) affordance(member string, reference string) (string, error) {
+	parts := strings.SplitN(strings.TrimLeft(reference, "#/"), "/", 2)
+
+	path := "/" + member
+	if member == "" {
+		path = ""
+	}
+
+	if len(parts) == 2 {
+		affordances, _ := m.document[parts[0]].(map[string]interface{})
+		if _, ok := affordances[parts[1]]; ok {
+			return "#/" + parts[0] + "/" + parts[1], nil
+		}
+	}
+
+	return "", &ModelError{Path: path, Reason: fmt.Sprintf("unknown affordance %s", reference)}
+}
+
+// substitute returns a copy of the value with all placeholders replaced
+func substitute(value interface{}, placeholders map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(v); match != nil && match[0] == v {
+			return placeholders[match[1]]
+		}
+
+		return substituteString(v, placeholders)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = substitute(v[i], placeholders)
+		}
+
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, currValue := range v {
+			result[substituteString(key, placeholders)] = substitute(currValue, placeholders)
+		}
+
+		return result
+	}
+
+	return value
+}
+
+// substituteString formats the values of all placeholders into a string
+func substituteString(s string, placeholders map[string]interface{}) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if s, ok := placeholders[name].(string); ok {
+			return s
+		}
+
+		return fmt.Sprint(placeholders[name])
+	})
+}
+
+// isThingModel checks if the type of a document contains tm:ThingModel
+func isThingModel(document map[string]interface{}) bool {
+	for _, currType := range stringValues(document["@type"]) {
+		if ExpandIRI(currType) == TypeThingModel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutThingModelType removes tm:ThingModel from a type value
+func withoutThingModelType(value interface{}) interface{} {
+	var types []interface{}
+	for _, currType := range stringValues(value) {
+		if ExpandIRI(currType) != TypeThingModel {
+			types = append(types, currType)
+		}
+	}
+
+	switch len(types) {
+	case 0:
+		return nil
+	case 1:
+		return types[0]
+	}
+
+	return types
+}
+
+// stringValues returns the strings of a value which is either a string or an array
+func stringValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, currValue := range v {
+			if s, ok := currValue.(string); ok {
+				result = append(result, s)
+			}
+		}
+
+		return result
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package wotlib
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testThingModel = `{
+    "@context": [
+        "https://www.w3.org/2019/wot/td/v1",
+        {
+            "iot": "http://iotschema.org/",
+            "tm": "https://www.w3.org/2019/wot/tm#"
+        }
+    ],
+    "@type": ["tm:ThingModel", "iot:Light"],
+    "id": "urn:dev:ops:{{SERIAL}}",
+    "title": "Lamp {{SERIAL}}",
+    "base": "{{BASE}}",
+    "securityDefinitions": {
+        "nosec_sc": {"scheme": "nosec"}
+    },
+    "security": ["nosec_sc"],
+    "tm:required": ["#/properties/on", "#/actions/toggle"],
+    "properties": {
+        "on": {
+            "type": "boolean",
+            "forms": [{"href": "properties/on"}]
+        },
+        "brightness": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": "{{MAX_BRIGHTNESS}}",
+            "forms": [{"href": "properties/brightness"}]
+        }
+    },
+    "actions": {
+        "toggle": {
+            "forms": [{"href": "actions/toggle"}]
+        }
+    },
+    "events": {
+        "overheated": {
+            "forms": [{"href": "events/overheated"}]
+        }
+    }
+}`
+
+func TestThingModel(t *testing.T) {
+	m, err := ParseThingModel([]byte(testThingModel))
+	if err != nil {
+		t.Fatalf("Failed to parse thing model: %v", err)
+	}
+
+	if placeholders := m.Placeholders(); !reflect.DeepEqual(placeholders, []string{"BASE", "MAX_BRIGHTNESS", "SERIAL"}) {
+		t.Fatalf("Unexpected placeholders %v", placeholders)
+	}
+
+	if optional := m.OptionalAffordances(); !reflect.DeepEqual(optional, []string{"#/events/overheated", "#/properties/brightness"}) {
+		t.Fatalf("Unexpected optional affordances %v", optional)
+	}
+
+	placeholders := map[string]interface{}{
+		"SERIAL":         "4711",
+		"BASE":           "http://lamp.local/",
+		"MAX_BRIGHTNESS": 255,
+	}
+
+	td, err := m.Instantiate(placeholders, "properties/brightness")
+	if err != nil {
+		t.Fatalf("Failed to instantiate thing model: %v", err)
+	}
+
+	if td.ID != "urn:dev:ops:4711" || !reflect.DeepEqual(td.Type, []string{"http://iotschema.org/Light"}) {
+		t.Fatalf("Unexpected thing %s of types %v", td.ID, td.Type)
+	}
+
+	if len(td.Properties) != 2 || len(td.Actions) != 1 || len(td.Events) != 0 {
+		t.Fatalf("Unexpected affordances %d/%d/%d", len(td.Properties), len(td.Actions), len(td.Events))
+	}
+
+	for _, currProperty := range td.Properties {
+		if currProperty.Name.Value() != "brightness" {
+			continue
+		}
+
+		if max, _ := currProperty.Maximum.Value(); max != 255 {
+			t.Fatalf("Expected typed placeholder value, got %v", max)
+		}
+
+		if href := currProperty.Form.Value().Href.Value(); href != "http://lamp.local/properties/brightness" {
+			t.Fatalf("Unexpected href %s", href)
+		}
+	}
+}
+
+func TestThingModelErrors(t *testing.T) {
+	m, err := ParseThingModel([]byte(testThingModel))
+	if err != nil {
+		t.Fatalf("Failed to parse thing model: %v", err)
+	}
+
+	placeholders := map[string]interface{}{"SERIAL": "4711", "BASE": "http://lamp.local/", "MAX_BRIGHTNESS": 100}
+
+	testCases := []struct {
+		placeholders map[string]interface{}
+		optional     []string
+		path         string
+	}{
+		{map[string]interface{}{"SERIAL": "4711"}, nil, ""},
+		{placeholders, []string{"#/properties/color"}, ""},
+		{placeholders, []string{"#/actions/toggle"}, "/actions/toggle"},
+	}
+
+	for _, currTestCase := range testCases {
+		_, err := m.Instantiate(currTestCase.placeholders, currTestCase.optional...)
+
+		var modelErr *ModelError
+		if !errors.As(err, &modelErr) || modelErr.Path != currTestCase.path {
+			t.Fatalf("Expected model error at %q, got %v", currTestCase.path, err)
+		}
+	}
+
+	invalidModels := []string{
+		`{"title": "Lamp"}`,
+		`{"@type": "tm:ThingModel", "tm:required": ["#/properties/missing"]}`,
+		`{"@type": "tm:ThingModel", "tm:required": ["#/actions/a"], "tm:optional": ["#/actions/a"], "actions": {"a": {}}}`,
+	}
+
+	for _, currModel := range invalidModels {
+		var modelErr *ModelError
+		if _, err := ParseThingModel([]byte(currModel)); !errors.As(err, &modelErr) {
+			t.Fatalf("Expected model error for %s, got %v", currModel, err)
+		}
+	}
+}
+
+func TestThingModelOptional(t *testing.T) {
+	m, err := ParseThingModel([]byte(`{
+		"@type": "tm:ThingModel",
+		"title": "Sensor",
+		"tm:optional": ["#/properties/humidity"],
+		"properties": {"temperature": {}, "humidity": {}}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse thing model: %v", err)
+	}
+
+	if required := m.RequiredAffordances(); !reflect.DeepEqual(required, []string{"#/properties/temperature"}) {
+		t.Fatalf("Unexpected required affordances %v", required)
+	}
+}
@@ -0,0 +1,178 @@
+package wotlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// ValidationError is returned if a value does not match a data schema
+type ValidationError struct {
+	// Path points to the invalid element, e.g. "/color/red"
+	Path   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return "invalid value: " + e.Reason
+	}
+
+	return fmt.Sprintf("invalid value at %s: %s", e.Path, e.Reason)
+}
+
+// Validate checks if the given value matches with the data schema.
+// Values which are not made of json primitives are converted by
+// encoding them to json first. An empty schema accepts any value
+func (s ExpandedDataSchema) Validate(value interface{}) error {
+	normalized, err := normalizeValue(value)
+	if err != nil {
+		return &ValidationError{Reason: err.Error()}
+	}
+
+	return s.validate("", normalized)
+}
+
+// Validate checks if the given value matches with the data schema of the property
+func (t ExpandedPropertyAffordance) Validate(value interface{}) error {
+	return t.ExpandedDataSchema.Validate(value)
+}
+
+// Validate checks if the given value matches with the first schema inside the node
+func (s ExpandedDataSchemaNode) Validate(value interface{}) error {
+	return s.Value().Validate(value)
+}
+
+func (s ExpandedDataSchema) validate(path string, value interface{}) error {
+	if err := s.validateType(path, value); err != nil {
+		return err
+	}
+
+	if len(s.Const) > 0 && !reflect.DeepEqual(s.Const[0].Value, value) {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("expected constant %v", s.Const[0].Value)}
+	}
+
+	if len(s.Enum) > 0 {
+		matchFound := false
+		for _, currValue := range s.Enum.Values() {
+			if reflect.DeepEqual(currValue, value) {
+				matchFound = true
+				break
+			}
+		}
+
+		if !matchFound {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("%v is not one of %v", value, s.Enum.Values())}
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if min, ok := s.Minimum.Value(); ok && v < min {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("%v is less than minimum %v", v, min)}
+		}
+
+		if max, ok := s.Maximum.Value(); ok && v > max {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("%v is greater than maximum %v", v, max)}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if min, ok := s.MinLength.Value(); ok && length < min {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("string is shorter than %v", min)}
+		}
+
+		if max, ok := s.MaxLength.Value(); ok && length > max {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("string is longer than %v", max)}
+		}
+	case []interface{}:
+		length := float64(len(v))
+		if min, ok := s.MinItems.Value(); ok && length < min {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("array has less than %v items", min)}
+		}
+
+		if max, ok := s.MaxItems.Value(); ok && length > max {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("array has more than %v items", max)}
+		}
+
+		if len(s.Items) > 0 {
+			for i := range v {
+				if err := s.Items.Value().validate(path+"/"+strconv.Itoa(i), v[i]); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required.Values() {
+			if _, ok := v[name]; !ok {
+				return &ValidationError{Path: path, Reason: fmt.Sprintf("required property %s is missing", name)}
+			}
+		}
+
+		for _, currProperty := range s.Properties {
+			propertyValue, ok := v[currProperty.Name.Value()]
+			if !ok {
+				continue
+			}
+
+			if err := currProperty.validate(path+"/"+currProperty.Name.Value(), propertyValue); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s ExpandedDataSchema) validateType(path string, value interface{}) error {
+	if len(s.DataType) == 0 {
+		return nil
+	}
+
+	valid := false
+	switch s.DataType.Value() {
+	case DataTypeObject:
+		_, valid = value.(map[string]interface{})
+	case DataTypeArray:
+		_, valid = value.([]interface{})
+	case DataTypeString:
+		_, valid = value.(string)
+	case DataTypeBoolean:
+		_, valid = value.(bool)
+	case DataTypeNumber:
+		_, valid = value.(float64)
+	case DataTypeInteger:
+		f, ok := value.(float64)
+		valid = ok && f == math.Trunc(f)
+	case DataTypeNull:
+		valid = value == nil
+	default:
+		// unknown data types can't be checked
+		valid = true
+	}
+
+	if !valid {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("%v does not match type %s", value, s.DataType.Value())}
+	}
+
+	return nil
+}
+
+// normalizeValue converts a value into its generic json representation
+func normalizeValue(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case nil, bool, float64, string:
+		return value, nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	err = json.Unmarshal(b, &result)
+
+	return result, err
+}
@@ -0,0 +1,58 @@
+package wotlib
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	expandedTD, err := FromBytes(testTDOne)
+	if err != nil {
+		t.Fatalf("Failed to build expanded td: %v", err)
+	}
+
+	setColor := expandedTD.GetActionAffordances(ActionConstraint{Name: asStringPointer("lamp-setColor")})
+	if len(setColor) != 1 {
+		t.Fatalf("Unexpected result set. Expected: 1, Got: %d", len(setColor))
+	}
+
+	var validateTests = []struct {
+		Name  string
+		Value interface{}
+		Valid bool
+	}{
+		{
+			Name:  "Valid object",
+			Value: map[string]interface{}{"red": 1, "green": 2, "blue": 3},
+			Valid: true,
+		},
+		{
+			Name:  "Struct value",
+			Value: struct{ Red int }{Red: 5},
+			Valid: true,
+		},
+		{
+			Name:  "Wrong type",
+			Value: "red",
+			Valid: false,
+		},
+		{
+			Name:  "Wrong property type",
+			Value: map[string]interface{}{"red": "255"},
+			Valid: false,
+		},
+		{
+			Name:  "Float instead of integer",
+			Value: map[string]interface{}{"red": 0.5},
+			Valid: false,
+		},
+	}
+
+	for _, currTest := range validateTests {
+		t.Run(currTest.Name, func(t *testing.T) {
+			err := setColor[0].Input.Validate(currTest.Value)
+			if (err == nil) != currTest.Valid {
+				t.Fatalf(currTest.Name+" failed. Expected valid: %t, Got: %v", currTest.Valid, err)
+			}
+		})
+	}
+}